@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/symbiont-io/detsys-testkit/src/lib"
+)
+
+var resultsCmd = &cobra.Command{
+	Use:   "results [test-id] [run-id]",
+	Short: "Show the results of the checks of a test run",
+	Long:  ``,
+	Args:  cobra.ExactArgs(2),
+	Run: func(_ *cobra.Command, args []string) {
+		testId, err := lib.ParseTestId(args[0])
+		if err != nil {
+			panic(err)
+		}
+		runId, err := lib.ParseRunId(args[1])
+		if err != nil {
+			panic(err)
+		}
+		results, err := lib.LoadCheckResults(testId, runId)
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			os.Exit(1)
+		}
+		if len(results) == 0 {
+			fmt.Printf("Test %d run %d hasn't been checked\n", testId.TestId, runId.RunId)
+			return
+		}
+		for _, result := range results {
+			verdict := "passed"
+			if !result.Valid {
+				verdict = "failed"
+			}
+			fmt.Printf("%s %s: %s in %v\n", result.Checker, result.Model, verdict, result.Duration)
+			if result.Reason != "" {
+				fmt.Printf("  %s\n", result.Reason)
+			}
+		}
+	},
+}
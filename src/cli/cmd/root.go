@@ -20,6 +20,7 @@ func init() {
 	dbCmd.AddCommand(dbResetCmd)
 	dbCmd.AddCommand(dbShellCmd)
 	rootCmd.AddCommand(debugCmd)
+	rootCmd.AddCommand(resultsCmd)
 	rootCmd.AddCommand(schedulerCmd)
 	schedulerCmd.AddCommand(schedulerUpCmd)
 	schedulerCmd.AddCommand(schedulerDownCmd)
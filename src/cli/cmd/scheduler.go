@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -92,7 +93,12 @@ var schedulerStatusCmd = &cobra.Command{
 	Long:  ``,
 	Args:  cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		json, err := json.Marshal(lib.Status())
+		status, err := lib.DefaultSchedulerClient().Status(context.Background())
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		json, err := json.Marshal(status)
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
@@ -107,7 +113,10 @@ var schedulerResetCmd = &cobra.Command{
 	Long:  ``,
 	Args:  cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		lib.Reset()
+		if err := lib.DefaultSchedulerClient().Reset(context.Background()); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 	},
 }
 
@@ -122,7 +131,11 @@ var schedulerLoadCmd = &cobra.Command{
 			fmt.Println(err)
 			os.Exit(1)
 		}
-		queueSize := lib.LoadTest(testId)
+		queueSize, err := lib.DefaultSchedulerClient().LoadTest(context.Background(), testId)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 		fmt.Printf("Test case loaded, current queue size: %d\n", queueSize)
 	},
 }
@@ -138,7 +151,10 @@ var schedulerRegisterCmd = &cobra.Command{
 			fmt.Println(err)
 			os.Exit(1)
 		}
-		lib.Register(testId)
+		if err := lib.DefaultSchedulerClient().Register(context.Background(), testId); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 	},
 }
 
@@ -162,7 +178,11 @@ var schedulerCreateRunCmd = &cobra.Command{
 			MinTimeNs:     0,
 			MaxTimeNs:     0,
 		}
-		runId := lib.CreateRun(testId, runEvent)
+		runId, err := lib.DefaultSchedulerClient().CreateRun(context.Background(), testId, runEvent)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 		fmt.Printf("Created run id: %v\n", runId)
 	},
 }
@@ -173,6 +193,11 @@ var schedulerStepCmd = &cobra.Command{
 	Long:  ``,
 	Args:  cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println(string(lib.Step()))
+		result, err := lib.DefaultSchedulerClient().Step(context.Background())
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Println(string(result))
 	},
 }
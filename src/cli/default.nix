@@ -3,7 +3,7 @@
 }:
 with pkgs;
 
-assert lib.versionAtLeast go.version "1.15";
+assert lib.versionAtLeast go.version "1.18";
 
 let
   inherit (import sources.gitignore {}) gitignoreSource;
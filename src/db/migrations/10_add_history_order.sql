@@ -0,0 +1,28 @@
+-- +migrate Up
+DROP VIEW IF EXISTS jepsen_history;
+CREATE VIEW IF NOT EXISTS jepsen_history AS
+  SELECT
+    id                                     AS id,
+    json_extract(meta, '$.test-id')        AS test_id,
+    json_extract(meta, '$.run-id')         AS run_id,
+    json_extract(data, '$.jepsen-type')    AS kind,
+    json_extract(data, '$.message')        AS event,
+    json_extract(data, '$.args')           AS args,
+    json_extract(data, '$.jepsen-process') AS process
+  FROM event_log
+  WHERE event = 'NetworkTrace'
+  AND json_extract(data, '$.jepsen-type') IS NOT NULL;
+
+-- +migrate Down
+DROP VIEW IF EXISTS jepsen_history;
+CREATE VIEW IF NOT EXISTS jepsen_history AS
+  SELECT
+    json_extract(meta, '$.test-id')        AS test_id,
+    json_extract(meta, '$.run-id')         AS run_id,
+    json_extract(data, '$.jepsen-type')    AS kind,
+    json_extract(data, '$.message')        AS event,
+    json_extract(data, '$.args')           AS args,
+    json_extract(data, '$.jepsen-process') AS process
+  FROM event_log
+  WHERE event = 'NetworkTrace'
+  AND json_extract(data, '$.jepsen-type') IS NOT NULL;
@@ -0,0 +1,30 @@
+-- +migrate Up
+DROP VIEW IF EXISTS execution_step;
+CREATE VIEW IF NOT EXISTS execution_step AS
+  SELECT
+    json_extract(meta, '$.test-id')        AS test_id,
+    json_extract(meta, '$.run-id')         AS run_id,
+    json_extract(data, '$.reactor')        AS reactor,
+    json_extract(data, '$.logical-time')   AS logical_time,
+    json_extract(data, '$.simulated-time') AS simulated_time,
+    json_extract(data, '$.log-lines')      AS log_lines,
+    json_extract(data, '$.diff')           AS heap_diff,
+    json_extract(data, '$.durable-state')  AS durable_state,
+    json_extract(data, '$.rand-draws')     AS rand_draws
+  FROM event_log
+  WHERE event = 'ExecutionStep';
+
+-- +migrate Down
+DROP VIEW IF EXISTS execution_step;
+CREATE VIEW IF NOT EXISTS execution_step AS
+  SELECT
+    json_extract(meta, '$.test-id')        AS test_id,
+    json_extract(meta, '$.run-id')         AS run_id,
+    json_extract(data, '$.reactor')        AS reactor,
+    json_extract(data, '$.logical-time')   AS logical_time,
+    json_extract(data, '$.simulated-time') AS simulated_time,
+    json_extract(data, '$.log-lines')      AS log_lines,
+    json_extract(data, '$.diff')           AS heap_diff,
+    json_extract(data, '$.durable-state')  AS durable_state
+  FROM event_log
+  WHERE event = 'ExecutionStep';
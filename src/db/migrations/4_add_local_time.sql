@@ -0,0 +1,63 @@
+-- +migrate Up
+DROP VIEW IF EXISTS network_trace;
+CREATE VIEW IF NOT EXISTS network_trace AS
+  SELECT
+    json_extract(meta, '$.test-id')             AS test_id,
+    json_extract(meta, '$.run-id')              AS run_id,
+    json_extract(data, '$.message')             AS message,
+    json_extract(data, '$.args')                AS args,
+    json_extract(data, '$.from')                AS sender,
+    json_extract(data, '$.to')                  AS receiver,
+    json_extract(data, '$.kind')                AS kind,
+    json_extract(data, '$.sent-logical-time')   AS sent_logical_time,
+    json_extract(data, '$.recv-logical-time')   AS recv_logical_time,
+    json_extract(data, '$.recv-simulated-time') AS recv_simulated_time,
+    json_extract(data, '$.recv-local-time')     AS recv_local_time,
+    json_extract(data, '$.dropped')             AS dropped
+  FROM event_log
+  WHERE event = 'NetworkTrace';
+
+DROP VIEW IF EXISTS run_info;
+CREATE VIEW IF NOT EXISTS run_info AS
+  SELECT
+    json_extract(meta, '$.test-id')        AS test_id,
+    json_extract(meta, '$.run-id')         AS run_id,
+    json_extract(data, '$.seed')           AS seed,
+    json_extract(data, '$.faults')         AS faults,
+    json_extract(data, '$.tick-frequency') AS tick_frequency,
+    json_extract(data, '$.max-time-ns')    AS max_time_ns,
+    json_extract(data, '$.min-time-ns')    AS min_time_ns,
+    json_extract(data, '$.clocks')         AS clocks
+  FROM event_log
+  WHERE event = 'CreateRun';
+
+-- +migrate Down
+DROP VIEW IF EXISTS network_trace;
+CREATE VIEW IF NOT EXISTS network_trace AS
+  SELECT
+    json_extract(meta, '$.test-id')             AS test_id,
+    json_extract(meta, '$.run-id')              AS run_id,
+    json_extract(data, '$.message')             AS message,
+    json_extract(data, '$.args')                AS args,
+    json_extract(data, '$.from')                AS sender,
+    json_extract(data, '$.to')                  AS receiver,
+    json_extract(data, '$.kind')                AS kind,
+    json_extract(data, '$.sent-logical-time')   AS sent_logical_time,
+    json_extract(data, '$.recv-logical-time')   AS recv_logical_time,
+    json_extract(data, '$.recv-simulated-time') AS recv_simulated_time,
+    json_extract(data, '$.dropped')             AS dropped
+  FROM event_log
+  WHERE event = 'NetworkTrace';
+
+DROP VIEW IF EXISTS run_info;
+CREATE VIEW IF NOT EXISTS run_info AS
+  SELECT
+    json_extract(meta, '$.test-id')        AS test_id,
+    json_extract(meta, '$.run-id')         AS run_id,
+    json_extract(data, '$.seed')           AS seed,
+    json_extract(data, '$.faults')         AS faults,
+    json_extract(data, '$.tick-frequency') AS tick_frequency,
+    json_extract(data, '$.max-time-ns')    AS max_time_ns,
+    json_extract(data, '$.min-time-ns')    AS min_time_ns
+  FROM event_log
+  WHERE event = 'CreateRun';
@@ -0,0 +1,30 @@
+-- +migrate Up
+DROP VIEW IF EXISTS run_info;
+CREATE VIEW IF NOT EXISTS run_info AS
+  SELECT
+    json_extract(meta, '$.test-id')        AS test_id,
+    json_extract(meta, '$.run-id')         AS run_id,
+    json_extract(data, '$.seed')           AS seed,
+    json_extract(data, '$.faults')         AS faults,
+    json_extract(data, '$.tick-frequency') AS tick_frequency,
+    json_extract(data, '$.max-time-ns')    AS max_time_ns,
+    json_extract(data, '$.min-time-ns')    AS min_time_ns,
+    json_extract(data, '$.clocks')         AS clocks,
+    json_extract(data, '$.network')        AS network
+  FROM event_log
+  WHERE event = 'CreateRun';
+
+-- +migrate Down
+DROP VIEW IF EXISTS run_info;
+CREATE VIEW IF NOT EXISTS run_info AS
+  SELECT
+    json_extract(meta, '$.test-id')        AS test_id,
+    json_extract(meta, '$.run-id')         AS run_id,
+    json_extract(data, '$.seed')           AS seed,
+    json_extract(data, '$.faults')         AS faults,
+    json_extract(data, '$.tick-frequency') AS tick_frequency,
+    json_extract(data, '$.max-time-ns')    AS max_time_ns,
+    json_extract(data, '$.min-time-ns')    AS min_time_ns,
+    json_extract(data, '$.clocks')         AS clocks
+  FROM event_log
+  WHERE event = 'CreateRun';
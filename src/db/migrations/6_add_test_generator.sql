@@ -0,0 +1,22 @@
+-- +migrate Up
+DROP VIEW IF EXISTS test_info;
+CREATE VIEW IF NOT EXISTS test_info AS
+  SELECT
+    json_extract(meta, '$.test-id')    AS test_id,
+    json_extract(data, '$.agenda')     AS agenda,
+    json_extract(data, '$.deployment') AS deployment,
+    json_extract(data, '$.generator')  AS generator,
+    at                                 AS created_time
+  FROM event_log
+  WHERE event = 'CreateTest';
+
+-- +migrate Down
+DROP VIEW IF EXISTS test_info;
+CREATE VIEW IF NOT EXISTS test_info AS
+  SELECT
+    json_extract(meta, '$.test-id')    AS test_id,
+    json_extract(data, '$.agenda')     AS agenda,
+    json_extract(data, '$.deployment') AS deployment,
+    at                                 AS created_time
+  FROM event_log
+  WHERE event = 'CreateTest';
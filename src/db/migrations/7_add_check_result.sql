@@ -0,0 +1,17 @@
+-- +migrate Up
+CREATE VIEW IF NOT EXISTS check_result AS
+  SELECT
+    json_extract(meta, '$.test-id')     AS test_id,
+    json_extract(meta, '$.run-id')      AS run_id,
+    json_extract(data, '$.checker')     AS checker,
+    json_extract(data, '$.model')       AS model,
+    json_extract(data, '$.valid')       AS valid,
+    json_extract(data, '$.reason')      AS reason,
+    json_extract(data, '$.anomalies')   AS anomalies,
+    json_extract(data, '$.duration-ns') AS duration_ns,
+    at                                  AS checked_time
+  FROM event_log
+  WHERE event = 'CheckResult';
+
+-- +migrate Down
+DROP VIEW IF EXISTS check_result;
@@ -70,6 +70,9 @@ type DebugApplication struct {
 	heaps         []map[string][]byte
 	diagrams      *debugger.SequenceDiagrams
 	events        []debugger.NetworkEvent
+	faults        []lib.Fault
+	network       *lib.NetworkModel
+	checks        string
 	reactors      []string
 	activeRow     int // should probably be logic time
 	activeReactor int
@@ -131,6 +134,9 @@ func (da *DebugApplication) redraw() {
 
 	event := da.events[row-1]
 	fmt.Fprintf(wMessageView, "%s", string(event.Args))
+	for _, fault := range debugger.EventFaults(da.faults, event) {
+		fmt.Fprintf(wMessageView, "\n[red]fault: %s[-]", fault)
+	}
 
 	logs := debugger.GetLogMessages(da.testId, da.runId, reactor, event.RecvAt)
 	for _, log := range logs {
@@ -215,6 +221,9 @@ func MakeDebugApplication(testId lib.TestId, runId lib.RunId) *DebugApplication
 		heaps:         heaps,
 		diagrams:      diagrams,
 		events:        events,
+		faults:        debugger.GetFaults(testId, runId),
+		network:       debugger.GetNetworkModel(testId, runId),
+		checks:        debugger.GetCheckResults(testId, runId),
 		reactors:      reactors,
 		activeRow:     1,
 		activeReactor: ac,
@@ -245,6 +254,15 @@ func displayDuration(dur time.Duration) string {
 	return fmt.Sprintf("%d days %s", days, dur)
 }
 
+// The first event shows the full time, the others show the time since the start
+// of the run.
+func displayTime(row int, t time.Time) string {
+	if row == 0 {
+		return t.Format(time.StampNano)
+	}
+	return displayDuration(t.Sub(time.Unix(0, 0).UTC()))
+}
+
 func main() {
 	if os.Args[1] == "--version" || os.Args[1] == "-v" {
 		fmt.Println(version)
@@ -306,12 +324,11 @@ func main() {
 				case "Received":
 					tableCell = tview.NewTableCell(strconv.Itoa(event.RecvAt))
 				case "Time":
-					if row == 0 {
-						tableCell = tview.NewTableCell(event.Simulated.Format(time.StampNano))
-					} else {
-						tableCell = tview.NewTableCell(
-							displayDuration(event.Simulated.Sub(time.Unix(0, 0).UTC())))
+					text := displayTime(row, event.Simulated)
+					if !event.Local.Equal(event.Simulated) {
+						text = fmt.Sprintf("%s (local %s)", text, displayTime(row, event.Local))
 					}
+					tableCell = tview.NewTableCell(text)
 				}
 				if event.Dropped {
 					tableCell.SetTextColor(tcell.ColorGray)
@@ -319,7 +336,7 @@ func main() {
 				table.SetCell(row+1, column, tableCell)
 			}
 		}
-		table.SetBorder(true).SetTitle("Events")
+		table.SetBorder(true).SetTitle(fmt.Sprintf("Events (network: %s, checks: %s)", da.network, da.checks))
 		table.SetSelectable(true, false)
 		table.SetSelectionChangedFunc(
 			func(row, column int) {
@@ -64,6 +64,7 @@ func GetHeapTrace(testId lib.TestId, runId lib.RunId) []HeapDiff {
 }
 
 type NetworkEvent struct {
+	Kind      string
 	Message   string
 	Args      []byte
 	From      string
@@ -72,20 +73,25 @@ type NetworkEvent struct {
 	RecvAt    int
 	Dropped   bool
 	Simulated time.Time
+	// The receiver's local time, which differs from the simulated time if the
+	// receiver's clock is offset, drifts or was skewed.
+	Local time.Time
 }
 
 func GetNetworkTrace(testId lib.TestId, runId lib.RunId) []NetworkEvent {
 	db := lib.OpenDB()
 	defer db.Close()
 
-	rows, err := db.Query(`SELECT message,
+	rows, err := db.Query(`SELECT kind,
+                                      message,
                                       args,
                                       sender,
                                       sent_logical_time,
                                       receiver,
                                       recv_logical_time,
                                       dropped,
-                                      recv_simulated_time
+                                      recv_simulated_time,
+                                      COALESCE(recv_local_time, recv_simulated_time)
 		               FROM network_trace
 		               WHERE test_id = ?
 		                 AND run_id = ?`, testId.TestId, runId.RunId)
@@ -97,17 +103,49 @@ func GetNetworkTrace(testId lib.TestId, runId lib.RunId) []NetworkEvent {
 	var trace []NetworkEvent
 	for rows.Next() {
 		event := NetworkEvent{}
-		err := rows.Scan(&event.Message, &event.Args, &event.From, &event.SentAt, &event.To, &event.RecvAt, &event.Dropped, (*lib.TimeFromString)(&event.Simulated))
+		err := rows.Scan(&event.Kind, &event.Message, &event.Args, &event.From, &event.SentAt, &event.To, &event.RecvAt, &event.Dropped, (*lib.TimeFromString)(&event.Simulated), (*lib.TimeFromString)(&event.Local))
 		if err != nil {
 			panic(err)
 		}
 		if !(event.Message == "timer" && event.Dropped) {
+			event.Message = timerMessage(event.Message, event.Args)
+			event.Message = ioMessage(event.Kind, event.Message, event.Args)
 			trace = append(trace, event)
 		}
 	}
 	return trace
 }
 
+// Named timers are shown with their id, e.g. "timer:resend", so that one can
+// tell which of a reactor's timers fired.
+func timerMessage(message string, args []byte) string {
+	if message != "timer" {
+		return message
+	}
+	var timer struct {
+		Id string `json:"id"`
+	}
+	if err := json.Unmarshal(args, &timer); err != nil || timer.Id == "" {
+		return message
+	}
+	return message + ":" + timer.Id
+}
+
+// I/O completions are shown with the request and its id, e.g. "io:write#3", so
+// that they can be told apart from messages.
+func ioMessage(kind string, message string, args []byte) string {
+	if kind != "io" {
+		return message
+	}
+	var io struct {
+		Id uint64 `json:"id"`
+	}
+	if err := json.Unmarshal(args, &io); err != nil {
+		return "io:" + message
+	}
+	return fmt.Sprintf("io:%s#%d", message, io.Id)
+}
+
 func applyDiff(original, diff []byte) []byte {
 	new, err := jsonpatch.MergePatch(original, diff)
 	if err != nil {
@@ -217,15 +255,17 @@ type SequenceDiagrams struct {
 	header  []byte
 	net     []NetworkEvent
 	crashes CrashInformation
+	markers MarkerInformation
 }
 
 func NewSequenceDiagrams(testId lib.TestId, runId lib.RunId) *SequenceDiagrams {
 	net := GetNetworkTrace(testId, runId)
-	crashes := GetCrashes(testId, runId)
+	faults := GetFaults(testId, runId)
 	return &SequenceDiagrams{
 		inner:   make(map[int]result),
 		net:     net,
-		crashes: crashes,
+		crashes: crashes(faults),
+		markers: markers(faults, net),
 	}
 }
 
@@ -250,6 +290,7 @@ func (s *SequenceDiagrams) At(at int) ([]byte, int) {
 		MarkerSize: 3,
 		MarkAt:     at,
 		Crashes:    s.crashes,
+		Markers:    s.markers,
 	})
 
 	if s.header == nil {
@@ -264,7 +305,7 @@ func (s *SequenceDiagrams) Header() []byte {
 	return s.header
 }
 
-func GetCrashes(testId lib.TestId, runId lib.RunId) CrashInformation {
+func GetFaults(testId lib.TestId, runId lib.RunId) []lib.Fault {
 	db := lib.OpenDB()
 	defer db.Close()
 
@@ -294,7 +335,57 @@ func GetCrashes(testId lib.TestId, runId lib.RunId) CrashInformation {
 			panic(err)
 		}
 	}
+	return faults
+}
+
+// The network model of the run, nil if the run used the scheduler's default.
+func GetNetworkModel(testId lib.TestId, runId lib.RunId) *lib.NetworkModel {
+	db := lib.OpenDB()
+	defer db.Close()
+
+	var jsonBlob []byte
+	err := db.QueryRow(`SELECT network
+                            FROM run_info
+                            WHERE test_id = ?
+                            AND   run_id = ?`, testId.TestId, runId.RunId).Scan(&jsonBlob)
+	if err != nil {
+		panic(err)
+	}
+	if jsonBlob == nil {
+		return nil
+	}
+	var network lib.NetworkModel
+	if err := json.Unmarshal(jsonBlob, &network); err != nil {
+		panic(err)
+	}
+	return &network
+}
+
+// Summarises the results of the checks of the run, e.g. "ltl failed".
+func GetCheckResults(testId lib.TestId, runId lib.RunId) string {
+	results, err := lib.LoadCheckResults(testId, runId)
+	if err != nil {
+		panic(err)
+	}
+	if len(results) == 0 {
+		return "none"
+	}
+	summary := make([]string, 0, len(results))
+	for _, result := range results {
+		verdict := "passed"
+		if !result.Valid {
+			verdict = "failed"
+		}
+		summary = append(summary, fmt.Sprintf("%s %s", result.Checker, verdict))
+	}
+	return strings.Join(summary, ", ")
+}
 
+func GetCrashes(testId lib.TestId, runId lib.RunId) CrashInformation {
+	return crashes(GetFaults(testId, runId))
+}
+
+func crashes(faults []lib.Fault) CrashInformation {
 	crashInformation := make(map[int][]string)
 	for _, fault := range faults {
 		switch ev := fault.Args.(type) {
@@ -309,6 +400,73 @@ func GetCrashes(testId lib.TestId, runId lib.RunId) CrashInformation {
 	return crashInformation
 }
 
+// Pauses, restarts and disk faults happen at a simulated time, their markers
+// are drawn before the first event that is received at or after that time.
+func markers(faults []lib.Fault, net []NetworkEvent) MarkerInformation {
+	markerInformation := make(MarkerInformation)
+	add := func(since time.Duration, marker Marker) {
+		at := time.Unix(0, 0).UTC().Add(since)
+		for _, event := range net {
+			if !event.Simulated.Before(at) {
+				markerInformation[event.RecvAt] = append(markerInformation[event.RecvAt], marker)
+				return
+			}
+		}
+	}
+	for _, fault := range faults {
+		switch ev := fault.Args.(type) {
+		case lib.Pause:
+			add(ev.From, Marker{Reactor: ev.Node, Symbol: "‖", Color: "yellow"})
+			add(ev.To, Marker{Reactor: ev.Node, Symbol: "»", Color: "yellow"})
+		case lib.Restart:
+			add(ev.At, Marker{Reactor: ev.Node, Symbol: "↻", Color: "blue"})
+		case lib.DiskFull:
+			add(ev.From, Marker{Reactor: ev.Node, Symbol: "▤", Color: "magenta"})
+		case lib.TornWrite:
+			add(ev.At, Marker{Reactor: ev.Node, Symbol: "▤", Color: "magenta"})
+		case lib.CorruptRead:
+			add(ev.At, Marker{Reactor: ev.Node, Symbol: "▤", Color: "magenta"})
+		case lib.DiskLatency:
+			add(ev.From, Marker{Reactor: ev.Node, Symbol: "▤", Color: "magenta"})
+		default:
+		}
+	}
+	return markerInformation
+}
+
+// The faults that affected the delivery of the event, described for display.
+// Delays aren't included, since the delayed event is delivered at a later
+// logical time than the fault's.
+func EventFaults(faults []lib.Fault, event NetworkEvent) []string {
+	var descriptions []string
+	link := func(from string, to string, at int) bool {
+		return from == event.From && to == event.To && at == event.RecvAt
+	}
+	for _, fault := range faults {
+		switch ev := fault.Args.(type) {
+		case lib.Omission:
+			if link(ev.From, ev.To, ev.At) {
+				descriptions = append(descriptions, "omission")
+			}
+		case lib.Duplicate:
+			if link(ev.From, ev.To, ev.At) {
+				descriptions = append(descriptions, "duplicate")
+			}
+		case lib.Reorder:
+			if link(ev.From, ev.To, ev.At) {
+				descriptions = append(descriptions, "reorder")
+			}
+		case lib.Partition:
+			if ev.Separates(event.From, event.To, event.RecvAt) {
+				descriptions = append(descriptions,
+					fmt.Sprintf("partition %v from %d to %d", ev.Groups, ev.From, ev.To))
+			}
+		default:
+		}
+	}
+	return descriptions
+}
+
 func GetLogMessages(testId lib.TestId, runId lib.RunId, reactor string, at int) [][]byte {
 	db := lib.OpenDB()
 	defer db.Close()
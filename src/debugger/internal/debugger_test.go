@@ -0,0 +1,62 @@
+package debugger
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/symbiont-io/detsys-testkit/src/lib"
+)
+
+func TestEventFaults(t *testing.T) {
+	faults := []lib.Fault{
+		{Kind: "omission", Args: lib.Omission{From: "a", To: "b", At: 2}},
+		{Kind: "crash", Args: lib.Crash{From: "c", At: 3}},
+		{Kind: "partition", Args: lib.Partition{Groups: [][]string{{"a"}, {"b"}}, From: 2, To: 4}},
+		{Kind: "delay", Args: lib.Delay{From: "a", To: "b", At: 2, By: time.Second}},
+	}
+	got := EventFaults(faults, NetworkEvent{From: "a", To: "b", RecvAt: 2})
+	expected := []string{"omission", "partition [[a] [b]] from 2 to 4"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	if got := EventFaults(faults, NetworkEvent{From: "a", To: "b", RecvAt: 4}); got != nil {
+		t.Errorf("Expected no faults after the partition healed, got %v", got)
+	}
+	if got := crashes(faults); !reflect.DeepEqual(got, CrashInformation{3: {"c"}}) {
+		t.Errorf("Unexpected crashes: %v", got)
+	}
+}
+
+func TestMarkers(t *testing.T) {
+	start := time.Unix(0, 0).UTC()
+	net := []NetworkEvent{
+		{From: "a", To: "b", RecvAt: 1, Simulated: start.Add(500 * time.Millisecond)},
+		{From: "b", To: "a", RecvAt: 2, Simulated: start.Add(time.Second)},
+		{From: "b", To: "b", RecvAt: 3, Simulated: start.Add(2 * time.Second), Message: "restart"},
+	}
+	faults := []lib.Fault{
+		{Kind: "pause", Args: lib.Pause{Node: "a", From: 600 * time.Millisecond, To: 2 * time.Second}},
+		{Kind: "restart", Args: lib.Restart{Node: "b", At: 2 * time.Second}},
+		{Kind: "corrupt-read", Args: lib.CorruptRead{Node: "b", At: 0}},
+		// Never drawn, there are no events after it.
+		{Kind: "restart", Args: lib.Restart{Node: "a", At: time.Minute}},
+	}
+	expected := MarkerInformation{
+		1: {{Reactor: "b", Symbol: "▤", Color: "magenta"}},
+		2: {{Reactor: "a", Symbol: "‖", Color: "yellow"}},
+		3: {{Reactor: "a", Symbol: "»", Color: "yellow"}, {Reactor: "b", Symbol: "↻", Color: "blue"}},
+	}
+	if got := markers(faults, net); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestIOMessage(t *testing.T) {
+	if got := ioMessage("io", "write", []byte(`{"id":3,"request":{}}`)); got != "io:write#3" {
+		t.Errorf("Unexpected I/O message: %s", got)
+	}
+	if got := ioMessage("message", "write", []byte(`{}`)); got != "write" {
+		t.Errorf("Unexpected message: %s", got)
+	}
+}
@@ -7,6 +7,24 @@ import (
 type CrashInformation = map[int][]string
 type crashInformationInternal = map[int][]int
 
+// Markers, other than crashes, drawn on a reactor's lifeline before the arrows
+// at the given logical time.
+type Marker struct {
+	Reactor string
+	Symbol  string
+	Color   string
+}
+
+type MarkerInformation = map[int][]Marker
+
+type markerInternal struct {
+	symbol string
+	color  string
+	crash  bool // The lifeline ends.
+}
+
+type markerInformationInternal = map[int]map[int]markerInternal
+
 // inlined version of b.WriteString(strings.Repeat(s,count))
 func WriteRepeat(b *strings.Builder, s string, count int) {
 	if count == 0 {
@@ -142,7 +160,7 @@ func appendBoxes(isTop bool, output *strings.Builder, names []string, gaps []int
 	}
 }
 
-func appendArrows(output *strings.Builder, names []string, arrows []arrowInternal, gaps []int, boxSize int, crashInformation crashInformationInternal) int {
+func appendArrows(output *strings.Builder, names []string, arrows []arrowInternal, gaps []int, boxSize int, crashInformation crashInformationInternal, markerInformation markerInformationInternal) int {
 	deadNodes := make(map[int]bool)
 	line := 0
 	foundLine := false
@@ -158,74 +176,42 @@ func appendArrows(output *strings.Builder, names []string, arrows []arrowInterna
 			deadNodes[n] = true
 		}
 
-		// output lines for new crashes
-		if newCrashes != nil {
-			for i, _ := range names {
-				// "Top"
-				middle := "  │  "
-				newCrash := false
-				for _, v := range newCrashes {
-					if v == i {
-						newCrash = true
-					}
-				}
+		newMarkers := make(map[int]markerInternal)
+		for n, m := range markerInformation[arr.at] {
+			newMarkers[n] = m
+		}
+		for _, n := range newCrashes {
+			newMarkers[n] = markerInternal{symbol: "☠", color: "red", crash: true}
+		}
 
-				if newCrash {
-					middle = "[red]╭─┴─╮[-]"
-				} else if deadNodes[i] {
-					middle = "     "
-				}
-				WriteRepeat(output, " ", halfBox-2+gaps[i])
-				output.WriteString(middle)
-				WriteRepeat(output, " ", halfBox-2)
-			}
-			output.WriteString("\n")
-			if !foundLine {
-				line++
-			}
-			for i, _ := range names {
-				middle := "  │  "
-				newCrash := false
-				for _, v := range newCrashes {
-					if v == i {
-						newCrash = true
+		// output lines for new crashes and other markers
+		if len(newMarkers) > 0 {
+			rows := []func(m markerInternal) string{
+				func(_ markerInternal) string { return "╭─┴─╮" },
+				func(m markerInternal) string { return "│ " + m.symbol + " │" },
+				func(m markerInternal) string {
+					if m.crash {
+						return "╰───╯"
 					}
-				}
-
-				if newCrash {
-					middle = "[red]│ ☠ │[-]"
-				} else if deadNodes[i] {
-					middle = "     "
-				}
-				WriteRepeat(output, " ", halfBox-2+gaps[i])
-				output.WriteString(middle)
-				WriteRepeat(output, " ", halfBox-2)
+					return "╰─┬─╯"
+				},
 			}
-			output.WriteString("\n")
-			if !foundLine {
-				line++
-			}
-			for i, _ := range names {
-				middle := "  │  "
-				newCrash := false
-				for _, v := range newCrashes {
-					if v == i {
-						newCrash = true
+			for _, row := range rows {
+				for i, _ := range names {
+					middle := "  │  "
+					if m, ok := newMarkers[i]; ok {
+						middle = "[" + m.color + "]" + row(m) + "[-]"
+					} else if deadNodes[i] {
+						middle = "     "
 					}
+					WriteRepeat(output, " ", halfBox-2+gaps[i])
+					output.WriteString(middle)
+					WriteRepeat(output, " ", halfBox-2)
 				}
-
-				if newCrash {
-					middle = "[red]╰───╯[-]"
-				} else if deadNodes[i] {
-					middle = "     "
+				output.WriteString("\n")
+				if !foundLine {
+					line++
 				}
-				WriteRepeat(output, " ", halfBox-2+gaps[i])
-				output.WriteString(middle)
-				WriteRepeat(output, " ", halfBox-2)
-			}
-			output.WriteString("\n")
-			if !foundLine {
-				line++
 			}
 		}
 
@@ -387,7 +373,7 @@ func appendArrows(output *strings.Builder, names []string, arrows []arrowInterna
 	return line
 }
 
-func drawDiagram(names []string, arrows []arrowInternal, gaps []int, nrLoops int, crashInformation crashInformationInternal) ([]byte, []byte, int) {
+func drawDiagram(names []string, arrows []arrowInternal, gaps []int, nrLoops int, crashInformation crashInformationInternal, markerInformation markerInformationInternal) ([]byte, []byte, int) {
 	if len(names) < 1 {
 		panic("We need at least one box")
 	}
@@ -404,14 +390,14 @@ func drawDiagram(names []string, arrows []arrowInternal, gaps []int, nrLoops int
 		}
 		lineWidth += boxSize * len(names)
 		lineWidth++ // newline
-		// loops have one more line than normal, plus each crash is three lines
-		expectedSize = lineWidth * (len(arrows) + nrLoops + len(crashInformation)*3)
+		// loops have one more line than normal, plus each crash or marker is three lines
+		expectedSize = lineWidth * (len(arrows) + nrLoops + (len(crashInformation)+len(markerInformation))*3)
 	}
 	output.Grow(expectedSize)
 
 	appendBoxes(true, &header, names, gaps)
 
-	line := appendArrows(&output, names, arrows, gaps, boxSize, crashInformation)
+	line := appendArrows(&output, names, arrows, gaps, boxSize, crashInformation, markerInformation)
 	appendBoxes(false, &output, names, gaps)
 
 	// remove last newline
@@ -431,6 +417,7 @@ type DrawSettings struct {
 	MarkerSize int
 	MarkAt     int
 	Crashes    CrashInformation
+	Markers    MarkerInformation
 }
 
 func DrawDiagram(arrows []Arrow, settings DrawSettings) ([]byte, []byte, int) {
@@ -454,6 +441,14 @@ func DrawDiagram(arrows []Arrow, settings DrawSettings) ([]byte, []byte, int) {
 		}
 		crashInformation[k] = targets
 	}
+	markerInformation := make(markerInformationInternal)
+	for k, markers := range settings.Markers {
+		targets := make(map[int]markerInternal, len(markers))
+		for _, m := range markers {
+			targets[index(names, m.Reactor)] = markerInternal{symbol: m.Symbol, color: m.Color}
+		}
+		markerInformation[k] = targets
+	}
 
 	gaps := make([]int, len(names), len(names))
 	arrowsInternal := make([]arrowInternal, 0, len(arrows))
@@ -524,5 +519,5 @@ func DrawDiagram(arrows []Arrow, settings DrawSettings) ([]byte, []byte, int) {
 		}
 	}
 
-	return drawDiagram(names, arrowsInternal, gaps, nrLoops, crashInformation)
+	return drawDiagram(names, arrowsInternal, gaps, nrLoops, crashInformation, markerInformation)
 }
@@ -141,6 +141,37 @@ func TestSequenceCrash(t *testing.T) {
 	goldenTest(t, settings, arrows, outcome)
 }
 
+const outcome_3 = `
+╭───╮       ╭───╮        ╭───╮
+│ A │       │ B │        │ C │
+╰─┬─╯       ╰─┬─╯        ╰─┬─╯
+  │   first   │            │
+  ├───────────▶            │
+[red]╭─┴─╮[-]       [yellow]╭─┴─╮[-]          │
+[red]│ ☠ │[-]       [yellow]│ ‖ │[-]          │
+[red]╰───╯[-]       [yellow]╰─┬─╯[-]          │
+              │["focused"][yellow]>>>second<<<[-][""]│
+              ├╌╌╌╌╌╌╌╌╌╌╌╌▶
+╭─┴─╮       ╭─┴─╮        ╭─┴─╮
+│ A │       │ B │        │ C │
+╰───╯       ╰───╯        ╰───╯
+`
+
+var settings_3 = DrawSettings{
+	MarkerSize: 3,
+	MarkAt:     1,
+	Crashes: map[int][]string{
+		1: []string{"A"},
+	},
+	Markers: map[int][]Marker{
+		1: []Marker{{Reactor: "B", Symbol: "‖", Color: "yellow"}},
+	},
+}
+
+func TestSequenceMarkers(t *testing.T) {
+	goldenTest(t, settings_3, arrows_2, outcome_3)
+}
+
 var theResultThatWeStoreForBenchmarking []byte
 
 // run with `go test -bench=Sequence -run XXX`
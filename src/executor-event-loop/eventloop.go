@@ -104,17 +104,26 @@ func (el *EventLoop) Run() {
 			}
 		case envelope := <-commands:
 			fmt.Printf("Found message\n")
-			me := envelope.Receiver.ToLocal()
-			el.LogicalTime.Merge(envelope.LogicalTime)
-			el.AddToLog(LogResumeContinuation, me, envelope.Message)
-			outgoingMessage, _ /*rui*/ := el.Executor.processEnvelope(envelope)
-			// TODO we should add rui to the log
-			// el.LogicalTime.Incr()
-			// el.AddToLog()
-			el.LogicalTime.Incr()
-			outgoing := el.toSchedulerEnvelope(envelope.Receiver, outgoingMessage, envelope.CorrelationId)
-			el.AddToLog(LogSend, me, outgoingMessage)
-			el.CommandTransport.Send(outgoing)
+			el.CommandTransport.Send(el.processCommand(envelope))
 		}
 	}
 }
+
+// Executes the command and logs it, what the reactors did, see
+// `ReactorStepInfo`, and the response to the scheduler.
+func (el *EventLoop) processCommand(envelope Envelope) Envelope {
+	me := envelope.Receiver.ToLocal()
+	el.LogicalTime.Merge(envelope.LogicalTime)
+	el.AddToLog(LogResumeContinuation, me, envelope.Message)
+	outgoingMessage, rui := el.Executor.processEnvelope(envelope)
+	stepInfo, err := json.Marshal(rui)
+	if err != nil {
+		panic(err)
+	}
+	el.LogicalTime.Incr()
+	el.AddToLog(LogStepInfo, me, Message{"StepInfo", stepInfo})
+	el.LogicalTime.Incr()
+	outgoing := el.toSchedulerEnvelope(envelope.Receiver, outgoingMessage, envelope.CorrelationId)
+	el.AddToLog(LogSend, me, outgoingMessage)
+	return outgoing
+}
@@ -0,0 +1,136 @@
+package executorEL
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/symbiont-io/detsys-testkit/src/lib"
+)
+
+// A reactor that stores what it receives and counts its restarts, only the
+// former survives a restart. It pretends to draw a random number when it's
+// initialised, and reports an error if that's after a restart.
+type node struct {
+	Stored   int `json:"stored"`
+	Restarts int `json:"restarts"`
+	draws    int
+	errors   []error
+}
+
+func (n *node) Receive(_ time.Time, _ string, _ lib.InEvent) []lib.OutEvent {
+	return nil
+}
+
+func (n *node) Tick(_ time.Time) []lib.OutEvent { return nil }
+
+func (n *node) Timer(_ time.Time) []lib.OutEvent { return nil }
+
+func (n *node) Init() []lib.OutEvent {
+	n.draws++
+	if n.Restarts > 0 {
+		n.errors = append(n.errors, fmt.Errorf("initialised after %d restarts", n.Restarts))
+	}
+	return nil
+}
+
+func (n *node) DrainRandDraws() int {
+	draws := n.draws
+	n.draws = 0
+	return draws
+}
+
+func (n *node) DrainErrors() []error {
+	errs := n.errors
+	n.errors = nil
+	return errs
+}
+
+func (n *node) DurableState() interface{} {
+	return n.Stored
+}
+
+func (n *node) Recover(snapshot json.RawMessage) error {
+	restarts := n.Restarts
+	*n = node{Restarts: restarts + 1, draws: n.draws}
+	return json.Unmarshal(snapshot, &n.Stored)
+}
+
+func (n *node) Invariants() []lib.Invariant {
+	return []lib.Invariant{{
+		Name: "never-restarted",
+		Check: func(topology lib.Topology) error {
+			if restarts := topology.Reactor("node").(*node).Restarts; restarts > 0 {
+				return fmt.Errorf("restarted %d times", restarts)
+			}
+			return nil
+		},
+	}}
+}
+
+func newTestEventLoop() *EventLoop {
+	executor := NewExecutor([]string{"node"}, func(_ string, _ *LogWriter) lib.Reactor {
+		return &node{Stored: 1}
+	}, nil)
+	return NewEventLoop(nil, nil, executor)
+}
+
+func restart(el *EventLoop) {
+	el.processCommand(Envelope{
+		Kind:     Request,
+		Sender:   el.SchedulerRef,
+		Message:  Message{"fault", json.RawMessage(`{"to":"node","event":"restart","at":"1970-01-01T00:00:01Z","args":{}}`)},
+		Receiver: RemoteRef{"executor", 0},
+	})
+}
+
+// The step info of the last step.
+func lastStepInfo(t *testing.T, el *EventLoop) ReactorsUpdateInfo {
+	for i := len(el.Log) - 1; i >= 0; i-- {
+		if el.Log[i].LogEntry.Direction != LogStepInfo {
+			continue
+		}
+		var rui ReactorsUpdateInfo
+		if err := json.Unmarshal(el.Log[i].LogEntry.Message.Message, &rui); err != nil {
+			t.Fatal(err)
+		}
+		return rui
+	}
+	t.Fatal("No step info in the log")
+	return nil
+}
+
+func TestStepInfoViolations(t *testing.T) {
+	el := newTestEventLoop()
+	restart(el)
+	got := lastStepInfo(t, el)["node"].Violations
+	if len(got) != 1 {
+		t.Errorf("Expected the violation to be logged, got %v", got)
+	}
+}
+
+func TestStepInfoDurableState(t *testing.T) {
+	el := newTestEventLoop()
+	restart(el)
+	if got := string(lastStepInfo(t, el)["node"].DurableState); got != "1" {
+		t.Errorf("Expected the durable state to be logged, got %s", got)
+	}
+}
+
+func TestStepInfoRandDraws(t *testing.T) {
+	el := newTestEventLoop()
+	restart(el)
+	if got := lastStepInfo(t, el)["node"].RandDraws; got == nil || *got != 1 {
+		t.Errorf("Expected the draws to be logged, got %v", got)
+	}
+}
+
+func TestStepInfoErrors(t *testing.T) {
+	el := newTestEventLoop()
+	restart(el)
+	got := lastStepInfo(t, el)["node"].Errors
+	if len(got) != 1 || got[0].Message != "initialised after 1 restarts" {
+		t.Errorf("Expected the error to be logged, got %+v", got)
+	}
+}
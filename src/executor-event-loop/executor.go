@@ -12,10 +12,20 @@ func jsonError(s string) string {
 	return fmt.Sprintf("{\"error\":\"%s\"}", s)
 }
 
+// Logged for every step, with the same fields as the execution steps of the
+// HTTP executor.
 type ReactorStepInfo struct {
-	SimulatedTime time.Time
-	LogLines      []string
-	StateDiff     json.RawMessage
+	SimulatedTime time.Time       `json:"simulated-time"`
+	LogLines      []string        `json:"log-lines"`
+	StateDiff     json.RawMessage `json:"diff"`
+	// The reactor's durable state after the step, see `lib.Durable`.
+	DurableState json.RawMessage `json:"durable-state,omitempty"`
+	// How many pseudo-random numbers the reactor drew, see `lib.RandReporter`.
+	RandDraws *int `json:"rand-draws,omitempty"`
+	// The errors the reactor reported, see `lib.ErrorReporter`.
+	Errors []lib.StepError `json:"errors,omitempty"`
+	// The invariants that were violated after the step, see `lib.Invariant`.
+	Violations []string `json:"violations,omitempty"`
 }
 
 type ReactorsUpdateInfo = map[string]ReactorStepInfo
@@ -59,9 +69,21 @@ func (ex Executor) DumpReactorLoglines(name string) []string {
 		panic(fmt.Sprintf("Couldn't find buffer for %s", name))
 	}
 	logs := buffer.dump()
+	if r, ok := ex.Topology.Reactor(name).(lib.LogReporter); ok {
+		logs = append(logs, r.DrainLogLines()...)
+	}
 	return logs
 }
 
+func randDraws(reactor lib.Reactor) *int {
+	r, ok := reactor.(lib.RandReporter)
+	if !ok {
+		return nil
+	}
+	draws := r.DrainRandDraws()
+	return &draws
+}
+
 func (ex Executor) Reset() {
 	ex.Topology, ex.Buffers = buildTopology(ex.BuildReactor, ex.Reactors)
 
@@ -76,6 +98,8 @@ func (el Executor) processEnvelope(env Envelope) (Message, ReactorsUpdateInfo) {
 	switch msg.Kind {
 	case "message":
 		fallthrough
+	case "io":
+		fallthrough
 	case "invoke":
 		var sev lib.ScheduledEvent
 		bytesToDeserialise := msg.Message
@@ -86,19 +110,27 @@ func (el Executor) processEnvelope(env Envelope) (Message, ReactorsUpdateInfo) {
 
 		reactorName := sev.To // should be from env
 		reactor := el.Topology.Reactor(reactorName)
+		if r, ok := reactor.(lib.RunAware); ok {
+			r.SetRun(sev.Meta)
+		}
 		heapBefore := dumpHeapJson(reactor)
 		oevs := reactor.Receive(sev.At, sev.From, sev.Event)
 		heapAfter := dumpHeapJson(reactor)
 		heapDiff := jsonDiff(heapBefore, heapAfter)
 		logLines := el.DumpReactorLoglines(reactorName)
+		violations, stop := lib.SummariseViolations(el.Topology.CheckInvariants())
 
 		rui[reactorName] = ReactorStepInfo{
 			SimulatedTime: sev.At,
 			LogLines:      logLines,
 			StateDiff:     heapDiff,
+			DurableState:  lib.MarshalDurableState(reactor),
+			RandDraws:     randDraws(reactor),
+			Errors:        lib.DrainStepErrors(reactor),
+			Violations:    violations,
 		}
 
-		returnMessage = lib.MarshalUnscheduledEvents(reactorName, int(env.CorrelationId), oevs)
+		returnMessage = lib.MarshalUnscheduledEventsAndStop(reactorName, int(env.CorrelationId), oevs, stop)
 	case "init":
 		var inits = make([]lib.Event, 0)
 
@@ -114,6 +146,9 @@ func (el Executor) processEnvelope(env Envelope) (Message, ReactorsUpdateInfo) {
 				SimulatedTime: time.Unix(0, 0).UTC(),
 				LogLines:      logLines,
 				StateDiff:     heapDiff,
+				DurableState:  lib.MarshalDurableState(reactor),
+				RandDraws:     randDraws(reactor),
+				Errors:        lib.DrainStepErrors(reactor),
 			}
 
 		}
@@ -128,52 +163,98 @@ func (el Executor) processEnvelope(env Envelope) (Message, ReactorsUpdateInfo) {
 		returnMessage = bs
 	case "timer":
 		type TimerRequest struct {
-			Reactor string       `json:"to"`
-			At      lib.TimePico `json:"at"`
-			Meta    lib.MetaInfo `json:"meta"`
+			Reactor string          `json:"to"`
+			At      lib.TimePico    `json:"at"`
+			Args    json.RawMessage `json:"args"`
+			Meta    lib.MetaInfo    `json:"meta"`
 		}
 		var req TimerRequest
 		if err := json.Unmarshal(msg.Message, &req); err != nil {
 			panic(err)
 		}
+		timer, err := lib.UnmarshalTimer(el.Marshaler, req.Args)
+		if err != nil {
+			panic(err)
+		}
 		reactor := el.Topology.Reactor(req.Reactor)
+		if r, ok := reactor.(lib.RunAware); ok {
+			r.SetRun(req.Meta)
+		}
 		heapBefore := dumpHeapJson(reactor)
-		oevs := reactor.Timer(time.Time(req.At))
+		oevs := lib.FireTimer(reactor, time.Time(req.At), timer)
 		heapAfter := dumpHeapJson(reactor)
 		heapDiff := jsonDiff(heapBefore, heapAfter)
 		logLines := el.DumpReactorLoglines(req.Reactor)
+		violations, stop := lib.SummariseViolations(el.Topology.CheckInvariants())
 		rui[req.Reactor] = ReactorStepInfo{
 			SimulatedTime: time.Time(req.At),
 			LogLines:      logLines,
 			StateDiff:     heapDiff,
+			DurableState:  lib.MarshalDurableState(reactor),
+			RandDraws:     randDraws(reactor),
+			Errors:        lib.DrainStepErrors(reactor),
+			Violations:    violations,
 		}
-		returnMessage = lib.MarshalUnscheduledEvents(req.Reactor, int(env.CorrelationId), oevs)
+		returnMessage = lib.MarshalUnscheduledEventsAndStop(req.Reactor, int(env.CorrelationId), oevs, stop)
 	case "fault":
-		// unclear how to log this as a ReactorsStepInfo...
 		type FaultRequest struct {
-			Reactor string `json:"to"`
-			Event   string `json:"event"`
+			Reactor string          `json:"to"`
+			Event   string          `json:"event"`
+			At      lib.TimePico    `json:"at"`
+			Args    json.RawMessage `json:"args"`
 		}
 		var req FaultRequest
 		if err := json.Unmarshal(msg.Message, &req); err != nil {
 			panic(err)
 		}
+		reactor := el.Topology.Reactor(req.Reactor)
+		heapBefore := dumpHeapJson(reactor)
+		var oevs []lib.OutEvent
+		// Faults that can't be applied are logged rather than failing the
+		// run.
+		var notApplied []string
 		switch req.Event {
 		case "restart":
-			buffer := el.Buffers[req.Reactor]
-			el.Topology.Insert(req.Reactor, el.BuildReactor(req.Reactor, buffer))
+			// Reactors that can tell their durable state apart from their
+			// volatile state lose only the latter, other reactors are rebuilt
+			// from scratch.
+			ok, err := lib.RestartReactor(reactor)
+			if err != nil {
+				panic(err)
+			}
+			if ok {
+				oevs = reactor.Init()
+			} else {
+				buffer := el.Buffers[req.Reactor]
+				reactor = el.BuildReactor(req.Reactor, buffer)
+				el.Topology.Insert(req.Reactor, reactor)
+			}
 		default:
-			fmt.Printf("Unhandled fault type %s\n", req.Event)
-			panic("Unhandled fault type")
+			// Other faults, such as the disk faults, are carried out by the
+			// reactor itself.
+			var fault lib.Fault
+			if err := json.Unmarshal(req.Args, &fault); err != nil {
+				fmt.Printf("Unhandled fault type %s\n", req.Event)
+				panic(err)
+			}
+			if r, ok := reactor.(lib.FaultInjectable); !ok {
+				notApplied = append(notApplied, "Fault not applied, the reactor doesn't support it: "+req.Event)
+			} else if err := r.InjectFault(fault); err != nil {
+				notApplied = append(notApplied, "Fault not applied: "+err.Error())
+			}
 		}
-		bs, err := json.Marshal(struct {
-			Events        []lib.Event   `json:"events"`
-			CorrelationId CorrelationId `json:"corrId"`
-		}{[]lib.Event{}, env.CorrelationId})
-		if err != nil {
-			panic(err)
+		heapAfter := dumpHeapJson(reactor)
+		violations, stop := lib.SummariseViolations(el.Topology.CheckInvariants())
+		rui[req.Reactor] = ReactorStepInfo{
+			SimulatedTime: time.Time(req.At),
+			LogLines:      append(el.DumpReactorLoglines(req.Reactor), notApplied...),
+			StateDiff:     jsonDiff(heapBefore, heapAfter),
+			DurableState:  lib.MarshalDurableState(reactor),
+			RandDraws:     randDraws(reactor),
+			Errors:        lib.DrainStepErrors(reactor),
+			Violations:    violations,
 		}
-		returnMessage = bs
+		returnMessage = lib.MarshalUnscheduledEventsAndStop(req.Reactor, int(env.CorrelationId), oevs, stop)
 
 	default:
 		fmt.Printf("Unknown message type: %#v\n", msg.Kind)
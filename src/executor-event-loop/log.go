@@ -10,6 +10,8 @@ type LogDirection int
 const (
 	LogSend = iota
 	LogResumeContinuation
+	// What the reactors did during a step, see `ReactorStepInfo`.
+	LogStepInfo
 )
 
 type LogEntry struct {
@@ -26,6 +28,8 @@ func (le LogEntry) MarshalJSON() ([]byte, error) {
 		tag = "LogSend"
 	case LogResumeContinuation:
 		tag = "LogResumeContinuation"
+	case LogStepInfo:
+		tag = "LogStepInfo"
 	}
 	j, err := json.Marshal(struct {
 		LocalRef  int       `json:"localRef"`
@@ -15,6 +15,14 @@ type ExecutionStepEvent struct {
 	SimulatedTime time.Time
 	LogLines      []string
 	HeapDiff      json.RawMessage
+	// The reactor's durable state after the step, see `lib.Durable`.
+	DurableState json.RawMessage
+	// How many pseudo-random numbers the reactor drew, if it reports that, see
+	// `lib.RandReporter`.
+	RandDraws *int
+	Errors    []lib.StepError
+	// The invariants that were violated after the step, see `lib.Invariant`.
+	Violations []string
 }
 
 func EmitExecutionStepEvent(db *sql.DB, event ExecutionStepEvent) {
@@ -34,13 +42,53 @@ func EmitExecutionStepEvent(db *sql.DB, event ExecutionStepEvent) {
 		SimulatedTime time.Time       `json:"simulated-time"`
 		LogLines      []string        `json:"log-lines"`
 		HeapDiff      json.RawMessage `json:"diff"`
+		DurableState  json.RawMessage `json:"durable-state,omitempty"`
+		RandDraws     *int            `json:"rand-draws,omitempty"`
+		Errors        []lib.StepError `json:"errors,omitempty"`
+		Violations    []string        `json:"violations,omitempty"`
 	}{
 		Reactor:       event.Reactor,
 		LogicalTime:   event.Meta.LogicalTime,
 		SimulatedTime: event.SimulatedTime,
 		LogLines:      event.LogLines,
 		HeapDiff:      event.HeapDiff,
+		DurableState:  event.DurableState,
+		RandDraws:     event.RandDraws,
+		Errors:        event.Errors,
+		Violations:    event.Violations,
 	}
 
 	lib.EmitEvent(db, "ExecutionStep", meta, data)
 }
+
+// The errors the reactor reported, see `lib.DrainStepErrors`. Ticks don't get
+// an execution step of their own unless they violate an invariant, so errors
+// from ticks end up in the reactor's next step.
+func reactorErrors(reactor lib.Reactor) []lib.StepError {
+	return lib.DrainStepErrors(reactor)
+}
+
+// Like errors, lines logged during ticks end up in the reactor's next step.
+func reactorLogLines(reactor lib.Reactor) []string {
+	r, ok := reactor.(lib.LogReporter)
+	if !ok {
+		return nil
+	}
+	return r.DrainLogLines()
+}
+
+// Like errors, draws made during ticks are counted in the reactor's next step.
+func reactorRandDraws(reactor lib.Reactor) *int {
+	r, ok := reactor.(lib.RandReporter)
+	if !ok {
+		return nil
+	}
+	draws := r.DrainRandDraws()
+	return &draws
+}
+
+func setRun(reactor lib.Reactor, meta lib.MetaInfo) {
+	if r, ok := reactor.(lib.RunAware); ok {
+		r.SetRun(meta)
+	}
+}
@@ -1,10 +1,12 @@
 package executor
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"strconv"
 	"time"
@@ -45,32 +47,39 @@ func handler(db *sql.DB, topology lib.Topology, m lib.Marshaler, cu ComponentUpd
 			panic(err)
 		}
 		reactor := topology.Reactor(sev.To)
+		setRun(reactor, sev.Meta)
 		heapBefore := dumpHeapJson(reactor)
 		oevs := reactor.Receive(sev.At, sev.From, sev.Event)
 		heapAfter := dumpHeapJson(reactor)
 		heapDiff := jsonDiff(heapBefore, heapAfter)
 		si := cu(sev.To)
+		violations, stop := lib.SummariseViolations(topology.CheckInvariants())
 
 		EmitExecutionStepEvent(db, ExecutionStepEvent{
 			Meta:          sev.Meta,
 			Reactor:       sev.To,
 			SimulatedTime: sev.At,
-			LogLines:      si.LogLines,
+			LogLines:      append(si.LogLines, reactorLogLines(reactor)...),
 			HeapDiff:      heapDiff,
+			DurableState:  lib.MarshalDurableState(reactor),
+			RandDraws:     reactorRandDraws(reactor),
+			Errors:        reactorErrors(reactor),
+			Violations:    violations,
 		})
 		corrId, err := strconv.Atoi(r.Header.Get("correlation-id"))
 		if err != nil {
 			corrId = -1
 		}
-		bs := lib.MarshalUnscheduledEvents(sev.To, corrId, oevs)
+		bs := lib.MarshalUnscheduledEventsAndStop(sev.To, corrId, oevs, stop)
 		fmt.Fprint(w, string(bs))
 	}
 }
 
-func handleTick(topology lib.Topology, m lib.Marshaler, cu ComponentUpdate) http.HandlerFunc {
+func handleTick(db *sql.DB, topology lib.Topology, m lib.Marshaler, cu ComponentUpdate) http.HandlerFunc {
 	type TickRequest struct {
-		Reactor string    `json:"reactor"`
-		At      time.Time `json:"at"`
+		Reactor string       `json:"reactor"`
+		At      time.Time    `json:"at"`
+		Meta    lib.MetaInfo `json:"meta"`
 	}
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
@@ -88,19 +97,40 @@ func handleTick(topology lib.Topology, m lib.Marshaler, cu ComponentUpdate) http
 		if err := json.Unmarshal(body, &req); err != nil {
 			panic(err)
 		}
-		oevs := topology.Reactor(req.Reactor).Tick(req.At)
-		cu(req.Reactor)
+		reactor := topology.Reactor(req.Reactor)
+		setRun(reactor, req.Meta)
+		heapBefore := dumpHeapJson(reactor)
+		oevs := reactor.Tick(req.At)
+		heapAfter := dumpHeapJson(reactor)
+		si := cu(req.Reactor)
+		violations, stop := lib.SummariseViolations(topology.CheckInvariants())
+		// Ticks only get an execution step of their own if they violate an
+		// invariant, otherwise what happened ends up in the next step.
+		if len(violations) > 0 {
+			EmitExecutionStepEvent(db, ExecutionStepEvent{
+				Meta:          req.Meta,
+				Reactor:       req.Reactor,
+				SimulatedTime: req.At,
+				LogLines:      append(si.LogLines, reactorLogLines(reactor)...),
+				HeapDiff:      jsonDiff(heapBefore, heapAfter),
+				DurableState:  lib.MarshalDurableState(reactor),
+				RandDraws:     reactorRandDraws(reactor),
+				Errors:        reactorErrors(reactor),
+				Violations:    violations,
+			})
+		}
 		// XXX: CorrId doesn't make sense here, right? Hence -1...
-		bs := lib.MarshalUnscheduledEvents(req.Reactor, -1, oevs)
+		bs := lib.MarshalUnscheduledEventsAndStop(req.Reactor, -1, oevs, stop)
 		fmt.Fprint(w, string(bs))
 	}
 }
 
 func handleTimer(db *sql.DB, topology lib.Topology, m lib.Marshaler, cu ComponentUpdate) http.HandlerFunc {
 	type TimerRequest struct {
-		Reactor string       `json:"to"`
-		At      time.Time    `json:"at"`
-		Meta    lib.MetaInfo `json:"meta"`
+		Reactor string          `json:"to"`
+		At      time.Time       `json:"at"`
+		Args    json.RawMessage `json:"args"`
+		Meta    lib.MetaInfo    `json:"meta"`
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -119,45 +149,162 @@ func handleTimer(db *sql.DB, topology lib.Topology, m lib.Marshaler, cu Componen
 		if err := json.Unmarshal(body, &req); err != nil {
 			panic(err)
 		}
+		timer, err := lib.UnmarshalTimer(m, req.Args)
+		if err != nil {
+			panic(err)
+		}
 
 		reactor := topology.Reactor(req.Reactor)
+		setRun(reactor, req.Meta)
 		heapBefore := dumpHeapJson(reactor)
-		oevs := reactor.Timer(req.At)
+		oevs := lib.FireTimer(reactor, req.At, timer)
 		heapAfter := dumpHeapJson(reactor)
 		heapDiff := jsonDiff(heapBefore, heapAfter)
 		si := cu(req.Reactor)
+		violations, stop := lib.SummariseViolations(topology.CheckInvariants())
 
 		EmitExecutionStepEvent(db, ExecutionStepEvent{
 			Meta:          req.Meta,
 			Reactor:       req.Reactor,
 			SimulatedTime: req.At,
-			LogLines:      si.LogLines,
+			LogLines:      append(si.LogLines, reactorLogLines(reactor)...),
 			HeapDiff:      heapDiff,
+			DurableState:  lib.MarshalDurableState(reactor),
+			RandDraws:     reactorRandDraws(reactor),
+			Errors:        reactorErrors(reactor),
+			Violations:    violations,
 		})
 		corrId, err := strconv.Atoi(r.Header.Get("correlation-id"))
 		if err != nil {
 			corrId = -1
 		}
-		bs := lib.MarshalUnscheduledEvents(req.Reactor, corrId, oevs)
+		bs := lib.MarshalUnscheduledEventsAndStop(req.Reactor, corrId, oevs, stop)
 		fmt.Fprint(w, string(bs))
 	}
 }
 
+func handleFault(db *sql.DB, topology lib.Topology, m lib.Marshaler, cu ComponentUpdate) http.HandlerFunc {
+	type FaultRequest struct {
+		Reactor string          `json:"to"`
+		Event   string          `json:"event"`
+		At      time.Time       `json:"at"`
+		Args    json.RawMessage `json:"args"`
+		Meta    lib.MetaInfo    `json:"meta"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if r.Method != "POST" {
+			http.Error(w, jsonError("Method is not supported."),
+				http.StatusNotFound)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, 1048576)
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			panic(err)
+		}
+		var req FaultRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			panic(err)
+		}
+
+		reactor := topology.Reactor(req.Reactor)
+		setRun(reactor, req.Meta)
+		heapBefore := dumpHeapJson(reactor)
+		var oevs []lib.OutEvent
+		// Faults that can't be applied are recorded with the execution step
+		// rather than failing the run.
+		var notApplied []string
+		if req.Event == "restart" {
+			ok, err := lib.RestartReactor(reactor)
+			if err != nil {
+				panic(err)
+			}
+			if !ok {
+				// Like the event loop executor, rebuild reactors that can't
+				// be restarted in place.
+				if reactor, ok = topology.Rebuild(req.Reactor); ok {
+					setRun(reactor, req.Meta)
+				} else {
+					reactor = topology.Reactor(req.Reactor)
+				}
+			}
+			if ok {
+				oevs = reactor.Init()
+			} else {
+				notApplied = append(notApplied, "Fault not applied, the reactor can't be restarted: "+req.Reactor)
+			}
+		} else {
+			// Other faults, such as the disk faults, are carried out by the
+			// reactor itself.
+			var fault lib.Fault
+			if err := json.Unmarshal(req.Args, &fault); err != nil {
+				http.Error(w, jsonError("Unknown fault: "+req.Event),
+					http.StatusBadRequest)
+				return
+			}
+			if injectable, ok := reactor.(lib.FaultInjectable); !ok {
+				notApplied = append(notApplied, "Fault not applied, the reactor doesn't support it: "+req.Event)
+			} else if err := injectable.InjectFault(fault); err != nil {
+				notApplied = append(notApplied, "Fault not applied: "+err.Error())
+			}
+		}
+		heapAfter := dumpHeapJson(reactor)
+		heapDiff := jsonDiff(heapBefore, heapAfter)
+		si := cu(req.Reactor)
+		violations, stop := lib.SummariseViolations(topology.CheckInvariants())
+
+		EmitExecutionStepEvent(db, ExecutionStepEvent{
+			Meta:          req.Meta,
+			Reactor:       req.Reactor,
+			SimulatedTime: req.At,
+			LogLines:      append(append(si.LogLines, reactorLogLines(reactor)...), notApplied...),
+			HeapDiff:      heapDiff,
+			DurableState:  lib.MarshalDurableState(reactor),
+			RandDraws:     reactorRandDraws(reactor),
+			Errors:        reactorErrors(reactor),
+			Violations:    violations,
+		})
+		corrId, err := strconv.Atoi(r.Header.Get("correlation-id"))
+		if err != nil {
+			corrId = -1
+		}
+		bs := lib.MarshalUnscheduledEventsAndStop(req.Reactor, corrId, oevs, stop)
+		fmt.Fprint(w, string(bs))
+	}
+}
+
+// The scheduler asks for the initial events once the run is created, so that
+// the reactors are initialised with the run's seed.
 func handleInits(topology lib.Topology, m lib.Marshaler) http.HandlerFunc {
+	type InitsRequest struct {
+		Meta lib.MetaInfo `json:"meta"`
+	}
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
-		if r.Method != "GET" {
+		if r.Method != "POST" {
 			http.Error(w, jsonError("Method is not supported."),
 				http.StatusNotFound)
 			return
 		}
+		r.Body = http.MaxBytesReader(w, r.Body, 1048576)
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			panic(err)
+		}
+		var req InitsRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			panic(err)
+		}
 
 		var inits []lib.Event
 
 		reactors := topology.Reactors()
-		for _, reactor := range reactors {
-			inits = append(inits,
-				lib.OutEventsToEvents(reactor, topology.Reactor(reactor).Init())...)
+		for _, name := range reactors {
+			reactor := topology.Reactor(name)
+			setRun(reactor, req.Meta)
+			inits = append(inits, lib.OutEventsToEvents(name, reactor.Init())...)
 		}
 
 		// Use `[]` for no events, rather than `null`, in the JSON encoding.
@@ -176,24 +323,82 @@ func handleInits(topology lib.Topology, m lib.Marshaler) http.HandlerFunc {
 	}
 }
 
-func DeployWithComponentUpdate(srv *http.Server, topology lib.Topology, m lib.Marshaler, cu ComponentUpdate) {
+// Binds the address the executor gets served on, `srv.Addr` or ":3001" if
+// that isn't set, and assigns the reactors of the topology that haven't been
+// assigned an executor to this one. Use port 0, e.g. "localhost:0", to let the
+// OS pick a free port.
+func Listen(srv *http.Server, topology lib.Topology) (net.Listener, error) {
+	addr := srv.Addr
+	if addr == "" {
+		addr = ":3001"
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	topology.SetDefaultExecutorUrl(executorUrl(l.Addr()))
+	return l, nil
+}
+
+func executorUrl(addr net.Addr) string {
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		panic(err)
+	}
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+		host = "localhost"
+	}
+	return fmt.Sprintf("http://%s/api/v1/", net.JoinHostPort(host, port))
+}
+
+func DeployListenerWithComponentUpdate(srv *http.Server, l net.Listener, topology lib.Topology, m lib.Marshaler, cu ComponentUpdate) {
 	mux := http.NewServeMux()
 
 	db := lib.OpenDB()
 	defer db.Close()
 
 	mux.HandleFunc("/api/v1/event", handler(db, topology, m, cu))
-	mux.HandleFunc("/api/v1/tick", handleTick(topology, m, cu))
+	mux.HandleFunc("/api/v1/tick", handleTick(db, topology, m, cu))
 	mux.HandleFunc("/api/v1/timer", handleTimer(db, topology, m, cu))
+	mux.HandleFunc("/api/v1/fault", handleFault(db, topology, m, cu))
 	mux.HandleFunc("/api/v1/inits", handleInits(topology, m))
 
-	srv.Addr = ":3001"
 	srv.Handler = mux
-	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+	if err := srv.Serve(l); err != http.ErrServerClosed {
 		panic(err)
 	}
 }
 
+func DeployListener(srv *http.Server, l net.Listener, topology lib.Topology, m lib.Marshaler) {
+	DeployListenerWithComponentUpdate(srv, l, topology, m, func(string) StepInfo { return StepInfo{} })
+}
+
+// Deploys the topology on a port picked by the OS, so that several tests can
+// run at the same time, and returns a function that tears it down. Meant for
+// `lib.Spec`. Unlike `lib.Setup` and `lib.Teardown` it keeps no global state,
+// so the workers of `lib.Explore` can deploy at the same time.
+func DeployTopology(topology lib.Topology, m lib.Marshaler) (func(), error) {
+	srv := &http.Server{Addr: "localhost:0"}
+	l, err := Listen(srv, topology)
+	if err != nil {
+		return nil, err
+	}
+	go DeployListener(srv, l, topology, m)
+	return func() {
+		if err := srv.Shutdown(context.Background()); err != nil {
+			panic(err)
+		}
+	}, nil
+}
+
+func DeployWithComponentUpdate(srv *http.Server, topology lib.Topology, m lib.Marshaler, cu ComponentUpdate) {
+	l, err := Listen(srv, topology)
+	if err != nil {
+		panic(err)
+	}
+	DeployListenerWithComponentUpdate(srv, l, topology, m, cu)
+}
+
 func Deploy(srv *http.Server, topology lib.Topology, m lib.Marshaler) {
 	DeployWithComponentUpdate(srv, topology, m, func(string) StepInfo { return StepInfo{} })
 }
@@ -205,9 +410,14 @@ func topologyFromDeployment(testId lib.TestId, constructor func(string) lib.Reac
 	if err != nil {
 		return topologyCooked, err
 	}
+	types := make(map[string]string)
 	for _, deploy := range deployments {
 		topologyCooked.Insert(deploy.Reactor, constructor(deploy.Type))
+		types[deploy.Reactor] = deploy.Type
 	}
+	topologyCooked.SetRebuild(func(reactor string) lib.Reactor {
+		return constructor(types[reactor])
+	})
 	return topologyCooked, nil
 }
 
@@ -278,6 +488,9 @@ func NewExecutor(marshaler lib.Marshaler, logger *zap.Logger, reactorNames []str
 		topology.Insert(reactorName, constructor(reactorName, buffer.AppendToLogger(logger)))
 		buffers[reactorName] = buffer
 	}
+	topology.SetRebuild(func(reactorName string) lib.Reactor {
+		return constructor(reactorName, buffers[reactorName].AppendToLogger(logger))
+	})
 
 	return &Executor{
 		topology:    topology,
@@ -288,8 +501,12 @@ func NewExecutor(marshaler lib.Marshaler, logger *zap.Logger, reactorNames []str
 	}
 }
 
-func (e *Executor) Deploy(srv *http.Server) {
-	DeployWithComponentUpdate(srv, e.topology, e.marshaler, func(name string) StepInfo {
+func (e *Executor) Listen(srv *http.Server) (net.Listener, error) {
+	return Listen(srv, e.topology)
+}
+
+func (e *Executor) DeployListener(srv *http.Server, l net.Listener) {
+	DeployListenerWithComponentUpdate(srv, l, e.topology, e.marshaler, func(name string) StepInfo {
 		buffer, ok := e.buffers[name]
 		if ok {
 			logs := make([]string, 0, len(buffer.current))
@@ -306,9 +523,18 @@ func (e *Executor) Deploy(srv *http.Server) {
 	})
 }
 
-func (e *Executor) Register() {
-	reactors := e.topology.Reactors()
-	lib.RegisterExecutor("http://localhost:3001/api/v1/", reactors)
+func (e *Executor) Deploy(srv *http.Server) {
+	l, err := e.Listen(srv)
+	if err != nil {
+		panic(err)
+	}
+	e.DeployListener(srv, l)
+}
+
+// Registers the executor with the scheduler, if the executor is deployed
+// concurrently then call `Listen` before this and `DeployListener` after.
+func (e *Executor) Register() error {
+	return lib.RegisterTopology(e.topology)
 }
 
 func (e *Executor) Reset() {
@@ -1,9 +1,16 @@
 package executor
 
 import (
+	"bytes"
+	"database/sql"
 	"encoding/json"
 	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -181,10 +188,11 @@ func TestSchedulerContractOutput(t *testing.T) {
 		// InternalMessage
 		// TODO(stevan): Is that all outgoing events from the executor?
 	}
-	got := lib.MarshalUnscheduledEvents("node", output)
-	expected := []byte(`{"events":
+	got := lib.MarshalUnscheduledEvents("node", -1, output)
+	expected := []byte(`{"corrId": -1,
+                             "events":
                               [{"from": "node",
-		                "to":   "client:0",
+		                "to":   ["client:0"],
 		                "kind": "ok",
 	                        "event":"value",
 		                "args": {"id": 0,
@@ -192,3 +200,140 @@ func TestSchedulerContractOutput(t *testing.T) {
 
 	equalUS(t, "Cannot mashal outgoing unscheduled events", expected, got)
 }
+
+// ---------------------------------------------------------------------
+// Ensure that the executor's address ends up in the topology.
+
+func TestListen(t *testing.T) {
+	topology := lib.NewTopology(
+		lib.Item{Name: "node", Reactor: nil},
+		lib.Item{Name: "other", Reactor: nil})
+	topology.SetReactorExecutorUrl("other", "http://localhost:4000/api/v1/")
+	srv := http.Server{Addr: "localhost:0"}
+	l, err := Listen(&srv, topology)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	_, port, err := net.SplitHostPort(l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := fmt.Sprintf("http://127.0.0.1:%s/api/v1/", port)
+	if got := topology.ExecutorUrl("node"); got != expected {
+		t.Errorf("Expected executor url %s, got %s", expected, got)
+	}
+	// Reactors that were assigned an executor keep it.
+	if got := topology.ExecutorUrl("other"); got != "http://localhost:4000/api/v1/" {
+		t.Errorf("Expected the other reactor to keep its executor, got %s", got)
+	}
+}
+
+// ---------------------------------------------------------------------
+// Ensure that invariants violated by a tick are recorded.
+
+// A reactor that counts its ticks.
+type ticker struct {
+	Ticks int `json:"ticks"`
+}
+
+func (t *ticker) Receive(_ time.Time, _ string, _ lib.InEvent) []lib.OutEvent {
+	return nil
+}
+
+func (t *ticker) Tick(_ time.Time) []lib.OutEvent {
+	t.Ticks++
+	return nil
+}
+
+func (t *ticker) Timer(_ time.Time) []lib.OutEvent { return nil }
+
+func (t *ticker) Init() []lib.OutEvent { return nil }
+
+func TestTickViolation(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "detsys.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE event_log (id INTEGER PRIMARY KEY, event TEXT, meta JSON, data JSON)`); err != nil {
+		t.Fatal(err)
+	}
+
+	topology := lib.NewTopology(lib.Item{Name: "node", Reactor: &ticker{}})
+	topology.AddInvariant(lib.Invariant{
+		Name: "no-ticks",
+		Check: func(topology lib.Topology) error {
+			if ticks := topology.Reactor("node").(*ticker).Ticks; ticks > 0 {
+				return fmt.Errorf("ticked %d times", ticks)
+			}
+			return nil
+		},
+		Stop: true,
+	})
+	cu := func(string) StepInfo { return StepInfo{} }
+
+	body := []byte(`{"reactor":"node","at":"1970-01-01T00:00:01Z","meta":{"test-id":1,"run-id":2,"logical-time":3,"seed":4}}`)
+	w := httptest.NewRecorder()
+	handleTick(db, topology, m, cu)(w, httptest.NewRequest("PUT", "/api/v1/tick", bytes.NewReader(body)))
+
+	var resp struct {
+		Stop string `json:"stop"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Stop == "" {
+		t.Errorf("Expected the tick to stop the run: %s", w.Body.String())
+	}
+
+	var event, meta, data string
+	if err := db.QueryRow(`SELECT event, meta, data FROM event_log`).Scan(&event, &meta, &data); err != nil {
+		t.Fatal(err)
+	}
+	var step struct {
+		Reactor     string   `json:"reactor"`
+		LogicalTime int      `json:"logical-time"`
+		Violations  []string `json:"violations"`
+	}
+	if err := json.Unmarshal([]byte(data), &step); err != nil {
+		t.Fatal(err)
+	}
+	if event != "ExecutionStep" || step.Reactor != "node" || step.LogicalTime != 3 ||
+		len(step.Violations) != 1 {
+		t.Errorf("Unexpected execution step: %s %s %s", event, meta, data)
+	}
+}
+
+// ---------------------------------------------------------------------
+// Ensure that reactors are initialised with the run's seed.
+
+// A reactor that sets a timer of a random duration when it's initialised.
+type dice struct{}
+
+func (_ dice) Init(env lib.Env) {
+	env.SetTimer(time.Duration(env.Rand().Intn(1000)) * time.Millisecond)
+}
+
+func (_ dice) Receive(_ lib.Env, _ string, _ lib.InEvent) {}
+
+func (_ dice) Tick(_ lib.Env) {}
+
+func (_ dice) Timer(_ lib.Env, _ lib.Timer) {}
+
+func TestInitsSeed(t *testing.T) {
+	inits := func(seed lib.Seed) string {
+		topology := lib.NewTopology(lib.Item{Name: "node", Reactor: lib.Host("node", dice{})})
+		body := fmt.Sprintf(`{"meta":{"test-id":1,"run-id":0,"logical-time":0,"seed":%d}}`, seed)
+		w := httptest.NewRecorder()
+		handleInits(topology, m)(w, httptest.NewRequest("POST", "/api/v1/inits", strings.NewReader(body)))
+		return w.Body.String()
+	}
+	if inits(1) != inits(1) {
+		t.Errorf("Expected the same inits from the same seed")
+	}
+	if one, two := inits(1), inits(2); one == two {
+		t.Errorf("Expected other inits from another seed, got %s", one)
+	}
+}
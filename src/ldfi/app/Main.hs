@@ -6,6 +6,7 @@
 
 module Main where
 
+import Data.Text (Text)
 import qualified Data.Text.IO as T
 import qualified Ldfi
 import Ldfi.FailureSpec (FailureSpec (FailureSpec))
@@ -33,9 +34,12 @@ gitHash = "unknown"
 
 type FailedRunHelpText = "Mark a RunId as having already failed in the test, the failures used in that run will not be generated again. This option can be repeated to mark several runs"
 
+type ExcludeFaultsHelpText = "A JSON array of faults, in the format of run_info, that will not be generated again, e.g. because they are being tried already. This option can be repeated to exclude several sets of faults"
+
 data Config = Config
   { testId :: Maybe Int <?> "Which TestId to test for",
     failedRunId :: [Int] <?> FailedRunHelpText,
+    excludeFaults :: [Text] <?> ExcludeFaultsHelpText,
     endOfFiniteFailures :: Maybe Int <?> "The logical time after which we can't generate faults",
     maxCrashes :: Maybe Int <?> "The number of crashes we are allowed to generate",
     endOfTime :: Maybe Int <?> "NOT USED",
@@ -58,7 +62,11 @@ go cfg help
     let mFailSpec = makeFailureSpec (unHelpful $ endOfFiniteFailures cfg) (unHelpful $ maxCrashes cfg) (unHelpful $ endOfTime cfg) (unHelpful $ limitNumberOfFaults cfg)
      in case (unHelpful $ testId cfg, mFailSpec) of
           (Just tid, Just failSpec) -> do
-            let testInformation = TestInformation tid (unHelpful $ failedRunId cfg)
+            let testInformation =
+                  TestInformation
+                    tid
+                    (unHelpful $ failedRunId cfg)
+                    (map parseFaults (unHelpful $ excludeFaults cfg))
             json <- Ldfi.run' sqliteStorage z3Solver testInformation failSpec
             T.putStrLn json
           (_, _) -> help
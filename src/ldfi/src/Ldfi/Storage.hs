@@ -6,12 +6,14 @@ module Ldfi.Storage where
 
 import Control.Arrow (second)
 import Control.Exception
-import Data.Aeson (decode)
+import Data.Aeson (Result (..), Value, decode, fromJSON, withObject, (.:))
+import Data.Aeson.Types (parseMaybe)
 import qualified Data.Binary.Builder as BB
 import Data.Hashable (Hashable)
 import Data.List (groupBy, intercalate)
 import Data.Map (Map)
 import qualified Data.Map as Map
+import Data.Maybe (mapMaybe)
 import Data.Set (Set)
 import qualified Data.Set as Set
 import Data.Text (Text)
@@ -51,7 +53,10 @@ data LdfiEvent = LdfiEvent
 
 data TestInformation = TestInformation
   { tiTestId :: TestId,
-    tiFailedRuns :: [RunId]
+    tiFailedRuns :: [RunId],
+    -- | Faults that are being tried, but haven't got runs yet, and so shouldn't
+    -- be generated again either.
+    tiExcludedFaults :: [[Fault]]
   }
 
 data Storage m = Storage
@@ -110,7 +115,7 @@ sqliteLoad testInformation = do
       conn
       "SELECT run_id,sender,receiver,recv_logical_time,sent_logical_time FROM network_trace \
       \ WHERE test_id = :testId \
-      \ AND kind <> 'timer' \
+      \ AND kind NOT IN ('timer', 'io', 'fault') \
       \ AND NOT dropped \
       \ AND NOT (sender   LIKE 'client:%') \
       \ AND NOT (receiver LIKE 'client:%') \
@@ -142,21 +147,35 @@ sqliteLoadFailure testInformation = do
       "SELECT run_id, faults FROM run_info WHERE test_id = :testId"
       [":testId" := testId] ::
       IO [(RunId, Text)]
-  return . toFailures failedRuns . map (second parse) $ r
+  return . toFailures failedRuns . map (second parseFaults) $ r
   where
     toFailures :: Set RunId -> [(Int, [Fault])] -> Failures
     toFailures failedRuns xs =
       Failures
-        { fFaultsFromFailedRuns = map snd . filter (flip Set.member failedRuns . fst) $ xs,
+        { fFaultsFromFailedRuns =
+            tiExcludedFaults testInformation
+              ++ (map snd . filter (flip Set.member failedRuns . fst) $ xs),
           fFaultsPerRun = Map.fromList xs
         }
-    parse :: Text -> [Fault]
-    parse s = case decode (BB.toLazyByteString $ TextE.encodeUtf8Builder s) of
-      Nothing -> error $ "Unable to parse faults: " ++ Text.unpack s
-      Just x -> map convert x
-    convert :: MF.Fault -> Fault
-    convert (MF.Omission f t a) = Omission (f, t) a
-    convert (MF.Crash f a) = Crash f a
+
+-- | Parses faults in the format of the `faults` column of `run_info`. The
+-- scheduler also injects faults that we don't solve for, e.g. partitions and
+-- restarts, those are ignored.
+parseFaults :: Text -> [Fault]
+parseFaults s = case decode (BB.toLazyByteString $ TextE.encodeUtf8Builder s) of
+  Nothing -> error $ "Unable to parse faults: " ++ Text.unpack s
+  Just xs -> mapMaybe convert xs
+  where
+    convert :: Value -> Maybe Fault
+    convert v
+      | kind v `notElem` map Just ["omission", "crash"] = Nothing
+      | otherwise = case fromJSON v of
+        Success (MF.Omission f t a) -> Just (Omission (f, t) a)
+        Success (MF.Crash f a) -> Just (Crash f a)
+        Error err -> error $ "Unable to parse faults: " ++ err ++ ": " ++ Text.unpack s
+
+    kind :: Value -> Maybe Text
+    kind = parseMaybe (withObject "Fault" (.: "kind"))
 
 -- TODO(stevan): What exactly do we need to store? Previous faults are no longer
 -- interesting.
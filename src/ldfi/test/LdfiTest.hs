@@ -1,5 +1,6 @@
 module LdfiTest where
 
+import qualified Data.Text as Text
 import Ldfi
 import Ldfi.FailureSpec
 import Ldfi.Prop
@@ -160,6 +161,20 @@ unit_broadcast2Run4 = do
           Omission ("A", "B") 1
         ] -- Minimal counterexample.
 
+------------------------------------------------------------------------
+-- Faults stored by the scheduler
+
+-- Faults that we don't solve for, such as partitions, are ignored.
+unit_parseFaultsPartition :: Assertion
+unit_parseFaultsPartition =
+  parseFaults
+    ( Text.pack
+        "[{\"kind\":\"omission\",\"from\":\"A\",\"to\":\"B\",\"at\":1},\
+        \{\"kind\":\"partition\",\"from\":\"\",\"to\":\"\",\"at\":1,\"until\":3,\"groups\":[[\"A\"],[\"B\",\"C\"]]},\
+        \{\"kind\":\"crash\",\"from\":\"C\",\"to\":\"\",\"at\":2}]"
+    )
+    @?= [Omission ("A", "B") 1, Crash "C" 2]
+
 ------------------------------------------------------------------------
 -- QuickCheck property
 
@@ -0,0 +1,86 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// The outcome of checking a run, stored as a `CheckResult` event so that the
+// `check_result` view records whether the run passed.
+type CheckResult struct {
+	// E.g. "detsys-checker", "ltl" or "linearizability".
+	Checker string `json:"checker"`
+	// The model or formula the run was checked against.
+	Model  string `json:"model"`
+	Valid  bool   `json:"valid"`
+	Reason string `json:"reason"`
+	// What the checker found, e.g. the operations that can't be linearized.
+	Anomalies interface{}   `json:"anomalies,omitempty"`
+	Duration  time.Duration `json:"duration-ns"`
+	// The results of the checkers of a combination, see `AllOf`. They're
+	// stored as results of their own, see `RunChecker`.
+	Results []CheckResult `json:"results,omitempty"`
+}
+
+func (r CheckResult) String() string {
+	verdict := "passed"
+	if !r.Valid {
+		verdict = "failed"
+	}
+	name := r.Checker
+	if r.Model != "" {
+		name = fmt.Sprintf("%s (%s)", r.Checker, r.Model)
+	}
+	if r.Reason == "" {
+		return fmt.Sprintf("%s %s", name, verdict)
+	}
+	return fmt.Sprintf("%s %s: %s", name, verdict, r.Reason)
+}
+
+func AppendCheckResult(testId TestId, runId RunId, result CheckResult) {
+	db := OpenDB()
+	defer db.Close()
+
+	meta := struct {
+		Component string `json:"component"`
+		TestId    TestId `json:"test-id"`
+		RunId     RunId  `json:"run-id"`
+	}{
+		Component: "checker",
+		TestId:    testId,
+		RunId:     runId,
+	}
+	EmitEvent(db, "CheckResult", meta, result)
+}
+
+// The results of the checks of the run, in the order they were made. The
+// anomalies are left as JSON.
+func LoadCheckResults(testId TestId, runId RunId) ([]CheckResult, error) {
+	db := OpenDB()
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT checker, model, valid, reason, anomalies, duration_ns
+                               FROM check_result
+                               WHERE test_id = ? AND run_id = ?`,
+		testId.TestId, runId.RunId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []CheckResult
+	for rows.Next() {
+		var result CheckResult
+		var anomalies []byte
+		if err := rows.Scan(&result.Checker, &result.Model, &result.Valid,
+			&result.Reason, &anomalies, &result.Duration); err != nil {
+			return nil, err
+		}
+		if anomalies != nil {
+			result.Anomalies = json.RawMessage(anomalies)
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,66 @@
+package lib
+
+import (
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Applies the migrations, given by the start of their file names, to the
+// database at `DETSYS_DB`.
+func applyMigrations(t *testing.T, db *sql.DB, migrations ...string) {
+	for _, migration := range migrations {
+		paths, err := filepath.Glob(filepath.Join("..", "db", "migrations", migration+"_*.sql"))
+		if err != nil || len(paths) != 1 {
+			t.Fatalf("Couldn't find migration %s: %v", migration, err)
+		}
+		bs, err := os.ReadFile(paths[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		up := strings.Split(strings.SplitN(string(bs), "-- +migrate Up", 2)[1], "-- +migrate Down")[0]
+		if _, err := db.Exec(up); err != nil {
+			t.Fatalf("Migration %s: %v", migration, err)
+		}
+	}
+}
+
+func TestCheckResultRoundTrip(t *testing.T) {
+	t.Setenv("DETSYS_DB", filepath.Join(t.TempDir(), "detsys.db"))
+	db := OpenDB()
+	defer db.Close()
+	var json1 int
+	if err := db.QueryRow(`SELECT json_valid('{}')`).Scan(&json1); err != nil {
+		t.Skip("SQLite was built without the JSON functions, which the views need")
+	}
+	applyMigrations(t, db, "0", "7")
+
+	results := []CheckResult{
+		{
+			Checker:   "linearizability",
+			Model:     "register",
+			Valid:     false,
+			Reason:    "read 2 after write 1",
+			Anomalies: json.RawMessage(`[{"op":"read","value":2}]`),
+			Duration:  3 * time.Millisecond,
+		},
+		{Checker: "ltl", Model: "[](x = 1)", Valid: true},
+	}
+	for _, result := range results {
+		AppendCheckResult(TestId{1}, RunId{2}, result)
+	}
+	AppendCheckResult(TestId{1}, RunId{3}, CheckResult{Checker: "other"})
+
+	loaded, err := LoadCheckResults(TestId{1}, RunId{2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, results) {
+		t.Errorf("Expected %+v, got %+v", results, loaded)
+	}
+}
@@ -1,22 +1,180 @@
 package lib
 
 import (
+	"errors"
 	"fmt"
 	"os/exec"
 	"strconv"
+	"strings"
+	"time"
 )
 
-func Check(model string, testId TestId, runId RunId) bool {
-	cmd := exec.Command("detsys-checker", model,
+// ---------------------------------------------------------------------
+// A `Checker` decides whether a run passed, e.g. `detsys-checker`, `detsys-ltl`,
+// the linearizability checker or a check written in Go. Checkers can be
+// combined with `AllOf` and `AnyOf`.
+
+type Checker interface {
+	// The error is for when the run couldn't be checked, e.g. because the
+	// checker isn't installed, rather than for when the run fails the check.
+	Check(testId TestId, runId RunId) (CheckResult, error)
+}
+
+// Overridden in tests, which don't have a database.
+var appendCheckResult = AppendCheckResult
+
+// Checks the run and stores the result as a `CheckResult` event. The results
+// of the checkers a combination combines are stored before its own, which
+// leaves out theirs.
+func RunChecker(checker Checker, testId TestId, runId RunId) (CheckResult, error) {
+	result, err := checker.Check(testId, runId)
+	if err != nil {
+		return result, err
+	}
+	var store func(result CheckResult)
+	store = func(result CheckResult) {
+		for _, r := range result.Results {
+			store(r)
+		}
+		result.Results = nil
+		appendCheckResult(testId, runId, result)
+	}
+	store(result)
+	return result, nil
+}
+
+// ---------------------------------------------------------------------
+
+type jepsenChecker struct {
+	model string
+}
+
+// Checks the run with `detsys-checker` against the model, e.g. "list-append".
+func NewJepsenChecker(model string) Checker {
+	return jepsenChecker{model}
+}
+
+func (c jepsenChecker) Check(testId TestId, runId RunId) (CheckResult, error) {
+	start := time.Now()
+	cmd := exec.Command("detsys-checker", c.model,
 		strconv.Itoa(testId.TestId),
 		strconv.Itoa(runId.RunId))
 
 	out, err := cmd.CombinedOutput()
 
+	result := CheckResult{
+		Checker: "detsys-checker",
+		Model:   c.model,
+		Valid:   err == nil,
+	}
 	if err != nil {
-		fmt.Printf("Error occured during analysis:\n%s\n%s\n", err, string(out))
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return result, err
+		}
+		result.Reason = strings.TrimSpace(string(out))
+	}
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+func Check(model string, testId TestId, runId RunId) bool {
+	result, err := RunChecker(NewJepsenChecker(model), testId, runId)
+
+	if err != nil || !result.Valid {
+		fmt.Printf("Error occured during analysis:\n%v\n%s\n", err, result.Reason)
 		return false
 	}
 
 	return true
 }
+
+// Checks the run with `detsys-checker` against the model, e.g. "list-append",
+// see `NewJepsenChecker`.
+func CheckModel(model string) Checker {
+	return NewJepsenChecker(model)
+}
+
+// ---------------------------------------------------------------------
+
+type funcChecker struct {
+	name  string
+	check func(testId TestId, runId RunId) (bool, string, error)
+}
+
+// A check written in Go, which returns whether the run passed and why not if
+// it didn't.
+func NewFuncChecker(name string, check func(testId TestId, runId RunId) (bool, string, error)) Checker {
+	return funcChecker{name, check}
+}
+
+func (c funcChecker) Check(testId TestId, runId RunId) (CheckResult, error) {
+	start := time.Now()
+	valid, reason, err := c.check(testId, runId)
+	return CheckResult{
+		Checker:  c.name,
+		Valid:    valid,
+		Reason:   reason,
+		Duration: time.Since(start),
+	}, err
+}
+
+// A check written in Go over the history of the run, see `LoadHistory`.
+func NewHistoryChecker(name string, check func(history []Op) (bool, string)) Checker {
+	return funcChecker{name, func(testId TestId, runId RunId) (bool, string, error) {
+		history, err := LoadHistory(testId, runId)
+		if err != nil {
+			return false, "", err
+		}
+		valid, reason := check(history)
+		return valid, reason, nil
+	}}
+}
+
+// ---------------------------------------------------------------------
+
+type combination struct {
+	name     string
+	checkers []Checker
+	// Whether all of the checkers need to pass, rather than any of them.
+	all bool
+}
+
+// Passes if all of the checkers do. Every checker is run, so that the result
+// says which of them failed.
+func AllOf(checkers ...Checker) Checker {
+	return combination{"all-of", checkers, true}
+}
+
+// Passes if any of the checkers does.
+func AnyOf(checkers ...Checker) Checker {
+	return combination{"any-of", checkers, false}
+}
+
+func (c combination) Check(testId TestId, runId RunId) (CheckResult, error) {
+	start := time.Now()
+	result := CheckResult{
+		Checker: c.name,
+		Valid:   c.all,
+		Results: []CheckResult{},
+	}
+	var failed []string
+	for _, checker := range c.checkers {
+		r, err := checker.Check(testId, runId)
+		if err != nil {
+			return result, err
+		}
+		result.Results = append(result.Results, r)
+		if r.Valid {
+			result.Valid = result.Valid || !c.all
+		} else {
+			result.Valid = result.Valid && !c.all
+			failed = append(failed, r.String())
+		}
+	}
+	if !result.Valid {
+		result.Reason = strings.Join(failed, "; ")
+	}
+	result.Duration = time.Since(start)
+	return result, nil
+}
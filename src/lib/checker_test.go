@@ -0,0 +1,50 @@
+package lib
+
+import (
+	"testing"
+)
+
+func TestCombinations(t *testing.T) {
+	defer func(old func(TestId, RunId, CheckResult)) { appendCheckResult = old }(appendCheckResult)
+	var stored []string
+	appendCheckResult = func(_ TestId, _ RunId, result CheckResult) {
+		stored = append(stored, result.Checker)
+	}
+	pass := NewFuncChecker("pass", func(TestId, RunId) (bool, string, error) {
+		return true, "", nil
+	})
+	fail := NewFuncChecker("fail", func(TestId, RunId) (bool, string, error) {
+		return false, "because", nil
+	})
+
+	result, err := RunChecker(AllOf(pass, AnyOf(fail, pass), fail), TestId{0}, RunId{0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid || result.Reason != "fail failed: because" || len(result.Results) != 3 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if !result.Results[1].Valid {
+		t.Errorf("Expected any-of to pass: %+v", result.Results[1])
+	}
+	expected := []string{"pass", "fail", "pass", "any-of", "fail", "all-of"}
+	if len(stored) != len(expected) {
+		t.Fatalf("Expected the results of %v to be stored, got %v", expected, stored)
+	}
+	for i := range expected {
+		if stored[i] != expected[i] {
+			t.Errorf("Expected the results of %v to be stored, got %v", expected, stored)
+		}
+	}
+
+	result, err = AnyOf(fail, fail).Check(TestId{0}, RunId{0})
+	if err != nil || result.Valid || result.Reason != "fail failed: because; fail failed: because" {
+		t.Errorf("Unexpected result: %+v, %v", result, err)
+	}
+
+	stored = nil
+	result, err = RunChecker(AnyOf(), TestId{0}, RunId{0})
+	if err != nil || result.Valid || len(stored) != 1 || stored[0] != "any-of" {
+		t.Errorf("Expected the empty combination to fail and be stored: %+v, %v", result, stored)
+	}
+}
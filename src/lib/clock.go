@@ -0,0 +1,91 @@
+package lib
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ---------------------------------------------------------------------
+// Every reactor has a clock of its own, which the scheduler's time is
+// translated to before it's handed to the reactor. The clocks are given per
+// reactor when the run is created, reactors without one see the scheduler's
+// time, and are skewed by `ClockSkew` faults.
+
+type Clock struct {
+	// How far ahead, or behind if negative, the clock is at the start of the
+	// run.
+	Offset time.Duration `json:"offset-ns"`
+	// How much faster, or slower if negative, the clock runs than the
+	// scheduler's, e.g. 0.001 gains a millisecond per second. Must be greater
+	// than -1, the schedulers reject runs with other clocks.
+	Drift float64 `json:"drift"`
+}
+
+// Rejects clocks that would run backwards or stand still.
+func ValidateClocks(clocks map[string]Clock) error {
+	reactors := make([]string, 0, len(clocks))
+	for reactor := range clocks {
+		reactors = append(reactors, reactor)
+	}
+	sort.Strings(reactors)
+	for _, reactor := range reactors {
+		if drift := clocks[reactor].Drift; drift <= -1 {
+			return fmt.Errorf("The drift of the clock of %s must be greater than -1, got: %v", reactor, drift)
+		}
+	}
+	return nil
+}
+
+// The reactor's local time at the scheduler's time `at`.
+func LocalTime(clock Clock, skews []ClockSkew, at time.Time) time.Time {
+	elapsed := at.Sub(time.Unix(0, 0))
+	local := at.Add(clock.Offset).Add(time.Duration(clock.Drift * float64(elapsed)))
+	for _, skew := range skews {
+		local = local.Add(skew.skew(elapsed))
+	}
+	return local
+}
+
+// How long a duration on the reactor's clock, e.g. of a timer, takes on the
+// scheduler's clock. Skews aren't taken into account, like they aren't for
+// timers that use a monotonic clock.
+func (c Clock) Global(d time.Duration) time.Duration {
+	return time.Duration(float64(d) / (1 + c.Drift))
+}
+
+// How much the skew has adjusted the clock, `elapsed` since the start of the
+// run.
+func (s ClockSkew) skew(elapsed time.Duration) time.Duration {
+	switch {
+	case elapsed < s.At:
+		return 0
+	case s.Over <= 0 || elapsed >= s.At+s.Over:
+		return s.By
+	default:
+		return time.Duration(float64(s.By) * float64(elapsed-s.At) / float64(s.Over))
+	}
+}
+
+// The clock skews of the reactor `node` among the faults of a run.
+func ClockSkews(faults []SchedulerFault, node string) []ClockSkew {
+	var skews []ClockSkew
+	for _, fault := range faults {
+		if fault.Kind == "clock-skew" && fault.From == node {
+			skews = append(skews, clockSkew(fault))
+		}
+	}
+	return skews
+}
+
+func clockSkew(s SchedulerFault) ClockSkew {
+	skew := ClockSkew{
+		Node: s.From,
+		At:   s.AtNs,
+		By:   s.ByNs,
+	}
+	if s.UntilNs > s.AtNs {
+		skew.Over = s.UntilNs - s.AtNs
+	}
+	return skew
+}
@@ -0,0 +1,49 @@
+package lib
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLocalTime(t *testing.T) {
+	start := time.Unix(0, 0).UTC()
+	clock := Clock{Offset: time.Second, Drift: 0.5}
+	faults := []SchedulerFault{
+		{Kind: "clock-skew", From: "a", AtNs: 2 * time.Second, ByNs: -time.Second},
+		{Kind: "clock-skew", From: "a", AtNs: 4 * time.Second, ByNs: 2 * time.Second, UntilNs: 6 * time.Second},
+		{Kind: "clock-skew", From: "b", AtNs: 0, ByNs: time.Hour},
+	}
+	skews := ClockSkews(faults, "a")
+	tests := []struct {
+		at    time.Duration
+		local time.Duration
+	}{
+		{0, time.Second},
+		{time.Second, 2500 * time.Millisecond},
+		// Jumps back.
+		{2 * time.Second, 3 * time.Second},
+		// Slews forward, half way through.
+		{5 * time.Second, 8500 * time.Millisecond},
+		{6 * time.Second, 11 * time.Second},
+	}
+	for _, test := range tests {
+		if got := LocalTime(clock, skews, start.Add(test.at)); !got.Equal(start.Add(test.local)) {
+			t.Errorf("At %v: expected %v, got %v", test.at, test.local, got.Sub(start))
+		}
+	}
+	if got := clock.Global(3 * time.Second); got != 2*time.Second {
+		t.Errorf("Expected a timer of 3s to take 2s, got %v", got)
+	}
+	if got := LocalTime(Clock{}, nil, start.Add(time.Second)); !got.Equal(start.Add(time.Second)) {
+		t.Errorf("Expected reactors without a clock to see the scheduler's time, got %v", got)
+	}
+}
+
+func TestValidateClocks(t *testing.T) {
+	if err := ValidateClocks(map[string]Clock{"a": {Drift: -0.5}, "b": {}}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := ValidateClocks(map[string]Clock{"a": {}, "b": {Drift: -1}}); err == nil {
+		t.Errorf("Expected a clock that stands still to be rejected")
+	}
+}
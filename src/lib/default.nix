@@ -2,7 +2,7 @@
 , pkgs ? import sources.nixpkgs {} }:
 with pkgs;
 
-assert lib.versionAtLeast go.version "1.15";
+assert lib.versionAtLeast go.version "1.18";
 
 let
   inherit (import sources.gitignore {}) gitignoreSource;
@@ -16,8 +16,9 @@ buildGoModule rec {
 
   postInstall = ''
     mkdir -p $out/src
-    # Don't copy over bazel build file, or bazel will try to build that
-    # directory.
-    cp $(ls $src | grep -v BUILD.bazel) $out/src
+    # Don't copy over bazel build files, or bazel will try to build those
+    # directories.
+    cp -r $(ls $src | grep -v BUILD.bazel) $out/src
+    find $out/src -name BUILD.bazel -delete
   '';
 }
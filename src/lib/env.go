@@ -0,0 +1,295 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ---------------------------------------------------------------------
+// An `Env` is everything a reactor may use besides its own state. Reactors
+// written against it, see `EnvReactor`, are deterministic by construction and
+// run the same no matter which executor hosts them.
+
+type Env interface {
+	// The name of the reactor in the topology.
+	Self() string
+	// The simulated time of the current step, plus the latency of the disk
+	// operations during it, if any.
+	Now() time.Time
+	// A pseudo-random number generator seeded from the run's seed, the
+	// reactor's name and its incarnation, see `ReactorRand`.
+	Rand() *rand.Rand
+	// Adds a line to the log of the current step, `keyvals` are alternating
+	// keys and values.
+	Log(msg string, keyvals ...interface{})
+	Send(to Receiver, msg Message)
+	Respond(client Receiver, id uint64, resp Response)
+	SetTimer(d time.Duration)
+	// Sets, or resets, the named timer `id`, see `Timer`. The payload may be
+	// nil.
+	SetNamedTimer(id string, d time.Duration, payload Message)
+	CancelTimer(id string)
+	// Submits an asynchronous I/O request, see `IORequest`. The completion is
+	// received from the reactor itself. I/O submitted while the disk is slow,
+	// see `DiskLatency`, takes longer.
+	SubmitIO(id uint64, request Message, latency time.Duration)
+	// The reactor's disk, which survives restarts but loses the writes that
+	// weren't synced, see `SimFS`.
+	FS() FS
+}
+
+type EnvReactor interface {
+	Init(env Env)
+	Receive(env Env, from string, event InEvent)
+	Tick(env Env)
+	// The timer that fired, its `Id` is empty for timers set with `SetTimer`.
+	Timer(env Env, timer Timer)
+}
+
+// Reactors that implement `RunAware` are told which run the next step belongs
+// to before the executor executes it.
+type RunAware interface {
+	SetRun(meta MetaInfo)
+}
+
+// Reactors that implement `LogReporter` have their log lines recorded with the
+// execution step, next to what was logged through the executor's logger.
+type LogReporter interface {
+	// Returns the log lines since the last call.
+	DrainLogLines() []string
+}
+
+type env struct {
+	self     string
+	fs       *SimFS
+	rand     *rand.Rand
+	logLines *[]string
+	oevs     []OutEvent
+}
+
+func (e *env) Self() string {
+	return e.self
+}
+
+func (e *env) Now() time.Time {
+	return e.fs.Now()
+}
+
+func (e *env) Rand() *rand.Rand {
+	return e.rand
+}
+
+func (e *env) Log(msg string, keyvals ...interface{}) {
+	*e.logLines = append(*e.logLines, formatLogLine(msg, keyvals))
+}
+
+func formatLogLine(msg string, keyvals []interface{}) string {
+	var b strings.Builder
+	b.WriteString(msg)
+	for i := 0; i < len(keyvals); i += 2 {
+		if i+1 < len(keyvals) {
+			fmt.Fprintf(&b, " %v=%+v", keyvals[i], keyvals[i+1])
+		} else {
+			fmt.Fprintf(&b, " %v=MISSING", keyvals[i])
+		}
+	}
+	return b.String()
+}
+
+func (e *env) Send(to Receiver, msg Message) {
+	e.oevs = append(e.oevs, OutEvent{
+		To:   Singleton(to),
+		Args: &InternalMessage{msg},
+	})
+}
+
+func (e *env) Respond(client Receiver, id uint64, resp Response) {
+	e.oevs = append(e.oevs, OutEvent{
+		To: Singleton(client),
+		Args: &ClientResponse{
+			Id:       id,
+			Response: resp,
+		},
+	})
+}
+
+func (e *env) SetTimer(d time.Duration) {
+	e.oevs = append(e.oevs, OutEvent{
+		To:   Singleton(e.self),
+		Args: &Timer{Duration: d},
+	})
+}
+
+func (e *env) SetNamedTimer(id string, d time.Duration, payload Message) {
+	e.oevs = append(e.oevs, OutEvent{
+		To: Singleton(e.self),
+		Args: &Timer{
+			Duration: d,
+			Id:       id,
+			Payload:  payload,
+		},
+	})
+}
+
+func (e *env) SubmitIO(id uint64, request Message, latency time.Duration) {
+	e.oevs = append(e.oevs, OutEvent{
+		To: Singleton(e.self),
+		Args: &IORequest{
+			Id:      id,
+			Request: request,
+			Latency: latency + e.fs.slowdown(),
+		},
+	})
+}
+
+func (e *env) FS() FS {
+	return e.fs
+}
+
+func (e *env) CancelTimer(id string) {
+	e.oevs = append(e.oevs, OutEvent{
+		To:   Singleton(e.self),
+		Args: &CancelTimer{Id: id},
+	})
+}
+
+// ---------------------------------------------------------------------
+// `Hosted` runs an `EnvReactor` as a `Reactor`, so that it can be put in a
+// `Topology` and deployed on any executor.
+
+type Hosted struct {
+	name    string
+	reactor EnvReactor
+	run     MetaInfo
+	// The scheduler's time of the next step, if the executor was told.
+	global   time.Time
+	rand     *ReactorRand
+	fs       *SimFS
+	logLines []string
+}
+
+func Host(name string, reactor EnvReactor) *Hosted {
+	h := &Hosted{
+		name:    name,
+		reactor: reactor,
+	}
+	h.rand = NewReactorRand(name)
+	h.fs = NewSimFS(name, reactorSeed(h.run.Seed, name+":fs", 0))
+	h.fs.SetLogger(func(line string) { h.logLines = append(h.logLines, line) })
+	return h
+}
+
+// The pseudo-random number generators, also the disk's, are reseeded when a new
+// run starts and the disk faults of the previous run are forgotten. The
+// executors set the run before calling `Init`, so its draws depend on the run's
+// seed too. The scheduler's time that comes along is what the disk faults of the
+// following step are relative to.
+func (h *Hosted) SetRun(meta MetaInfo) {
+	h.global = meta.SimulatedTime
+	if meta.TestId == h.run.TestId && meta.RunId == h.run.RunId && meta.Seed == h.run.Seed {
+		return
+	}
+	h.run = meta
+	h.rand.SetRun(meta)
+	h.fs.newRun(reactorSeed(meta.Seed, h.name+":fs", 0))
+}
+
+func (h *Hosted) step(at time.Time, f func(env Env)) []OutEvent {
+	h.fs.SetNow(at)
+	if !h.global.IsZero() {
+		h.fs.SetGlobalNow(h.global)
+		h.global = time.Time{}
+	}
+	e := &env{
+		self:     h.name,
+		fs:       h.fs,
+		rand:     h.rand.Rand,
+		logLines: &h.logLines,
+	}
+	f(e)
+	return e.oevs
+}
+
+func (h *Hosted) Receive(at time.Time, from string, event InEvent) []OutEvent {
+	return h.step(at, func(env Env) { h.reactor.Receive(env, from, event) })
+}
+
+func (h *Hosted) Tick(at time.Time) []OutEvent {
+	return h.step(at, h.reactor.Tick)
+}
+
+func (h *Hosted) Timer(at time.Time) []OutEvent {
+	return h.TimerFired(at, Timer{})
+}
+
+func (h *Hosted) TimerFired(at time.Time, timer Timer) []OutEvent {
+	return h.step(at, func(env Env) { h.reactor.Timer(env, timer) })
+}
+
+func (h *Hosted) Init() []OutEvent {
+	return h.step(time.Unix(0, 0).UTC(), h.reactor.Init)
+}
+
+// Disk faults are injected into the hosted reactor's disk.
+func (h *Hosted) InjectFault(fault Fault) error {
+	return h.fs.Inject(fault)
+}
+
+// A restart crashes the hosted reactor's disk, starts the next incarnation of
+// its pseudo-random number generator, and restarts the hosted reactor if it's
+// `Restartable`. Hosted reactors that aren't are expected to rebuild
+// their state from their disk in `Init`, which is called after the restart.
+func (h *Hosted) Restart() {
+	h.fs.Crash()
+	h.rand.Restarted()
+	if r, ok := h.reactor.(Restartable); ok {
+		r.Restart()
+	}
+}
+
+func (h *Hosted) DrainRandDraws() int {
+	return h.rand.DrainRandDraws()
+}
+
+func (h *Hosted) DrainLogLines() []string {
+	lines := h.logLines
+	h.logLines = nil
+	return lines
+}
+
+// Errors are passed on if the hosted reactor reports any.
+func (h *Hosted) DrainErrors() []error {
+	if r, ok := h.reactor.(ErrorReporter); ok {
+		return r.DrainErrors()
+	}
+	return nil
+}
+
+// Invariants are passed on if the hosted reactor has any.
+func (h *Hosted) Invariants() []Invariant {
+	if r, ok := h.reactor.(InvariantReporter); ok {
+		return r.Invariants()
+	}
+	return nil
+}
+
+// The executor records the state of the hosted reactor rather than the state
+// of the host.
+func (h *Hosted) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.reactor)
+}
+
+func (h *Hosted) ReactorType() string {
+	if t, ok := h.reactor.(interface{ ReactorType() string }); ok {
+		return t.ReactorType()
+	}
+	typ := reflect.TypeOf(h.reactor)
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return strings.ToLower(typ.Name())
+}
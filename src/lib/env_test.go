@@ -0,0 +1,163 @@
+package lib
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type jitter struct {
+	Draws []int `json:"draws"`
+}
+
+func (j *jitter) Init(env Env) {
+	env.SetTimer(time.Second)
+}
+
+func (j *jitter) Receive(env Env, from string, event InEvent) {
+	switch ev := event.(type) {
+	case *ClientRequest:
+		env.Log("request", "from", from, "id", ev.Id)
+		env.Respond(from, ev.Id, registryAck{})
+	case *InternalMessage:
+		env.Send(from, pong{})
+	}
+}
+
+func (j *jitter) Tick(env Env) {}
+
+func (j *jitter) Timer(env Env, timer Timer) {
+	switch timer.Id {
+	case "":
+		j.Draws = append(j.Draws, env.Rand().Intn(1000))
+	case "retry":
+		env.CancelTimer("giveup")
+	default:
+		env.SetNamedTimer("retry", time.Second, timer.Payload)
+	}
+}
+
+func TestHostedEnv(t *testing.T) {
+	h := Host("node", &jitter{})
+	at := time.Unix(1, 0).UTC()
+
+	oevs := h.Init()
+	if !reflect.DeepEqual(oevs, []OutEvent{{To: []Receiver{"node"}, Args: &Timer{Duration: time.Second}}}) {
+		t.Errorf("Unexpected init events: %+v", oevs)
+	}
+	oevs = h.Receive(at, "client:0", &ClientRequest{Id: 3, Request: incr{}})
+	if !reflect.DeepEqual(oevs, []OutEvent{{To: []Receiver{"client:0"}, Args: &ClientResponse{Id: 3, Response: registryAck{}}}}) {
+		t.Errorf("Unexpected response: %+v", oevs)
+	}
+	oevs = h.Receive(at, "other", &InternalMessage{ping{}})
+	if !reflect.DeepEqual(oevs, []OutEvent{{To: []Receiver{"other"}, Args: &InternalMessage{pong{}}}}) {
+		t.Errorf("Unexpected message: %+v", oevs)
+	}
+	if lines := h.DrainLogLines(); !reflect.DeepEqual(lines, []string{"request from=client:0 id=3"}) {
+		t.Errorf("Unexpected log lines: %v", lines)
+	}
+	if lines := h.DrainLogLines(); len(lines) != 0 {
+		t.Errorf("Expected the log lines to be drained, got: %v", lines)
+	}
+	oevs = h.TimerFired(at, Timer{Id: "start", Payload: ping{}})
+	if !reflect.DeepEqual(oevs, []OutEvent{{To: []Receiver{"node"}, Args: &Timer{Duration: time.Second, Id: "retry", Payload: ping{}}}}) {
+		t.Errorf("Unexpected named timer: %+v", oevs)
+	}
+	oevs = h.TimerFired(at, Timer{Id: "retry"})
+	if !reflect.DeepEqual(oevs, []OutEvent{{To: []Receiver{"node"}, Args: &CancelTimer{Id: "giveup"}}}) {
+		t.Errorf("Unexpected cancellation: %+v", oevs)
+	}
+	bs, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bs) != `{"draws":null}` {
+		t.Errorf("Unexpected JSON: %s", bs)
+	}
+	if typ := reactorType(h); typ != "jitter" {
+		t.Errorf("Unexpected reactor type: %s", typ)
+	}
+}
+
+func draws(name string, meta MetaInfo) []int {
+	j := &jitter{}
+	h := Host(name, j)
+	h.SetRun(meta)
+	for i := 0; i < 3; i++ {
+		h.Timer(time.Unix(0, 0))
+	}
+	return j.Draws
+}
+
+func TestHostedRandIsSeededByRun(t *testing.T) {
+	run := MetaInfo{TestId: TestId{1}, RunId: RunId{0}, Seed: 4}
+	if !reflect.DeepEqual(draws("a", run), draws("a", run)) {
+		t.Error("Expected the same draws for the same run")
+	}
+	if reflect.DeepEqual(draws("a", run), draws("b", run)) {
+		t.Error("Expected different reactors to draw different numbers")
+	}
+	other := run
+	other.Seed = 5
+	if reflect.DeepEqual(draws("a", run), draws("a", other)) {
+		t.Error("Expected different seeds to give different draws")
+	}
+	// Later steps of the same run don't reseed.
+	j := &jitter{}
+	h := Host("a", j)
+	h.SetRun(run)
+	h.Timer(time.Unix(0, 0))
+	h.SetRun(MetaInfo{TestId: run.TestId, RunId: run.RunId, Seed: run.Seed, LogicalTime: 7})
+	h.Timer(time.Unix(0, 0))
+	h.Timer(time.Unix(0, 0))
+	if !reflect.DeepEqual(j.Draws, draws("a", run)) {
+		t.Errorf("Expected the generator not to be reseeded within a run")
+	}
+}
+
+// Writes to its disk whenever its timer fires.
+type writer struct {
+	err error
+}
+
+func (w *writer) Init(env Env) {}
+
+func (w *writer) Receive(env Env, from string, event InEvent) {}
+
+func (w *writer) Tick(env Env) {}
+
+func (w *writer) Timer(env Env, timer Timer) {
+	f, err := env.FS().Open("data")
+	if err != nil {
+		w.err = err
+		return
+	}
+	_, w.err = f.Write([]byte("data"))
+}
+
+func TestHostedDiskFaultsUseGlobalTime(t *testing.T) {
+	w := &writer{}
+	h := Host("a", w)
+	run := MetaInfo{TestId: TestId{1}, RunId: RunId{0}, Seed: 4}
+	h.SetRun(run)
+	if err := h.InjectFault(Fault{Kind: "disk-full", Args: DiskFull{Node: "a", From: time.Second, To: 2 * time.Second}}); err != nil {
+		t.Fatal(err)
+	}
+	// The node's clock is ten seconds ahead.
+	local := time.Unix(10, 0).UTC()
+
+	run.SimulatedTime = time.Unix(1, 0).UTC()
+	h.SetRun(run)
+	h.Timer(local)
+	if w.err != ErrDiskFull {
+		t.Errorf("Expected the disk to be full, got: %v", w.err)
+	}
+
+	run.SimulatedTime = time.Unix(3, 0).UTC()
+	h.SetRun(run)
+	h.Timer(local.Add(time.Second))
+	if w.err != nil {
+		t.Errorf("Expected the disk not to be full, got: %v", w.err)
+	}
+}
@@ -0,0 +1,301 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ---------------------------------------------------------------------
+// `Explore` drives the lineage-driven fault injection loop: it runs the test
+// without faults, asks LDFI which faults to try next given the runs so far,
+// and runs the test again with those, until a run fails the check, LDFI runs
+// out of faults to try or the budget is spent.
+//
+// Every set of faults is tried with each of the seeds, and the runs are spread
+// over the workers. Every worker has a scheduler and an executor of its own,
+// so the runs of different workers happen at the same time. If there are more
+// workers than seeds, LDFI is asked for several sets of faults at a time, each
+// excluding the ones before it, so that those are tried at the same time too.
+
+type Spec struct {
+	// Creates the reactors afresh for every run.
+	Topology  func() Topology
+	Marshaler Marshaler
+	// The test is generated from the agenda and the topology, unless the
+	// agenda is empty in which case `TestId` is explored.
+	Agenda Agenda
+	TestId TestId
+	// Deploys an executor for the topology and returns a function that tears
+	// it down, see `executor.DeployTopology`.
+	Deploy func(topology Topology, marshaler Marshaler) (func(), error)
+	// The faults of the run are replaced by the ones LDFI suggests, and the
+	// seed by each of the seeds if any are given.
+	Run      CreateRunEvent
+	Seeds    []Seed
+	FailSpec FailSpec
+	// The run passes if it passes all of the checkers.
+	Checkers []Checker
+	// The most runs to try, zero means no limit.
+	MaxRuns int
+	// Defaults to `DefaultSchedulerClient()`.
+	Scheduler *SchedulerClient
+	// How many runs happen at the same time, defaults to one.
+	Workers int
+	// Starts a scheduler for a worker and returns a client for it and a
+	// function that stops it, see `scheduler.Start`. Needed if there's more
+	// than one worker, since a scheduler runs one run at a time.
+	NewScheduler func() (*SchedulerClient, func(), error)
+}
+
+// A run of the test, see `RunJobs`.
+type Job struct {
+	Seed   Seed
+	Faults []Fault
+}
+
+type RunReport struct {
+	RunId  RunId
+	Seed   Seed
+	Faults []Fault
+	Passed bool
+	// Which of the checkers failed and why.
+	Reason string
+	// The results of the spec's checkers, in the same order.
+	Results []CheckResult
+	Elapsed time.Duration
+}
+
+type Report struct {
+	TestId TestId
+	// In the order the faults were tried, and then in the order of the seeds.
+	Runs []RunReport
+	// The first run that failed the check, nil if none did.
+	Counterexample *RunReport
+	// Whether LDFI ran out of faults to try, i.e. the test passed with every
+	// combination of faults allowed by the `FailSpec`.
+	Exhausted bool
+	Elapsed   time.Duration
+}
+
+func (r Report) String() string {
+	switch {
+	case r.Counterexample != nil:
+		return fmt.Sprintf("test %d failed in run %d of %d with faults %v: %s",
+			r.TestId.TestId, r.Counterexample.RunId.RunId, len(r.Runs),
+			r.Counterexample.Faults, r.Counterexample.Reason)
+	case r.Exhausted:
+		return fmt.Sprintf("test %d passed all %d runs", r.TestId.TestId, len(r.Runs))
+	default:
+		return fmt.Sprintf("test %d passed %d runs before the budget was spent",
+			r.TestId.TestId, len(r.Runs))
+	}
+}
+
+// Overridden in tests, which don't have `detsys-ldfi`.
+var ldfi = LdfiExcluding
+
+// Asks LDFI for up to `n` different sets of faults to try next.
+func nextFaults(testId TestId, fail FailSpec, n int) [][]Fault {
+	var sets [][]Fault
+	for len(sets) < n {
+		faults := ldfi(testId, nil, sets, fail).Faults
+		if len(faults) == 0 {
+			break
+		}
+		sets = append(sets, faults)
+	}
+	return sets
+}
+
+// Returns the report so far along with the error, if the context is cancelled
+// or the scheduler, the executor or a checker fail.
+func Explore(ctx context.Context, spec Spec) (Report, error) {
+	start := time.Now()
+	if spec.Topology == nil || spec.Deploy == nil || len(spec.Checkers) == 0 {
+		return Report{}, errors.New("Explore: the spec needs a topology, a deploy and checkers")
+	}
+	report := Report{TestId: spec.TestId}
+	if len(spec.Agenda) > 0 {
+		report.TestId = GenerateTestFromTopologyAndAgenda(spec.Topology(), spec.Agenda)
+	}
+	seeds := spec.Seeds
+	if len(seeds) == 0 {
+		seeds = []Seed{spec.Run.Seed}
+	}
+	// Enough sets of faults to keep the workers busy.
+	sets := (spec.Workers + len(seeds) - 1) / len(seeds)
+	if sets < 1 {
+		sets = 1
+	}
+
+	faultSets := [][]Fault{nil}
+	for spec.MaxRuns == 0 || len(report.Runs) < spec.MaxRuns {
+		jobs := make([]Job, 0, len(faultSets)*len(seeds))
+		for _, faults := range faultSets {
+			log.Printf("Injecting faults: %#v\n", faults)
+			for _, seed := range seeds {
+				jobs = append(jobs, Job{Seed: seed, Faults: faults})
+			}
+		}
+		if spec.MaxRuns > 0 && len(report.Runs)+len(jobs) > spec.MaxRuns {
+			jobs = jobs[:spec.MaxRuns-len(report.Runs)]
+		}
+		runs, err := RunJobs(ctx, spec, report.TestId, jobs)
+		report.Runs = append(report.Runs, runs...)
+		if err != nil {
+			report.Elapsed = time.Since(start)
+			return report, err
+		}
+		for i := len(report.Runs) - len(runs); i < len(report.Runs); i++ {
+			if !report.Runs[i].Passed {
+				log.Printf("%+v and %+v doesn't pass analysis\n%s\n",
+					report.TestId, report.Runs[i].RunId, report.Runs[i].Reason)
+				report.Counterexample = &report.Runs[i]
+				break
+			}
+		}
+		if report.Counterexample != nil {
+			break
+		}
+		faultSets = nextFaults(report.TestId, spec.FailSpec, sets)
+		if len(faultSets) == 0 {
+			report.Exhausted = true
+			break
+		}
+	}
+	report.Elapsed = time.Since(start)
+	return report, nil
+}
+
+// Runs the jobs on the spec's workers and returns their reports in the order
+// of the jobs. If a run fails to complete the runs that haven't started are
+// skipped, and the reports of the runs that did complete are returned along
+// with the error.
+func RunJobs(ctx context.Context, spec Spec, testId TestId, jobs []Job) ([]RunReport, error) {
+	workers := spec.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+	if workers > 1 && spec.NewScheduler == nil {
+		return nil, errors.New("RunJobs: more than one worker needs a NewScheduler")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	reports := make([]*RunReport, len(jobs))
+	next := make(chan int)
+	go func() {
+		defer close(next)
+		for i := range jobs {
+			select {
+			case next <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	errs := make(chan error, workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			errs <- work(ctx, spec, testId, jobs, next, reports)
+		}()
+	}
+	var err error
+	for w := 0; w < workers; w++ {
+		if werr := <-errs; werr != nil && err == nil {
+			err = werr
+			cancel()
+		}
+	}
+
+	runs := make([]RunReport, 0, len(jobs))
+	for _, report := range reports {
+		if report != nil {
+			runs = append(runs, *report)
+		}
+	}
+	if err == nil && len(runs) < len(jobs) {
+		err = ctx.Err()
+	}
+	return runs, err
+}
+
+// A worker, which runs jobs on a scheduler of its own until there are none
+// left.
+func work(ctx context.Context, spec Spec, testId TestId, jobs []Job, next <-chan int, reports []*RunReport) error {
+	scheduler := spec.Scheduler
+	if spec.NewScheduler != nil {
+		var stop func()
+		var err error
+		scheduler, stop, err = spec.NewScheduler()
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
+	if scheduler == nil {
+		scheduler = DefaultSchedulerClient()
+	}
+	for i := range next {
+		report, err := runJob(ctx, spec, scheduler, testId, jobs[i])
+		if err != nil {
+			return err
+		}
+		reports[i] = &report
+	}
+	return nil
+}
+
+func runJob(ctx context.Context, spec Spec, scheduler *SchedulerClient, testId TestId, job Job) (RunReport, error) {
+	start := time.Now()
+	if err := scheduler.Reset(ctx); err != nil {
+		return RunReport{}, err
+	}
+	topology := spec.Topology()
+	topology.SetRebuild(func(reactor string) Reactor {
+		return spec.Topology().Reactor(reactor)
+	})
+	teardown, err := spec.Deploy(topology, spec.Marshaler)
+	if err != nil {
+		return RunReport{}, err
+	}
+	defer teardown()
+	qs, err := scheduler.LoadTest(ctx, testId)
+	if err != nil {
+		return RunReport{}, err
+	}
+	log.Printf("Loaded test of size: %d\n", qs.QueueSize)
+	if err := scheduler.RegisterTopology(ctx, topology); err != nil {
+		return RunReport{}, err
+	}
+	event := spec.Run
+	event.Seed = job.Seed
+	event.Faults = Faults{job.Faults}
+	runId, err := scheduler.CreateRun(ctx, testId, event)
+	if err != nil {
+		return RunReport{}, err
+	}
+	if err := scheduler.Run(ctx); err != nil {
+		return RunReport{}, err
+	}
+	log.Printf("Finished run id: %d\n", runId.RunId)
+	result, err := RunChecker(AllOf(spec.Checkers...), testId, runId)
+	if err != nil {
+		return RunReport{}, err
+	}
+	return RunReport{
+		RunId:   runId,
+		Seed:    job.Seed,
+		Faults:  job.Faults,
+		Passed:  result.Valid,
+		Reason:  result.Reason,
+		Results: result.Results,
+		Elapsed: time.Since(start),
+	}, nil
+}
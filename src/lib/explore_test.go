@@ -0,0 +1,224 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestExplore(t *testing.T) {
+	runs := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req SchedulerRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		switch req.Command {
+		case "load-test!":
+			fmt.Fprint(w, `{"queue-size":1}`)
+		case "create-run!":
+			fmt.Fprintf(w, `{"run-id":%d}`, runs)
+			runs++
+		default:
+			fmt.Fprint(w, `{}`)
+		}
+	}))
+	defer srv.Close()
+
+	omission := []Fault{{Kind: "omission", Args: Omission{From: "a", To: "b", At: 1}}}
+	defer func(old func(TestId, []RunId, [][]Fault, FailSpec) Faults) { ldfi = old }(ldfi)
+	defer func(old func(TestId, RunId, CheckResult)) { appendCheckResult = old }(appendCheckResult)
+	var stored []CheckResult
+	appendCheckResult = func(_ TestId, _ RunId, result CheckResult) {
+		stored = append(stored, result)
+	}
+	ldfi = func(TestId, []RunId, [][]Fault, FailSpec) Faults {
+		if runs == 1 {
+			return Faults{omission}
+		}
+		return Faults{[]Fault{}}
+	}
+
+	deployed := 0
+	spec := Spec{
+		Topology: func() Topology { return NewTopology() },
+		TestId:   TestId{1},
+		Deploy: func(Topology, Marshaler) (func(), error) {
+			deployed++
+			return func() { deployed-- }, nil
+		},
+		Checkers: []Checker{NewFuncChecker("pass", func(TestId, RunId) (bool, string, error) {
+			return true, "", nil
+		})},
+		Scheduler: NewSchedulerClient(srv.URL, srv.Client()),
+	}
+
+	report, err := Explore(context.Background(), spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Runs) != 2 || !report.Exhausted || report.Counterexample != nil || deployed != 0 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if len(stored) != 4 {
+		t.Errorf("Expected the checker's and the combination's result per run, got: %+v", stored)
+	}
+
+	runs = 0
+	spec.Checkers = append(spec.Checkers, NewFuncChecker("writes", func(testId TestId, runId RunId) (bool, string, error) {
+		return runId.RunId == 0, "dropped a write", nil
+	}))
+	report, err = Explore(context.Background(), spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := report.Counterexample; c == nil || c.RunId.RunId != 1 ||
+		!reflect.DeepEqual(c.Faults, omission) || c.Reason != "writes failed: dropped a write" ||
+		len(c.Results) != 2 || !c.Results[0].Valid || c.Results[1].Valid {
+		t.Errorf("Unexpected counterexample: %+v", report)
+	}
+
+	runs = 0
+	spec.MaxRuns = 1
+	report, err = Explore(context.Background(), spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Runs) != 1 || report.Exhausted || report.Counterexample != nil {
+		t.Errorf("Expected the budget to be spent, got: %+v", report)
+	}
+}
+
+func TestExploreInParallel(t *testing.T) {
+	var mu sync.Mutex
+	runs := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req SchedulerRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		switch req.Command {
+		case "create-run!":
+			mu.Lock()
+			fmt.Fprintf(w, `{"run-id":%d}`, runs)
+			runs++
+			mu.Unlock()
+		default:
+			fmt.Fprint(w, `{}`)
+		}
+	}))
+	defer srv.Close()
+
+	defer func(old func(TestId, []RunId, [][]Fault, FailSpec) Faults) { ldfi = old }(ldfi)
+	ldfi = func(TestId, []RunId, [][]Fault, FailSpec) Faults { return Faults{[]Fault{}} }
+	defer func(old func(TestId, RunId, CheckResult)) { appendCheckResult = old }(appendCheckResult)
+	appendCheckResult = func(TestId, RunId, CheckResult) {}
+
+	schedulers := 0
+	report, err := Explore(context.Background(), Spec{
+		Topology: func() Topology { return NewTopology() },
+		Deploy: func(Topology, Marshaler) (func(), error) {
+			return func() {}, nil
+		},
+		Seeds: []Seed{1, 2, 3, 4, 5},
+		Checkers: []Checker{NewFuncChecker("pass", func(TestId, RunId) (bool, string, error) {
+			return true, "", nil
+		})},
+		Workers: 3,
+		NewScheduler: func() (*SchedulerClient, func(), error) {
+			mu.Lock()
+			defer mu.Unlock()
+			schedulers++
+			return NewSchedulerClient(srv.URL, srv.Client()), func() {}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Runs) != 5 || !report.Exhausted || schedulers != 3 {
+		t.Fatalf("Unexpected report, from %d schedulers: %+v", schedulers, report)
+	}
+	runIds := make(map[int]bool)
+	for i, run := range report.Runs {
+		if run.Seed != Seed(i+1) {
+			t.Errorf("Expected the runs in the order of the seeds, got: %+v", report.Runs)
+		}
+		runIds[run.RunId.RunId] = true
+	}
+	if len(runIds) != 5 {
+		t.Errorf("Expected distinct run ids, got: %+v", report.Runs)
+	}
+}
+
+func TestExploreFaultSetsInParallel(t *testing.T) {
+	var mu sync.Mutex
+	runs := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req SchedulerRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		switch req.Command {
+		case "create-run!":
+			mu.Lock()
+			fmt.Fprintf(w, `{"run-id":%d}`, runs)
+			runs++
+			mu.Unlock()
+		default:
+			fmt.Fprint(w, `{}`)
+		}
+	}))
+	defer srv.Close()
+
+	// LDFI suggests omissions on three links, one at a time, and then runs out.
+	omission := func(to string) []Fault {
+		return []Fault{{Kind: "omission", Args: Omission{From: "a", To: to, At: 1}}}
+	}
+	suggestions := [][]Fault{omission("b"), omission("c"), omission("d")}
+	base, round := 0, 0
+	defer func(old func(TestId, []RunId, [][]Fault, FailSpec) Faults) { ldfi = old }(ldfi)
+	ldfi = func(_ TestId, _ []RunId, excluded [][]Fault, _ FailSpec) Faults {
+		if len(excluded) == 0 {
+			// The suggestions of the previous round have been tried.
+			base += round
+		}
+		round = len(excluded)
+		if i := base + len(excluded); i < len(suggestions) {
+			round++
+			return Faults{suggestions[i]}
+		}
+		return Faults{[]Fault{}}
+	}
+	defer func(old func(TestId, RunId, CheckResult)) { appendCheckResult = old }(appendCheckResult)
+	appendCheckResult = func(TestId, RunId, CheckResult) {}
+
+	report, err := Explore(context.Background(), Spec{
+		Topology: func() Topology { return NewTopology() },
+		Deploy: func(Topology, Marshaler) (func(), error) {
+			return func() {}, nil
+		},
+		Checkers: []Checker{NewFuncChecker("pass", func(TestId, RunId) (bool, string, error) {
+			return true, "", nil
+		})},
+		Workers: 2,
+		NewScheduler: func() (*SchedulerClient, func(), error) {
+			return NewSchedulerClient(srv.URL, srv.Client()), func() {}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var faults [][]Fault
+	for _, run := range report.Runs {
+		faults = append(faults, run.Faults)
+	}
+	expected := [][]Fault{nil, omission("b"), omission("c"), omission("d")}
+	if !report.Exhausted || !reflect.DeepEqual(faults, expected) {
+		t.Errorf("Expected the faults %v, got: %+v", expected, report)
+	}
+}
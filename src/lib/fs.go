@@ -0,0 +1,374 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// ---------------------------------------------------------------------
+// A file system for reactors to use instead of `os`, so that what they write
+// to disk is deterministic and subject to disk faults, see `SimFS`.
+
+type FS interface {
+	// Opens the file for reading and writing, creating it if it doesn't exist.
+	Open(name string) (File, error)
+	Rename(from string, to string) error
+	Remove(name string) error
+	// The names of all files, sorted.
+	List() []string
+}
+
+type File interface {
+	// Reads from, and writes at, the file's offset like `os.File` does.
+	Read(p []byte) (int, error)
+	Write(p []byte) (int, error)
+	Seek(offset int64, whence int) (int64, error)
+	// Writes are lost if the reactor crashes before they are synced.
+	Sync() error
+	Close() error
+}
+
+var (
+	ErrNotExist = errors.New("file does not exist")
+	ErrClosed   = errors.New("file already closed")
+	ErrDiskFull = errors.New("no space left on device")
+)
+
+// ---------------------------------------------------------------------
+// `SimFS` is an in-memory `FS`. Every file has the contents that reads see and
+// the contents that are on disk, which are the same after a sync. Likewise,
+// creating, renaming and removing files only changes what's on disk once any
+// file is synced, like a journaling file system commits its metadata along with
+// the data.
+//
+// The disk faults are injected with `Inject`, the pseudo-random choices they
+// involve are drawn from the seed.
+
+type SimFS struct {
+	// The disk faults of other nodes don't apply.
+	node  string
+	files map[string]*simFile
+	// The files on disk, by name.
+	disk map[string]*simFile
+	// The last write since the last sync, which a torn write partly persists.
+	lastWrite *simWrite
+	// Bumped by crashes, which close all open files.
+	incarnation int
+	rand        *rand.Rand
+	now         time.Time
+	// How far the node's clock is ahead of the scheduler's during the current
+	// step.
+	skew    time.Duration
+	faults  []Fault
+	applied map[int]bool
+	log     func(line string)
+}
+
+type simFile struct {
+	contents []byte
+	disk     []byte
+}
+
+type simWrite struct {
+	file   *simFile
+	offset int64
+	data   []byte
+}
+
+func NewSimFS(node string, seed int64) *SimFS {
+	return &SimFS{
+		node:    node,
+		files:   make(map[string]*simFile),
+		disk:    make(map[string]*simFile),
+		rand:    rand.New(rand.NewSource(seed)),
+		now:     time.Unix(0, 0).UTC(),
+		applied: make(map[int]bool),
+		log:     func(string) {},
+	}
+}
+
+// Adds a disk fault of the file system's node, see `DiskFull`, `TornWrite`,
+// `CorruptRead` and `DiskLatency`.
+func (fs *SimFS) Inject(fault Fault) error {
+	var node string
+	switch f := fault.Args.(type) {
+	case DiskFull:
+		node = f.Node
+	case TornWrite:
+		node = f.Node
+	case CorruptRead:
+		node = f.Node
+	case DiskLatency:
+		node = f.Node
+	default:
+		return fmt.Errorf("Not a disk fault: %s", fault.Kind)
+	}
+	if node != fs.node {
+		return fmt.Errorf("Disk fault of another node: %s", node)
+	}
+	fs.faults = append(fs.faults, fault)
+	fs.log(fmt.Sprintf("disk fault: %s injected", fault.Kind))
+	return nil
+}
+
+// Forgets the disk faults, which belong to a run, and reseeds.
+func (fs *SimFS) newRun(seed int64) {
+	fs.rand = rand.New(rand.NewSource(seed))
+	fs.faults = nil
+	fs.applied = make(map[int]bool)
+}
+
+// The time of the current step on the node's clock. Disk faults are relative
+// to it as well, unless `SetGlobalNow` is called after.
+func (fs *SimFS) SetNow(now time.Time) {
+	fs.now = now
+	fs.skew = 0
+}
+
+// The scheduler's time of the current step. Disk faults are injected for
+// windows of the scheduler's time, so whether they strike mustn't depend on the
+// node's clock offset, drift or skews.
+func (fs *SimFS) SetGlobalNow(now time.Time) {
+	fs.skew = fs.now.Sub(now)
+}
+
+// The time of the current step, plus the latency of the disk operations during
+// it.
+func (fs *SimFS) Now() time.Time {
+	return fs.now
+}
+
+// Where the disk faults that strike are logged.
+func (fs *SimFS) SetLogger(log func(line string)) {
+	fs.log = log
+}
+
+// The scheduler's time since the start of the run, including the latency of
+// the disk operations during the current step.
+func (fs *SimFS) since() time.Duration {
+	return fs.now.Add(-fs.skew).Sub(time.Unix(0, 0))
+}
+
+// The index of the first fault of the given kind that's active, if any. Faults
+// that happen at a time, rather than during an interval, strike only once.
+func (fs *SimFS) fault(kind string) (int, bool) {
+	since := fs.since()
+	for i, fault := range fs.faults {
+		if fault.Kind != kind {
+			continue
+		}
+		switch f := fault.Args.(type) {
+		case DiskFull:
+			if f.From <= since && since < f.To {
+				return i, true
+			}
+		case DiskLatency:
+			if f.From <= since && since < f.To {
+				return i, true
+			}
+		case TornWrite:
+			if f.At <= since && !fs.applied[i] {
+				return i, true
+			}
+		case CorruptRead:
+			if f.At <= since && !fs.applied[i] {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// How much longer disk operations take at the moment.
+func (fs *SimFS) slowdown() time.Duration {
+	if i, ok := fs.fault("disk-latency"); ok {
+		return fs.faults[i].Args.(DiskLatency).By
+	}
+	return 0
+}
+
+func (fs *SimFS) latency() {
+	fs.now = fs.now.Add(fs.slowdown())
+}
+
+// Drops everything that wasn't synced, as if the machine lost power, including
+// the files that were created, renamed or removed since. Open files are closed,
+// in the sense that their `File`s can no longer be used.
+func (fs *SimFS) Crash() {
+	if w := fs.lastWrite; w != nil {
+		if i, ok := fs.fault("torn-write"); ok {
+			fs.applied[i] = true
+			n := fs.rand.Intn(len(w.data))
+			w.file.disk = writeAt(w.file.disk, w.offset, w.data[:n])
+			fs.log(fmt.Sprintf("disk fault: torn write, %d of %d bytes persisted", n, len(w.data)))
+		}
+	}
+	fs.files = make(map[string]*simFile)
+	for name, f := range fs.disk {
+		f.contents = append([]byte{}, f.disk...)
+		fs.files[name] = f
+	}
+	fs.incarnation++
+	fs.lastWrite = nil
+}
+
+func writeAt(bs []byte, offset int64, data []byte) []byte {
+	if end := offset + int64(len(data)); end > int64(len(bs)) {
+		bs = append(bs, make([]byte, end-int64(len(bs)))...)
+	}
+	copy(bs[offset:], data)
+	return bs
+}
+
+func (fs *SimFS) Open(name string) (File, error) {
+	fs.latency()
+	f, ok := fs.files[name]
+	if !ok {
+		f = &simFile{}
+		fs.files[name] = f
+	}
+	return &simHandle{
+		fs:          fs,
+		name:        name,
+		file:        f,
+		incarnation: fs.incarnation,
+	}, nil
+}
+
+func (fs *SimFS) Rename(from string, to string) error {
+	fs.latency()
+	f, ok := fs.files[from]
+	if !ok {
+		return ErrNotExist
+	}
+	delete(fs.files, from)
+	fs.files[to] = f
+	return nil
+}
+
+func (fs *SimFS) Remove(name string) error {
+	fs.latency()
+	if _, ok := fs.files[name]; !ok {
+		return ErrNotExist
+	}
+	delete(fs.files, name)
+	return nil
+}
+
+func (fs *SimFS) List() []string {
+	names := make([]string, 0, len(fs.files))
+	for name := range fs.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type simHandle struct {
+	fs          *SimFS
+	name        string
+	file        *simFile
+	offset      int64
+	incarnation int
+	closed      bool
+}
+
+func (h *simHandle) check() error {
+	if h.closed || h.incarnation != h.fs.incarnation {
+		return ErrClosed
+	}
+	return nil
+}
+
+func (h *simHandle) Read(p []byte) (int, error) {
+	if err := h.check(); err != nil {
+		return 0, err
+	}
+	h.fs.latency()
+	if h.offset >= int64(len(h.file.contents)) {
+		return 0, io.EOF
+	}
+	n := copy(p, h.file.contents[h.offset:])
+	h.offset += int64(n)
+	if i, ok := h.fs.fault("corrupt-read"); ok && n > 0 {
+		h.fs.applied[i] = true
+		bit := h.fs.rand.Intn(n * 8)
+		p[bit/8] ^= 1 << (bit % 8)
+		h.fs.log(fmt.Sprintf("disk fault: corrupt read of %s", h.name))
+	}
+	return n, nil
+}
+
+func (h *simHandle) Write(p []byte) (int, error) {
+	if err := h.check(); err != nil {
+		return 0, err
+	}
+	h.fs.latency()
+	if _, ok := h.fs.fault("disk-full"); ok {
+		h.fs.log(fmt.Sprintf("disk fault: disk full, write to %s failed", h.name))
+		return 0, ErrDiskFull
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	h.file.contents = writeAt(h.file.contents, h.offset, p)
+	h.fs.lastWrite = &simWrite{
+		file:   h.file,
+		offset: h.offset,
+		data:   append([]byte{}, p...),
+	}
+	h.offset += int64(len(p))
+	return len(p), nil
+}
+
+func (h *simHandle) Seek(offset int64, whence int) (int64, error) {
+	if err := h.check(); err != nil {
+		return 0, err
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += h.offset
+	case io.SeekEnd:
+		offset += int64(len(h.file.contents))
+	default:
+		return 0, fmt.Errorf("Invalid whence: %d", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("Negative offset: %d", offset)
+	}
+	h.offset = offset
+	return offset, nil
+}
+
+func (h *simHandle) Sync() error {
+	if err := h.check(); err != nil {
+		return err
+	}
+	h.fs.latency()
+	if _, ok := h.fs.fault("disk-full"); ok {
+		h.fs.log(fmt.Sprintf("disk fault: disk full, sync of %s failed", h.name))
+		return ErrDiskFull
+	}
+	h.file.disk = append([]byte{}, h.file.contents...)
+	h.fs.disk = make(map[string]*simFile)
+	for name, f := range h.fs.files {
+		h.fs.disk[name] = f
+	}
+	if h.fs.lastWrite != nil && h.fs.lastWrite.file == h.file {
+		h.fs.lastWrite = nil
+	}
+	return nil
+}
+
+func (h *simHandle) Close() error {
+	if err := h.check(); err != nil {
+		return err
+	}
+	h.closed = true
+	return nil
+}
@@ -0,0 +1,136 @@
+package lib
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func readAll(t *testing.T, fs FS, name string) []byte {
+	f, err := fs.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	bs, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bs
+}
+
+func write(t *testing.T, f File, s string) {
+	if _, err := f.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSimFSCrash(t *testing.T) {
+	fs := NewSimFS("a", 1)
+	f, err := fs.Open("wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	write(t, f, "synced ")
+	if err := f.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	write(t, f, "unsynced")
+	if bs := readAll(t, fs, "wal"); string(bs) != "synced unsynced" {
+		t.Errorf("Unexpected contents before crash: %q", bs)
+	}
+	if err := fs.Rename("wal", "log"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Open("tmp"); err != nil {
+		t.Fatal(err)
+	}
+
+	fs.Crash()
+	if bs := readAll(t, fs, "wal"); string(bs) != "synced " {
+		t.Errorf("Unexpected contents after crash: %q", bs)
+	}
+	if _, err := f.Write([]byte("stale")); err != ErrClosed {
+		t.Errorf("Expected files to be closed by the crash, got: %v", err)
+	}
+	// Neither the rename nor the new file were synced.
+	if names := fs.List(); len(names) != 1 || names[0] != "wal" {
+		t.Errorf("Unexpected files: %v", names)
+	}
+
+	if err := fs.Rename("wal", "log"); err != nil {
+		t.Fatal(err)
+	}
+	g, err := fs.Open("log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	fs.Crash()
+	if names := fs.List(); len(names) != 1 || names[0] != "log" {
+		t.Errorf("Unexpected files after a synced rename: %v", names)
+	}
+}
+
+// Runs into every disk fault and returns what's on disk in the end.
+func diskFaults(t *testing.T, fs *SimFS) []byte {
+	start := time.Unix(0, 0).UTC()
+	faults := []Fault{
+		{Kind: "disk-full", Args: DiskFull{Node: "a", From: time.Second, To: 2 * time.Second}},
+		{Kind: "disk-latency", Args: DiskLatency{Node: "a", From: 0, To: time.Second, By: time.Millisecond}},
+		{Kind: "corrupt-read", Args: CorruptRead{Node: "a", At: 2 * time.Second}},
+		{Kind: "torn-write", Args: TornWrite{Node: "a", At: 2 * time.Second}},
+	}
+	for _, fault := range faults {
+		if err := fs.Inject(fault); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fs.Inject(Fault{Kind: "pause", Args: Pause{Node: "a"}}); err == nil {
+		t.Errorf("Expected pauses to be rejected")
+	}
+	if err := fs.Inject(Fault{Kind: "disk-full", Args: DiskFull{Node: "b"}}); err == nil {
+		t.Errorf("Expected the disk faults of other nodes to be rejected")
+	}
+
+	fs.SetNow(start)
+	f, _ := fs.Open("data")
+	write(t, f, "0123456789")
+	if now := fs.Now(); now != start.Add(2*time.Millisecond) {
+		t.Errorf("Expected the latency to show, got: %v", now)
+	}
+
+	fs.SetNow(start.Add(time.Second))
+	if _, err := f.Write([]byte("more")); err != ErrDiskFull {
+		t.Errorf("Expected the disk to be full, got: %v", err)
+	}
+	if err := f.Sync(); err != ErrDiskFull {
+		t.Errorf("Expected the disk to be full, got: %v", err)
+	}
+
+	fs.SetNow(start.Add(2 * time.Second))
+	if bs := readAll(t, fs, "data"); bytes.Equal(bs, []byte("0123456789")) {
+		t.Errorf("Expected the read to be corrupted")
+	}
+	if bs := readAll(t, fs, "data"); !bytes.Equal(bs, []byte("0123456789")) {
+		t.Errorf("Expected only the first read to be corrupted, got: %q", bs)
+	}
+
+	fs.Crash()
+	bs := readAll(t, fs, "data")
+	if len(bs) >= 10 || !bytes.HasPrefix([]byte("0123456789"), bs) {
+		t.Errorf("Expected a torn write, got: %q", bs)
+	}
+	return bs
+}
+
+func TestSimFSFaults(t *testing.T) {
+	bs := diskFaults(t, NewSimFS("a", 1))
+	// The same seed tears the write in the same place.
+	if again := diskFaults(t, NewSimFS("a", 1)); !bytes.Equal(again, bs) {
+		t.Errorf("Expected %q, got %q", bs, again)
+	}
+}
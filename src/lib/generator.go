@@ -29,7 +29,21 @@ func GenerateTest(test string) TestId {
 
 type Agenda = []ScheduledEvent
 
+// The reactor's type as recorded in the deployment, e.g. "register" for a
+// `*sut.Register`.
+func reactorType(r Reactor) string {
+	if t, ok := r.(interface{ ReactorType() string }); ok {
+		return t.ReactorType()
+	}
+	return strings.ToLower(strings.Split(reflect.TypeOf(r).String(), ".")[1])
+}
+
 func GenerateTestFromTopologyAndAgenda(topology Topology, agenda Agenda) TestId {
+	return generateTest(topology, agenda, nil)
+}
+
+// The generator, e.g. a `Workload`, is stored with the test if it's not nil.
+func generateTest(topology Topology, agenda Agenda, generator interface{}) TestId {
 	db := OpenDB()
 	defer db.Close()
 
@@ -109,20 +123,22 @@ func GenerateTestFromTopologyAndAgenda(topology Topology, agenda Agenda) TestId
 			panic(err)
 		}
 
-		typ := strings.ToLower(strings.Split(reflect.TypeOf(r).String(), ".")[1])
 		deployment = append(deployment, DeploymentInfo{
-			Reactor: reactor,
-			Type:    typ,
-			Args:    args,
+			Reactor:  reactor,
+			Type:     reactorType(r),
+			Args:     args,
+			Executor: topology.executors[reactor],
 		})
 	}
 
 	data := struct {
 		Agenda     []AgendaItem     `json:"agenda"`
 		Deployment []DeploymentInfo `json:"deployment"`
+		Generator  interface{}      `json:"generator,omitempty"`
 	}{
 		Agenda:     entries,
 		Deployment: deployment,
+		Generator:  generator,
 	}
 
 	EmitEvent(db, "CreateTest", meta, data)
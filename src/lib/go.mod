@@ -1,5 +1,5 @@
 module github.com/symbiont-io/detsys-testkit/src/lib
 
-go 1.15
+go 1.18
 
 require github.com/mattn/go-sqlite3 v1.14.5
@@ -0,0 +1,87 @@
+package lib
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ---------------------------------------------------------------------
+// Whitebox invariants over the states of a topology's reactors, e.g. "the
+// values of the registers are prefixes of each other". The executor checks
+// them after every step, records the violations with the execution step and
+// stops the run if a violation says so. Unlike the checkers, which look at the
+// run after it's finished, invariants see the reactors themselves.
+
+type Invariant struct {
+	Name string
+	// Returns why the invariant doesn't hold, nil if it does. Look the
+	// reactors up in the topology rather than closing over them, since a
+	// restart can replace a reactor with one built from scratch, see
+	// `Topology.Rebuild` and `Topology.Insert`, and the closure would keep
+	// checking the old one.
+	Check func(topology Topology) error
+	// Whether a violation stops the run.
+	Stop bool
+}
+
+// Reactors that implement `InvariantReporter` have their invariants checked
+// along with the ones added to the topology.
+type InvariantReporter interface {
+	Invariants() []Invariant
+}
+
+type Violation struct {
+	Invariant string
+	Reason    string
+	Stop      bool
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s", v.Invariant, v.Reason)
+}
+
+// Adds an invariant that the executor checks after every step.
+func (t Topology) AddInvariant(invariant Invariant) {
+	if t.invariants == nil {
+		panic("The topology wasn't made with NewTopology, so it can't have invariants")
+	}
+	*t.invariants = append(*t.invariants, invariant)
+}
+
+// Checks the topology's invariants, and then those of its reactors in the
+// order of their names.
+func (t Topology) CheckInvariants() []Violation {
+	var invariants []Invariant
+	if t.invariants != nil {
+		invariants = append(invariants, *t.invariants...)
+	}
+	for _, name := range t.Reactors() {
+		if r, ok := t.Reactor(name).(InvariantReporter); ok {
+			invariants = append(invariants, r.Invariants()...)
+		}
+	}
+	var violations []Violation
+	for _, invariant := range invariants {
+		if err := invariant.Check(t); err != nil {
+			violations = append(violations, Violation{
+				Invariant: invariant.Name,
+				Reason:    err.Error(),
+				Stop:      invariant.Stop,
+			})
+		}
+	}
+	return violations
+}
+
+// The violations as they're recorded with the execution step, and why the run
+// should stop, which is empty unless one of the violations stops it.
+func SummariseViolations(violations []Violation) ([]string, string) {
+	var vs, stops []string
+	for _, v := range violations {
+		vs = append(vs, v.String())
+		if v.Stop {
+			stops = append(stops, v.String())
+		}
+	}
+	return vs, strings.Join(stops, "; ")
+}
@@ -0,0 +1,79 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// A reactor that requires its draws to stay below a bound.
+type bounded struct {
+	jitter
+	bound int
+}
+
+func (b *bounded) Invariants() []Invariant {
+	return []Invariant{{
+		Name: "bounded",
+		Check: func(topology Topology) error {
+			for _, draw := range b.Draws {
+				if draw >= b.bound {
+					return fmt.Errorf("drew %d", draw)
+				}
+			}
+			return nil
+		},
+	}}
+}
+
+func TestCheckInvariants(t *testing.T) {
+	a := &bounded{bound: 10}
+	b := &bounded{bound: 10}
+	topology := NewTopology(Item{"b", Host("b", b)}, Item{"a", Host("a", a)})
+	draws := func(topology Topology, reactor string) []int {
+		return topology.Reactor(reactor).(*Hosted).reactor.(*bounded).Draws
+	}
+	topology.AddInvariant(Invariant{
+		Name: "same",
+		Check: func(topology Topology) error {
+			if len(draws(topology, "a")) != len(draws(topology, "b")) {
+				return errors.New("a and b drew a different number of times")
+			}
+			return nil
+		},
+		Stop: true,
+	})
+
+	if violations := topology.CheckInvariants(); len(violations) != 0 {
+		t.Errorf("Unexpected violations: %v", violations)
+	}
+
+	a.Draws = []int{12}
+	b.Draws = []int{3, 11}
+	violations := topology.CheckInvariants()
+	expected := []Violation{
+		{Invariant: "same", Reason: "a and b drew a different number of times", Stop: true},
+		{Invariant: "bounded", Reason: "drew 12"},
+		{Invariant: "bounded", Reason: "drew 11"},
+	}
+	if !reflect.DeepEqual(violations, expected) {
+		t.Errorf("Expected %v, got %v", expected, violations)
+	}
+
+	vs, stop := SummariseViolations(violations)
+	if !reflect.DeepEqual(vs, []string{
+		"same: a and b drew a different number of times",
+		"bounded: drew 12",
+		"bounded: drew 11",
+	}) {
+		t.Errorf("Unexpected violations: %v", vs)
+	}
+	if stop != "same: a and b drew a different number of times" {
+		t.Errorf("Unexpected reason to stop: %q", stop)
+	}
+
+	if violations := (Topology{}).CheckInvariants(); len(violations) != 0 {
+		t.Errorf("Unexpected violations of the empty topology: %v", violations)
+	}
+}
@@ -2,6 +2,7 @@ package lib
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"os"
 	"os/exec"
@@ -39,40 +40,251 @@ type Crash struct {
 
 func (_ Crash) FaultArgs() {}
 
+// Messages between reactors in different groups are dropped from logical time
+// `From` until, but not including, `To`. A `To` of 0 means the partition never
+// heals. Reactors that aren't in any of the groups, e.g. clients, are not
+// affected.
+type Partition struct {
+	Groups [][]string
+	From   int
+	To     int
+}
+
+func (_ Partition) FaultArgs() {}
+
+// Whether the partition separates `from` and `to` at logical time `at`.
+func (p Partition) Separates(from string, to string, at int) bool {
+	if at < p.From || (p.To != 0 && at >= p.To) {
+		return false
+	}
+	group := func(reactor string) int {
+		for i, g := range p.Groups {
+			for _, r := range g {
+				if r == reactor {
+					return i
+				}
+			}
+		}
+		return -1
+	}
+	i, j := group(from), group(to)
+	return i != -1 && j != -1 && i != j
+}
+
+// The message from `From` to `To` received at logical time `At` is delivered
+// twice.
+type Duplicate struct {
+	From string
+	To   string
+	At   int
+}
+
+func (_ Duplicate) FaultArgs() {}
+
+// The message from `From` to `To` that would have been received at logical
+// time `At` is held back for `By` instead.
+type Delay struct {
+	From string
+	To   string
+	At   int
+	By   time.Duration
+}
+
+func (_ Delay) FaultArgs() {}
+
+// The message from `From` to `To` that would have been received at logical
+// time `At` swaps places with the next message on the same link, if there is
+// one.
+type Reorder struct {
+	From string
+	To   string
+	At   int
+}
+
+func (_ Reorder) FaultArgs() {}
+
+// Unlike the faults above, pauses and restarts happen at a simulated time,
+// relative to the start of the run.
+
+// The reactor `Node` stalls from `From` until `To`: the messages and timers for
+// it queue up and are delivered once it resumes, and it doesn't tick in
+// between.
+type Pause struct {
+	Node string
+	From time.Duration
+	To   time.Duration
+}
+
+func (_ Pause) FaultArgs() {}
+
+// The reactor `Node` restarts at `At`, losing its volatile state, see
+// `Restartable`.
+type Restart struct {
+	Node string
+	At   time.Duration
+}
+
+func (_ Restart) FaultArgs() {}
+
+// Disk faults target a reactor's disk, the executors hand them to the reactor,
+// see `FaultInjectable`. They happen at a simulated time, relative to the start
+// of the run.
+
+// Writes and syncs of the reactor `Node` fail with `ErrDiskFull` from `From`
+// until `To`.
+type DiskFull struct {
+	Node string
+	From time.Duration
+	To   time.Duration
+}
+
+func (_ DiskFull) FaultArgs() {}
+
+// If the reactor `Node` crashes at or after `At`, its last unsynced write is
+// partly persisted, rather than lost.
+type TornWrite struct {
+	Node string
+	At   time.Duration
+}
+
+func (_ TornWrite) FaultArgs() {}
+
+// The first read by the reactor `Node` at or after `At` returns data with one
+// bit flipped, the disk itself is left intact.
+type CorruptRead struct {
+	Node string
+	At   time.Duration
+}
+
+func (_ CorruptRead) FaultArgs() {}
+
+// Every disk operation of the reactor `Node` from `From` until `To` takes `By`,
+// which shows in `Env.Now`.
+type DiskLatency struct {
+	Node string
+	From time.Duration
+	To   time.Duration
+	By   time.Duration
+}
+
+func (_ DiskLatency) FaultArgs() {}
+
+// The clock of the reactor `Node` jumps by `By`, backwards if negative, at
+// `At`, in the scheduler's simulated time. If `Over` isn't zero the clock
+// slews instead, it's adjusted gradually from `At` until `At + Over`. See
+// `Clock`.
+type ClockSkew struct {
+	Node string
+	At   time.Duration
+	By   time.Duration
+	Over time.Duration
+}
+
+func (_ ClockSkew) FaultArgs() {}
+
 type Faults = struct {
 	Faults []Fault `json:"faults"`
 }
 
+// Faults are unmarshaled from the output of `detsys-ldfi` and from the
+// `SchedulerFault`s that the scheduler stores in `run_info`.
 func (f *Fault) UnmarshalJSON(bs []byte) error {
-	var s map[string]interface{}
+	var s SchedulerFault
 	if err := json.Unmarshal(bs, &s); err != nil {
 		return err
 	}
-	switch strings.ToLower(s["kind"].(string)) {
+	kind := strings.ToLower(s.Kind)
+	var args FaultArgs
+	switch kind {
 	case "omission":
-		*f = Fault{
-			Kind: "omission",
-			Args: Omission{
-				From: s["from"].(string),
-				To:   s["to"].(string),
-				At:   int(s["at"].(float64)),
-			},
+		args = Omission{
+			From: s.From,
+			To:   s.To,
+			At:   s.At,
 		}
 	case "crash":
-		*f = Fault{
-			Kind: "crash",
-			Args: Crash{
-				From: s["from"].(string),
-				At:   int(s["at"].(float64)),
-			},
+		args = Crash{
+			From: s.From,
+			At:   s.At,
+		}
+	case "partition":
+		args = Partition{
+			Groups: s.Groups,
+			From:   s.At,
+			To:     s.Until,
+		}
+	case "duplicate":
+		args = Duplicate{
+			From: s.From,
+			To:   s.To,
+			At:   s.At,
+		}
+	case "delay":
+		args = Delay{
+			From: s.From,
+			To:   s.To,
+			At:   s.At,
+			By:   s.ByNs,
+		}
+	case "reorder":
+		args = Reorder{
+			From: s.From,
+			To:   s.To,
+			At:   s.At,
+		}
+	case "pause":
+		args = Pause{
+			Node: s.From,
+			From: s.AtNs,
+			To:   s.UntilNs,
+		}
+	case "restart":
+		args = Restart{
+			Node: s.From,
+			At:   s.AtNs,
+		}
+	case "disk-full":
+		args = DiskFull{
+			Node: s.From,
+			From: s.AtNs,
+			To:   s.UntilNs,
 		}
+	case "torn-write":
+		args = TornWrite{
+			Node: s.From,
+			At:   s.AtNs,
+		}
+	case "corrupt-read":
+		args = CorruptRead{
+			Node: s.From,
+			At:   s.AtNs,
+		}
+	case "disk-latency":
+		args = DiskLatency{
+			Node: s.From,
+			From: s.AtNs,
+			To:   s.UntilNs,
+			By:   s.ByNs,
+		}
+	case "clock-skew":
+		args = clockSkew(s)
 	default:
-		log.Panicf("Unknown kind: %+v\n", s)
+		return fmt.Errorf("Unknown fault kind: %s", bs)
+	}
+	*f = Fault{
+		Kind: kind,
+		Args: args,
 	}
 	return nil
 }
 
 func Ldfi(testId TestId, failedRunIds []RunId, fail FailSpec) Faults {
+	return LdfiExcluding(testId, failedRunIds, nil, fail)
+}
+
+// Like `Ldfi`, but the sets of faults in `excluded`, e.g. the ones that are
+// being tried already, aren't suggested again either.
+func LdfiExcluding(testId TestId, failedRunIds []RunId, excluded [][]Fault, fail FailSpec) Faults {
 	start := time.Now()
 	args := []string{
 		"--endOfFiniteFailures", strconv.Itoa(fail.EFF),
@@ -88,6 +300,14 @@ func Ldfi(testId TestId, failedRunIds []RunId, fail FailSpec) Faults {
 		args = append(args, "--failedRunId")
 		args = append(args, strconv.Itoa(r.RunId))
 	}
+	for _, faults := range excluded {
+		bs, err := json.Marshal(toSchedulerFaults(Faults{faults}))
+		if err != nil {
+			log.Panic(err)
+		}
+		args = append(args, "--excludeFaults")
+		args = append(args, string(bs))
+	}
 	cmd := exec.Command("detsys-ldfi", args...)
 	cmd.Stderr = os.Stderr
 
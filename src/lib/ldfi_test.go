@@ -2,7 +2,10 @@ package lib
 
 import (
 	"encoding/json"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLdfiFaultJsonUnmarshal(t *testing.T) {
@@ -39,3 +42,45 @@ func TestLdfiFaultsJsonUnmarshal(t *testing.T) {
 		t.Errorf("%+v", c)
 	}
 }
+
+func TestFaultsRoundTrip(t *testing.T) {
+	faults := Faults{[]Fault{
+		{Kind: "omission", Args: Omission{From: "a", To: "b", At: 1}},
+		{Kind: "crash", Args: Crash{From: "c", At: 2}},
+		{Kind: "partition", Args: Partition{Groups: [][]string{{"a"}, {"b", "c"}}, From: 3, To: 5}},
+		{Kind: "duplicate", Args: Duplicate{From: "a", To: "b", At: 4}},
+		{Kind: "delay", Args: Delay{From: "b", To: "a", At: 5, By: 2 * time.Second}},
+		{Kind: "reorder", Args: Reorder{From: "a", To: "c", At: 6}},
+		{Kind: "pause", Args: Pause{Node: "b", From: time.Second, To: 3 * time.Second}},
+		{Kind: "restart", Args: Restart{Node: "c", At: 2 * time.Second}},
+		{Kind: "disk-full", Args: DiskFull{Node: "a", From: time.Second, To: 2 * time.Second}},
+		{Kind: "torn-write", Args: TornWrite{Node: "b", At: time.Second}},
+		{Kind: "corrupt-read", Args: CorruptRead{Node: "b", At: 3 * time.Second}},
+		{Kind: "disk-latency", Args: DiskLatency{Node: "c", From: 0, To: time.Second, By: time.Millisecond}},
+		{Kind: "clock-skew", Args: ClockSkew{Node: "a", At: time.Second, By: -time.Second}},
+		{Kind: "clock-skew", Args: ClockSkew{Node: "b", At: time.Second, By: time.Second, Over: time.Minute}},
+	}}
+	// The scheduler stores the faults of the `CreateRun` request in
+	// `run_info`, from where they are read back as `Fault`s.
+	bs, err := json.Marshal(NewCreateRunRequest(TestId{0}, CreateRunEvent{Faults: faults}).Faults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []Fault
+	if err := json.Unmarshal(bs, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, faults.Faults) {
+		t.Errorf("Expected %+v, got %+v", faults.Faults, got)
+	}
+	if !strings.HasPrefix(string(bs), `[{"kind":"omission","from":"a","to":"b","at":1},`) {
+		t.Errorf("Omissions should be marshaled as before: %s", bs)
+	}
+}
+
+func TestUnknownFaultKind(t *testing.T) {
+	var fault Fault
+	if err := json.Unmarshal([]byte(`{"kind":"meteor", "at":0}`), &fault); err == nil {
+		t.Errorf("Expected an error, got: %+v", fault)
+	}
+}
@@ -30,6 +30,11 @@ type MetaInfo struct {
 	TestId      TestId `json:"test-id"`
 	RunId       RunId  `json:"run-id"`
 	LogicalTime int    `json:"logical-time"`
+	// The seed the run was created with.
+	Seed Seed `json:"seed"`
+	// The scheduler's time of the step, as opposed to the reactor's local
+	// time, see `Clock`. Zero if the scheduler doesn't say.
+	SimulatedTime time.Time `json:"simulated-time"`
 }
 
 type ScheduledEvent struct {
@@ -86,13 +91,120 @@ type Response interface {
 	ResponseEvent() string
 }
 
+// Sets a timer which fires after `Duration`. Timers with an `Id` are named:
+// setting a named timer replaces the reactor's pending timer with the same id,
+// if any, and named timers can be cancelled with `CancelTimer`. The optional
+// `Payload` is handed back to the reactor when the timer fires, see
+// `TimerAware`.
 type Timer struct {
 	Duration time.Duration `json:"duration"`
+	Id       string        `json:"id,omitempty"`
+	Payload  Message       `json:"payload,omitempty"`
 }
 
+// Cancels the reactor's pending timer with the given id. Cancelling a timer
+// which already fired, or was never set, does nothing.
+type CancelTimer struct {
+	Id string `json:"id"`
+}
+
+// Submits an asynchronous I/O request. The scheduler decides when the I/O
+// completes, at the earliest after `Latency`, and then delivers an
+// `IOCompletion` with the same `Id` and `Request` to the reactor, from itself.
+// The `Request` describes the I/O and is marshaled like an internal message,
+// the reactor typically carries it out when it completes. Requests submitted
+// together may complete in any order.
+type IORequest struct {
+	Id      uint64        `json:"id"`
+	Request Message       `json:"request"`
+	Latency time.Duration `json:"latency"`
+}
+
+type IOCompletion struct {
+	Id      uint64  `json:"id"`
+	Request Message `json:"request"`
+}
+
+func (_ IOCompletion) InEvent() {}
+
 func (_ ClientResponse) Args()  {}
 func (_ InternalMessage) Args() {}
 func (_ Timer) Args()           {}
+func (_ CancelTimer) Args()     {}
+func (_ IORequest) Args()       {}
+
+// Reactors that implement `TimerAware` are told which timer fired, the
+// executors call `TimerFired` rather than `Reactor.Timer` for them. The
+// `Duration` of the fired timer is not kept and hence zero.
+type TimerAware interface {
+	TimerFired(at time.Time, timer Timer) []OutEvent
+}
+
+// Reactors that implement `Restartable` can be restarted by the executors, see
+// the `Restart` fault. `Restart` drops the reactor's volatile state and keeps
+// its durable state, after which the executor calls `Init` again. Reactors
+// which want the executor to keep track of their durable state implement
+// `Durable` instead.
+type Restartable interface {
+	Restart()
+}
+
+// Reactors that implement `Durable` tell the state they have "written to disk"
+// apart from the rest of their state. Only the durable state survives a
+// restart, so bugs such as acknowledging a write before it's on disk show up.
+// The executors record the durable state with every execution step.
+type Durable interface {
+	// Returns the durable state, which must marshal to JSON.
+	DurableState() interface{}
+	// Forgets all state and recovers from the durable `snapshot`, like the
+	// reactor would when booting from its disk. `Init` is called afterwards.
+	Recover(snapshot json.RawMessage) error
+}
+
+// Reactors that implement `FaultInjectable` are handed the faults that target
+// them, which neither the scheduler nor the executor can carry out, such as
+// the disk faults. Only `Hosted` reactors have a disk, see `Env.FS`, the
+// executors log the disk faults of other reactors as not applied.
+type FaultInjectable interface {
+	InjectFault(fault Fault) error
+}
+
+// Marshals the reactor's durable state, nil if the reactor isn't `Durable`.
+func MarshalDurableState(reactor Reactor) json.RawMessage {
+	r, ok := reactor.(Durable)
+	if !ok {
+		return nil
+	}
+	bs, err := json.Marshal(r.DurableState())
+	if err != nil {
+		panic(err)
+	}
+	return bs
+}
+
+// Restarts the reactor with amnesia, without calling `Init`. `Durable` reactors
+// recover from their durable state, which goes through JSON so that nothing
+// volatile is shared with the old state, and `Restartable` reactors are
+// restarted. Returns false if the reactor is neither, in which case executors
+// that can should rebuild it.
+func RestartReactor(reactor Reactor) (bool, error) {
+	switch r := reactor.(type) {
+	case Durable:
+		return true, r.Recover(MarshalDurableState(reactor))
+	case Restartable:
+		r.Restart()
+		return true, nil
+	}
+	return false, nil
+}
+
+// Delivers a timer to the reactor, see `TimerAware`.
+func FireTimer(reactor Reactor, at time.Time, timer Timer) []OutEvent {
+	if r, ok := reactor.(TimerAware); ok {
+		return r.TimerFired(at, timer)
+	}
+	return reactor.Timer(at)
+}
 
 type Receiver = string
 
@@ -0,0 +1,69 @@
+package lib
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// A store which acknowledges writes before syncing them to disk.
+type store struct {
+	Synced   []string `json:"synced"`
+	Unsynced []string `json:"unsynced"`
+}
+
+func (s *store) Receive(_ time.Time, _ string, _ InEvent) []OutEvent { return nil }
+func (s *store) Tick(_ time.Time) []OutEvent                         { return nil }
+func (s *store) Timer(_ time.Time) []OutEvent                        { return nil }
+func (s *store) Init() []OutEvent                                    { return nil }
+
+func (s *store) DurableState() interface{} {
+	return s.Synced
+}
+
+func (s *store) Recover(snapshot json.RawMessage) error {
+	*s = store{}
+	return json.Unmarshal(snapshot, &s.Synced)
+}
+
+func TestRestartReactor(t *testing.T) {
+	s := &store{
+		Synced:   []string{"a"},
+		Unsynced: []string{"b"},
+	}
+	if state := string(MarshalDurableState(s)); state != `["a"]` {
+		t.Errorf("Unexpected durable state: %s", state)
+	}
+	ok, err := RestartReactor(s)
+	if !ok || err != nil {
+		t.Fatalf("Couldn't restart reactor: %v, %v", ok, err)
+	}
+	if !reflect.DeepEqual(s, &store{Synced: []string{"a"}}) {
+		t.Errorf("Unexpected state after restart: %+v", s)
+	}
+	if ok, _ := RestartReactor(NewTypedReactor(counter{})); ok {
+		t.Errorf("Restarted a reactor which is neither durable nor restartable")
+	}
+	if state := MarshalDurableState(NewTypedReactor(counter{})); state != nil {
+		t.Errorf("Unexpected durable state: %s", state)
+	}
+}
+
+func TestRebuild(t *testing.T) {
+	topology := NewTopology(Item{"store", &store{Unsynced: []string{"b"}}})
+	if _, ok := topology.Rebuild("store"); ok {
+		t.Errorf("Rebuilt a reactor without knowing how to")
+	}
+	topology.SetRebuild(func(reactor string) Reactor { return &store{} })
+	reactor, ok := topology.Rebuild("store")
+	if !ok {
+		t.Fatalf("Couldn't rebuild reactor")
+	}
+	if topology.Reactor("store") != reactor || !reflect.DeepEqual(reactor, &store{}) {
+		t.Errorf("Unexpected reactor after rebuild: %+v", topology.Reactor("store"))
+	}
+	if _, ok := (Topology{}).Rebuild("store"); ok {
+		t.Errorf("Rebuilt a reactor of the zero topology")
+	}
+}
@@ -0,0 +1,401 @@
+package lib
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ---------------------------------------------------------------------
+// A linearizability checker in the style of Porcupine: Wing and Gong's search
+// for a linearization of the history, with Lowe's memoization of the states
+// already reached for a set of linearized operations. Unlike `Check` it
+// doesn't need `detsys-checker`, and it reads the history of the run from the
+// `jepsen_history` view.
+//
+// The semantics of the system under test are given by a sequential `Model`.
+
+type HistoryEvent struct {
+	Event string          `json:"event"`
+	Args  json.RawMessage `json:"args"`
+}
+
+// An operation of the history, i.e. a client request and its response.
+type Op struct {
+	Process int          `json:"process"`
+	Input   HistoryEvent `json:"input"`
+	// Nil if the client timed out, in which case the request may or may not
+	// have taken effect.
+	Output *HistoryEvent `json:"output"`
+	// The positions of the request and the response in the history, `Return`
+	// is -1 if the output is nil.
+	Call   int `json:"call"`
+	Return int `json:"return"`
+}
+
+func (op Op) String() string {
+	output := "?"
+	if op.Output != nil {
+		output = fmt.Sprintf("%s %s", op.Output.Event, op.Output.Args)
+	}
+	return fmt.Sprintf("process %d: %s %s -> %s",
+		op.Process, op.Input.Event, op.Input.Args, output)
+}
+
+type Model struct {
+	Name string
+	// The initial state.
+	Init func() interface{}
+	// Returns whether the operation can take effect in the state, and the
+	// state after it. The states must not be modified in place. The output of
+	// the operation is nil if it's unknown.
+	Step func(state interface{}, op Op) (bool, interface{})
+	// Defaults to `reflect.DeepEqual`.
+	Equal func(state1, state2 interface{}) bool
+}
+
+type Verdict struct {
+	Model        string `json:"model"`
+	Linearizable bool   `json:"linearizable"`
+	Reason       string `json:"reason"`
+	// A smallest sub-history that isn't linearizable, nil if the history is.
+	// Removing any of its operations makes it linearizable, even if the
+	// operations that aren't part of it are allowed to take effect or not.
+	Anomaly []Op `json:"anomaly"`
+}
+
+type linearizabilityChecker struct {
+	model Model
+}
+
+// Checks the history of the run against the model, see `Linearizable`. The
+// anomalies of the result are the operations that can't be linearized.
+func NewLinearizabilityChecker(model Model) Checker {
+	return linearizabilityChecker{model}
+}
+
+func (c linearizabilityChecker) Check(testId TestId, runId RunId) (CheckResult, error) {
+	start := time.Now()
+	result := CheckResult{Checker: "linearizability", Model: c.model.Name}
+	history, err := LoadHistory(testId, runId)
+	if err != nil {
+		return result, err
+	}
+	verdict := Linearizable(c.model, history)
+	result.Valid = verdict.Linearizable
+	result.Reason = verdict.Reason
+	if verdict.Anomaly != nil {
+		result.Anomalies = verdict.Anomaly
+	}
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// Checks the history of the run against the model, see `Linearizable`, and
+// stores the verdict as a `CheckResult`.
+func LinearizabilityChecker(model Model, testId TestId, runId RunId) (Verdict, error) {
+	result, err := RunChecker(NewLinearizabilityChecker(model), testId, runId)
+	if err != nil {
+		return Verdict{}, err
+	}
+	verdict := Verdict{Model: model.Name, Linearizable: result.Valid, Reason: result.Reason}
+	if anomaly, ok := result.Anomalies.([]Op); ok {
+		verdict.Anomaly = anomaly
+	}
+	return verdict, nil
+}
+
+// Checks the history of the run against the model, see
+// `NewLinearizabilityChecker`.
+func CheckLinearizable(model Model) Checker {
+	return NewLinearizabilityChecker(model)
+}
+
+func Linearizable(model Model, history []Op) Verdict {
+	verdict := Verdict{Model: model.Name, Linearizable: true}
+	if linearizable(model, history) {
+		return verdict
+	}
+	verdict.Linearizable = false
+
+	// Every prefix of a linearizable history is linearizable, so the shortest
+	// prefix that isn't is found by bisecting.
+	end := 0
+	for _, op := range history {
+		if op.Return+1 > end {
+			end = op.Return + 1
+		}
+	}
+	end = sort.Search(end, func(n int) bool {
+		return !linearizable(model, prefix(history, n+1))
+	}) + 1
+	ops := prefix(history, end)
+
+	// Operations that aren't part of the anomaly are relaxed rather than
+	// removed, so that the anomaly can't be explained by them.
+	var completed []int
+	for i, op := range ops {
+		if op.Output != nil {
+			completed = append(completed, i)
+		}
+	}
+	anomaly, _ := ddmin(completed, func(keep []int) (bool, error) {
+		return !linearizable(model, relax(ops, keep)), nil
+	})
+	reasons := make([]string, 0, len(anomaly))
+	for _, i := range anomaly {
+		verdict.Anomaly = append(verdict.Anomaly, ops[i])
+		reasons = append(reasons, ops[i].String())
+	}
+	verdict.Reason = fmt.Sprintf("not linearizable with respect to %s: %s",
+		model.Name, strings.Join(reasons, "; "))
+	return verdict
+}
+
+// Reads the history of the run, pairing every request with the next response
+// to the same client.
+func LoadHistory(testId TestId, runId RunId) ([]Op, error) {
+	db := OpenDB()
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT kind, event, args, process FROM jepsen_history
+                               WHERE test_id = ? AND run_id = ?
+                               ORDER BY id`,
+		testId.TestId, runId.RunId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []Op
+	failed := make(map[int]bool)
+	pending := make(map[int]int)
+	for at := 0; rows.Next(); at++ {
+		var kind, event string
+		var args []byte
+		var process int
+		if err := rows.Scan(&kind, &event, &args, &process); err != nil {
+			return nil, err
+		}
+		if kind == "invoke" {
+			if _, ok := pending[process]; ok {
+				return nil, fmt.Errorf("Process %d invoked %s while it had a pending request",
+					process, event)
+			}
+			pending[process] = len(history)
+			history = append(history, Op{
+				Process: process,
+				Input:   HistoryEvent{Event: event, Args: args},
+				Return:  -1,
+				Call:    at,
+			})
+			continue
+		}
+		i, ok := pending[process]
+		// Responses to clients that have timed out are dropped.
+		if !ok {
+			continue
+		}
+		delete(pending, process)
+		switch kind {
+		case "ok":
+			history[i].Output = &HistoryEvent{Event: event, Args: args}
+			history[i].Return = at
+		case "fail":
+			failed[i] = true
+		case "info":
+		default:
+			return nil, fmt.Errorf("Unknown kind of history event: %s", kind)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// Requests that failed didn't take effect.
+	ops := make([]Op, 0, len(history))
+	for i, op := range history {
+		if !failed[i] {
+			ops = append(ops, op)
+		}
+	}
+	return ops, nil
+}
+
+// The history up to, but not including, position `end`.
+func prefix(history []Op, end int) []Op {
+	ops := make([]Op, 0, len(history))
+	for _, op := range history {
+		if op.Call >= end {
+			continue
+		}
+		if op.Return >= end {
+			op.Output = nil
+			op.Return = -1
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// Forgets the outputs of the operations that aren't kept.
+func relax(history []Op, keep []int) []Op {
+	ops := append([]Op{}, history...)
+	kept := make(map[int]bool, len(keep))
+	for _, i := range keep {
+		kept[i] = true
+	}
+	for i := range ops {
+		if !kept[i] {
+			ops[i].Output = nil
+			ops[i].Return = -1
+		}
+	}
+	return ops
+}
+
+// An entry is the call or the return of an operation. The entries that haven't
+// been linearized form a doubly linked list, in the order of the history.
+type entry struct {
+	op    int
+	call  bool
+	match *entry
+	prev  *entry
+	next  *entry
+}
+
+// Removes the call and the return of the operation from the list.
+func (e *entry) lift() {
+	e.prev.next = e.next
+	if e.next != nil {
+		e.next.prev = e.prev
+	}
+	m := e.match
+	m.prev.next = m.next
+	if m.next != nil {
+		m.next.prev = m.prev
+	}
+}
+
+func (e *entry) unlift() {
+	m := e.match
+	m.prev.next = m
+	if m.next != nil {
+		m.next.prev = m
+	}
+	e.prev.next = e
+	if e.next != nil {
+		e.next.prev = e
+	}
+}
+
+type bitset []uint64
+
+func (b bitset) set(i int)   { b[i/64] |= 1 << (i % 64) }
+func (b bitset) clear(i int) { b[i/64] &^= 1 << (i % 64) }
+
+func (b bitset) key() string {
+	bs := make([]byte, 8*len(b))
+	for i, word := range b {
+		binary.LittleEndian.PutUint64(bs[8*i:], word)
+	}
+	return string(bs)
+}
+
+func linearizable(model Model, history []Op) bool {
+	equal := model.Equal
+	if equal == nil {
+		equal = reflect.DeepEqual
+	}
+
+	// The returns of the operations whose output is unknown come last, since
+	// they could have taken effect at any point after their call.
+	type point struct {
+		at   int
+		op   int
+		call bool
+	}
+	points := make([]point, 0, 2*len(history))
+	for i, op := range history {
+		points = append(points, point{op.Call, i, true})
+		if op.Output != nil {
+			points = append(points, point{op.Return, i, false})
+		} else {
+			points = append(points, point{math.MaxInt, i, false})
+		}
+	}
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].at < points[j].at
+	})
+	head := &entry{}
+	last := head
+	calls := make([]*entry, len(history))
+	for _, p := range points {
+		e := &entry{op: p.op, call: p.call, prev: last}
+		last.next = e
+		last = e
+		if p.call {
+			calls[p.op] = e
+		} else {
+			calls[p.op].match = e
+		}
+	}
+
+	type frame struct {
+		call  *entry
+		state interface{}
+	}
+	var stack []frame
+	linearized := make(bitset, (len(history)+63)/64)
+	cache := make(map[string][]interface{})
+	seen := func(key string, state interface{}) bool {
+		for _, s := range cache[key] {
+			if equal(s, state) {
+				return true
+			}
+		}
+		return false
+	}
+	state := model.Init()
+	e := head.next
+	for e != nil {
+		if e.call {
+			ok, next := model.Step(state, history[e.op])
+			if ok {
+				linearized.set(e.op)
+				key := linearized.key()
+				if !seen(key, next) {
+					cache[key] = append(cache[key], next)
+					stack = append(stack, frame{e, state})
+					state = next
+					e.lift()
+					e = head.next
+					continue
+				}
+				linearized.clear(e.op)
+			}
+			e = e.next
+			continue
+		}
+		// Only operations whose output is unknown are left, and those might
+		// never have taken effect.
+		if history[e.op].Output == nil {
+			return true
+		}
+		// The operation returned before it could be linearized, so backtrack.
+		if len(stack) == 0 {
+			return false
+		}
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		linearized.clear(top.call.op)
+		state = top.state
+		top.call.unlift()
+		e = top.call.next
+	}
+	return true
+}
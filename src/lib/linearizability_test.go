@@ -0,0 +1,90 @@
+package lib
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// A register holding a single integer.
+var intRegister = Model{
+	Name: "int-register",
+	Init: func() interface{} { return 0 },
+	Step: func(state interface{}, op Op) (bool, interface{}) {
+		var arg int
+		switch op.Input.Event {
+		case "write":
+			json.Unmarshal(op.Input.Args, &arg)
+			return true, arg
+		case "read":
+			if op.Output == nil {
+				return true, state
+			}
+			json.Unmarshal(op.Output.Args, &arg)
+			return arg == state.(int), state
+		}
+		return false, state
+	},
+}
+
+func writeOp(process int, value string, call int, ret int) Op {
+	op := Op{
+		Process: process,
+		Input:   HistoryEvent{Event: "write", Args: json.RawMessage(value)},
+		Call:    call,
+		Return:  ret,
+	}
+	if ret >= 0 {
+		op.Output = &HistoryEvent{Event: "ack", Args: json.RawMessage(`{}`)}
+	}
+	return op
+}
+
+func readOp(process int, value string, call int, ret int) Op {
+	return Op{
+		Process: process,
+		Input:   HistoryEvent{Event: "read", Args: json.RawMessage(`{}`)},
+		Output:  &HistoryEvent{Event: "value", Args: json.RawMessage(value)},
+		Call:    call,
+		Return:  ret,
+	}
+}
+
+func TestLinearizable(t *testing.T) {
+	// The read overlaps with the write, so it can see either value.
+	concurrent := []Op{
+		writeOp(0, "1", 0, 2),
+		readOp(1, "0", 1, 3),
+		readOp(2, "1", 4, 5),
+	}
+	if verdict := Linearizable(intRegister, concurrent); !verdict.Linearizable {
+		t.Errorf("Expected a linearizable history, got: %+v", verdict)
+	}
+
+	// The write's outcome is unknown, so the reads can see either value.
+	timedOut := []Op{
+		writeOp(0, "1", 0, -1),
+		readOp(1, "1", 1, 2),
+		readOp(2, "1", 3, 4),
+	}
+	if verdict := Linearizable(intRegister, timedOut); !verdict.Linearizable {
+		t.Errorf("Expected a linearizable history, got: %+v", verdict)
+	}
+
+	// The second read happens after the first one saw the write, but doesn't.
+	stale := []Op{
+		readOp(3, "0", 0, 1),
+		writeOp(0, "1", 2, 5),
+		readOp(1, "1", 3, 4),
+		readOp(2, "0", 6, 7),
+		readOp(3, "1", 8, 9),
+	}
+	verdict := Linearizable(intRegister, stale)
+	if verdict.Linearizable {
+		t.Fatalf("Expected a stale read, got: %+v", verdict)
+	}
+	if !reflect.DeepEqual(verdict.Anomaly, []Op{stale[2], stale[3]}) {
+		t.Errorf("Expected the two reads as the anomaly, got:\n%v\n%s",
+			verdict.Anomaly, verdict.Reason)
+	}
+}
@@ -2,6 +2,7 @@ package lib
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"os"
 	"os/exec"
@@ -14,21 +15,55 @@ type LTLResult struct {
 	Reason string `json:"reason"`
 }
 
-func LtlChecker(testId TestId, runId RunId, formula string) LTLResult {
+type ltlChecker struct {
+	formula Formula
+}
+
+// Checks the run with `detsys-ltl` against the formula, which is validated
+// first.
+func NewLtlChecker(formula Formula) Checker {
+	return ltlChecker{formula}
+}
+
+func (c ltlChecker) Check(testId TestId, runId RunId) (CheckResult, error) {
+	check := CheckResult{Checker: "ltl", Model: c.formula.String()}
+	if err := c.formula.Validate(); err != nil {
+		return check, fmt.Errorf("Invalid LTL formula: %w", err)
+	}
 	start := time.Now()
-	cmd := exec.Command("detsys-ltl", "check", "--testId", strconv.Itoa(testId.TestId), "--runId", strconv.Itoa(runId.RunId), "--formula", formula)
+	cmd := exec.Command("detsys-ltl", "check", "--testId", strconv.Itoa(testId.TestId), "--runId", strconv.Itoa(runId.RunId), "--formula", c.formula.String())
 	cmd.Stderr = os.Stderr
 
 	out, err := cmd.Output()
 
 	if err != nil {
-		panic(err)
+		return check, err
 	}
 	var result LTLResult
 
-	err = json.Unmarshal(out, &result)
+	if err := json.Unmarshal(out, &result); err != nil {
+		return check, err
+	}
 	elapsed := time.Since(start)
 	log.Printf("LTL Checker time: %v\n", elapsed)
+	check.Valid = result.Result
+	check.Reason = result.Reason
+	check.Duration = elapsed
+
+	return check, nil
+}
+
+// Checks the run with `detsys-ltl` against the formula and stores the result
+// as a `CheckResult`. The error is for when the run couldn't be checked.
+func LtlChecker(testId TestId, runId RunId, formula string) (LTLResult, error) {
+	result, err := RunChecker(NewLtlChecker(RawFormula(formula)), testId, runId)
+	if err != nil {
+		return LTLResult{}, err
+	}
+	return LTLResult{Result: result.Valid, Reason: result.Reason}, nil
+}
 
-	return result
+// Checks the run with `detsys-ltl` against the formula, see `NewLtlChecker`.
+func CheckLtl(formula string) Checker {
+	return NewLtlChecker(RawFormula(formula))
 }
@@ -0,0 +1,207 @@
+package lib
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ---------------------------------------------------------------------
+// A builder for the formulas of `detsys-ltl`, so that formulas can be composed
+// in Go rather than by quoting strings. For example
+//
+//   Eventually(Eq(Heap("B").After().Field("log"), Value("Hello world!")))
+//
+// renders as "(<> (@B'.log = `\"Hello world!\"`))". Mistakes that
+// `detsys-ltl` wouldn't parse, e.g. a reactor name it doesn't accept, are
+// kept in the formula and reported by `Validate`, which `NewLtlChecker` calls
+// before it runs `detsys-ltl`.
+
+type Formula struct {
+	formula string
+	err     error
+}
+
+// A formula that isn't built, e.g. one that's written out by hand.
+func RawFormula(formula string) Formula {
+	return Formula{formula: formula}
+}
+
+func (f Formula) String() string {
+	return f.formula
+}
+
+func (f Formula) Validate() error {
+	if f.err == nil && f.formula == "" {
+		return errors.New("empty formula")
+	}
+	return f.err
+}
+
+var (
+	True  = Formula{formula: "TT"}
+	False = Formula{formula: "FF"}
+)
+
+// Every formula is parenthesised, as the temporal operators extend as far to
+// the right as possible.
+func formula(format string, fs ...Formula) Formula {
+	args := make([]interface{}, 0, len(fs))
+	for _, f := range fs {
+		if err := f.Validate(); err != nil {
+			return Formula{err: err}
+		}
+		args = append(args, f.formula)
+	}
+	return Formula{formula: "(" + fmt.Sprintf(format, args...) + ")"}
+}
+
+// The formula holds in every step from now on.
+func Always(f Formula) Formula {
+	return formula("[] %s", f)
+}
+
+// The formula holds in some step from now on.
+func Eventually(f Formula) Formula {
+	return formula("<> %s", f)
+}
+
+// The formula holds in the next step, of which there must be one.
+func Next(f Formula) Formula {
+	return formula("next %s", f)
+}
+
+// `f` holds in every step until `g` holds, which it must at some point.
+func Until(f Formula, g Formula) Formula {
+	return formula("%s until %s", f, g)
+}
+
+func Not(f Formula) Formula {
+	return formula("! %s", f)
+}
+
+func Implies(f Formula, g Formula) Formula {
+	return formula("%s -> %s", f, g)
+}
+
+func And(f Formula, fs ...Formula) Formula {
+	return formula(strings.Repeat("%s && ", len(fs))+"%s", append([]Formula{f}, fs...)...)
+}
+
+func Or(f Formula, fs ...Formula) Formula {
+	return formula(strings.Repeat("%s || ", len(fs))+"%s", append([]Formula{f}, fs...)...)
+}
+
+// ---------------------------------------------------------------------
+
+// The values formulas compare, see `Eq`.
+type Expr interface {
+	expr() (string, error)
+}
+
+// Both values are the same JSON.
+func Eq(x Expr, y Expr) Formula {
+	lhs, err := x.expr()
+	if err != nil {
+		return Formula{err: err}
+	}
+	rhs, err := y.expr()
+	if err != nil {
+		return Formula{err: err}
+	}
+	return Formula{formula: fmt.Sprintf("(%s = %s)", lhs, rhs)}
+}
+
+type value struct {
+	json string
+	err  error
+}
+
+func (v value) expr() (string, error) {
+	return v.json, v.err
+}
+
+// A constant, compared as JSON.
+func Value(v interface{}) Expr {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return value{err: err}
+	}
+	// Backticks delimit constants, and can only appear in strings.
+	return value{json: "`" + strings.ReplaceAll(string(bs), "`", `\u0060`) + "`"}
+}
+
+// A path into a reactor's heap or into the message of a step.
+type Path struct {
+	path string
+	err  error
+}
+
+func (p Path) expr() (string, error) {
+	return p.path, p.err
+}
+
+func (p Path) Field(name string) Path {
+	if p.err == nil && !alphaNumeric(name) {
+		p.err = fmt.Errorf("field %q isn't alphanumeric", name)
+	}
+	p.path += "." + name
+	return p
+}
+
+func (p Path) Index(i int) Path {
+	p.path += fmt.Sprintf("[%d]", i)
+	return p
+}
+
+type HeapPath struct {
+	Path
+}
+
+// The heap of the reactor before the step, see `After`.
+func Heap(reactor string) HeapPath {
+	p := Path{path: "@" + reactor}
+	if reactor == "" || !unicode.IsLetter([]rune(reactor)[0]) || !alphaNumeric(reactor) {
+		p.err = fmt.Errorf("reactor %q doesn't start with a letter or isn't alphanumeric", reactor)
+	}
+	return HeapPath{p}
+}
+
+// The heap of the reactor after the step.
+func (h HeapPath) After() Path {
+	h.path += "'"
+	return h.Path
+}
+
+// The message received in the step, with the fields "message", "sender",
+// "receiver" and "event" for its arguments.
+func Received() Path {
+	return Path{path: "$"}
+}
+
+// The step is the receipt of the message, e.g. "write".
+func MessageIs(message string) Formula {
+	return Eq(Received().Field("message"), Value(message))
+}
+
+func MessageFrom(reactor string) Formula {
+	return Eq(Received().Field("sender"), Value(reactor))
+}
+
+func MessageTo(reactor string) Formula {
+	return Eq(Received().Field("receiver"), Value(reactor))
+}
+
+func alphaNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
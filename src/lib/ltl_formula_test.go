@@ -0,0 +1,59 @@
+package lib
+
+import (
+	"testing"
+)
+
+func TestFormula(t *testing.T) {
+	tests := []struct {
+		formula  Formula
+		rendered string
+	}{
+		{
+			Eventually(Eq(Heap("B").After().Field("log"), Value("Hello world!"))),
+			"(<> (@B'.log = `\"Hello world!\"`))",
+		},
+		{
+			Always(Implies(And(MessageIs("write"), MessageTo("register1")),
+				Next(Eq(Heap("register1").Field("value").Index(0), Value(1))))),
+			"([] ((($.message = `\"write\"`) && ($.receiver = `\"register1\"`)) -> " +
+				"(next (@register1.value[0] = `1`))))",
+		},
+		{
+			Until(Not(Eq(Heap("A").Field("done"), Value(true))), Or(True, False)),
+			"((! (@A.done = `true`)) until (TT || FF))",
+		},
+		{
+			Eventually(Eq(Heap("A").Field("s"), Value("`"))),
+			"(<> (@A.s = `\"\\u0060\"`))",
+		},
+	}
+	for _, test := range tests {
+		if err := test.formula.Validate(); err != nil {
+			t.Errorf("Unexpected error for %s: %v", test.rendered, err)
+		}
+		if test.formula.String() != test.rendered {
+			t.Errorf("Expected %s, got %s", test.rendered, test.formula)
+		}
+	}
+
+	invalid := []Formula{
+		Always(Eq(Heap("client:0").Field("x"), Value(1))),
+		Eventually(And(True, Eq(Heap("A").Field("a.b"), Value(1)))),
+		Next(Eq(Received(), Value(func() {}))),
+		Not(Formula{}),
+	}
+	for _, formula := range invalid {
+		if err := formula.Validate(); err == nil {
+			t.Errorf("Expected an error, got %s", formula)
+		}
+	}
+}
+
+func TestLtlCheckerError(t *testing.T) {
+	// Without `detsys-ltl` the run can't be checked.
+	t.Setenv("PATH", t.TempDir())
+	if _, err := LtlChecker(TestId{0}, RunId{0}, "TT"); err == nil {
+		t.Error("Expected an error")
+	}
+}
@@ -80,6 +80,19 @@ func unmarshal(m Marshaler, kind string, event string, from string, input json.R
 		iev = &InternalMessage{
 			Message: msg,
 		}
+	case "io":
+		var args struct {
+			Id      uint64          `json:"id"`
+			Request json.RawMessage `json:"request"`
+		}
+		if err := json.Unmarshal(input, &args); err != nil {
+			return nil, err
+		}
+		completion := &IOCompletion{Id: args.Id}
+		if err := m.UnmarshalMessage(event, args.Request, &completion.Request); err != nil {
+			return nil, err
+		}
+		iev = completion
 	case "fault":
 		return nil, nil
 	default:
@@ -97,14 +110,70 @@ type unscheduledEvent struct {
 }
 type timerEvent struct {
 	Kind     string        `json:"kind"`
-	Args     struct{}      `json:"args"`
+	Args     timerArgs     `json:"args"`
 	From     string        `json:"from"`
 	Duration time.Duration `json:"duration-ns"`
 }
+
+// The scheduler sends the arguments of a timer back to the executor when the
+// timer fires. The payload is marshaled like an internal message, so that the
+// `Marshaler` can unmarshal it again.
+type timerArgs struct {
+	Id           string  `json:"id,omitempty"`
+	PayloadEvent string  `json:"payload-event,omitempty"`
+	Payload      Message `json:"payload,omitempty"`
+}
+
+type cancelTimerEvent struct {
+	Kind string      `json:"kind"`
+	Args CancelTimer `json:"args"`
+	From string      `json:"from"`
+}
+
+// Like timers, I/O requests are sent back to the reactor which made them, after
+// their latency. The request is marshaled like an internal message, the event
+// being the request's `MessageEvent`.
+type ioEvent struct {
+	Kind    string        `json:"kind"`
+	Event   string        `json:"event"`
+	Args    ioArgs        `json:"args"`
+	From    string        `json:"from"`
+	To      []string      `json:"to"`
+	Latency time.Duration `json:"duration-ns"`
+}
+
+type ioArgs struct {
+	Id      uint64  `json:"id"`
+	Request Message `json:"request"`
+}
+
 type Event interface{ IsEvent() }
 
 func (_ unscheduledEvent) IsEvent() {}
 func (_ timerEvent) IsEvent()       {}
+func (_ cancelTimerEvent) IsEvent() {}
+func (_ ioEvent) IsEvent()          {}
+
+// Unmarshals the arguments of a fired timer, as sent by the scheduler.
+func UnmarshalTimer(m Marshaler, input json.RawMessage) (Timer, error) {
+	var args struct {
+		Id           string          `json:"id"`
+		PayloadEvent string          `json:"payload-event"`
+		Payload      json.RawMessage `json:"payload"`
+	}
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &args); err != nil {
+			return Timer{}, err
+		}
+	}
+	timer := Timer{Id: args.Id}
+	if args.PayloadEvent != "" {
+		if err := m.UnmarshalMessage(args.PayloadEvent, args.Payload, &timer.Payload); err != nil {
+			return Timer{}, fmt.Errorf("UnmarshalTimer: payload of timer %q: %w", args.Id, err)
+		}
+	}
+	return timer, nil
+}
 
 func OutEventsToEvents(from string, oevs []OutEvent) []Event {
 	usevs := make([]Event, len(oevs))
@@ -128,12 +197,35 @@ func OutEventsToEvents(from string, oevs []OutEvent) []Event {
 				Args:  oev.Args,
 			}
 		case *Timer:
+			args := timerArgs{Id: kindT.Id}
+			if kindT.Payload != nil {
+				args.PayloadEvent = kindT.Payload.MessageEvent()
+				args.Payload = kindT.Payload
+			}
 			event = timerEvent{
 				Kind:     "timer",
-				Args:     struct{}{},
+				Args:     args,
 				From:     from,
 				Duration: kindT.Duration,
 			}
+		case *CancelTimer:
+			event = cancelTimerEvent{
+				Kind: "cancel-timer",
+				Args: *kindT,
+				From: from,
+			}
+		case *IORequest:
+			event = ioEvent{
+				Kind:  "io",
+				Event: kindT.Request.MessageEvent(),
+				Args: ioArgs{
+					Id:      kindT.Id,
+					Request: kindT.Request,
+				},
+				From:    from,
+				To:      Singleton(from),
+				Latency: kindT.Latency,
+			}
 		default:
 			panic(fmt.Sprintf("%T", kindT))
 		}
@@ -143,11 +235,18 @@ func OutEventsToEvents(from string, oevs []OutEvent) []Event {
 }
 
 func MarshalUnscheduledEvents(from string, corrId int, oevs []OutEvent) json.RawMessage {
+	return MarshalUnscheduledEventsAndStop(from, corrId, oevs, "")
+}
+
+// Like `MarshalUnscheduledEvents`, but also asks the scheduler to stop the run
+// for the reason, unless it's empty.
+func MarshalUnscheduledEventsAndStop(from string, corrId int, oevs []OutEvent, stop string) json.RawMessage {
 	usevs := OutEventsToEvents(from, oevs)
 	bs, err := json.Marshal(struct {
 		Events []Event `json:"events"`
 		CorrId int     `json:"corrId"`
-	}{usevs, corrId})
+		Stop   string  `json:"stop,omitempty"`
+	}{usevs, corrId, stop})
 	if err != nil {
 		panic(err)
 	}
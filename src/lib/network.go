@@ -0,0 +1,224 @@
+package lib
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// ---------------------------------------------------------------------
+// The network model decides how long messages between reactors take to be
+// delivered. It's part of the run, so that the run can be reproduced, and the
+// scheduler falls back to exponentially distributed latencies with a mean of
+// 20ms for links that the model doesn't say anything about.
+
+type LatencyKind string
+
+const (
+	UniformLatency     LatencyKind = "uniform"
+	ExponentialLatency LatencyKind = "exponential"
+	BimodalLatency     LatencyKind = "bimodal"
+)
+
+type Latency struct {
+	Kind LatencyKind `json:"kind"`
+	// Uniform latencies are between `Min` and `Max`.
+	Min time.Duration `json:"min-ns,omitempty"`
+	Max time.Duration `json:"max-ns,omitempty"`
+	// Exponential latencies have the mean `Mean`, bimodal ones have the mean
+	// `SlowMean` for a `SlowFraction` of the messages.
+	Mean         time.Duration `json:"mean-ns,omitempty"`
+	SlowMean     time.Duration `json:"slow-mean-ns,omitempty"`
+	SlowFraction float64       `json:"slow-fraction,omitempty"`
+}
+
+func Uniform(min time.Duration, max time.Duration) *Latency {
+	return &Latency{Kind: UniformLatency, Min: min, Max: max}
+}
+
+func Exponential(mean time.Duration) *Latency {
+	return &Latency{Kind: ExponentialLatency, Mean: mean}
+}
+
+func Bimodal(mean time.Duration, slowMean time.Duration, slowFraction float64) *Latency {
+	return &Latency{
+		Kind:         BimodalLatency,
+		Mean:         mean,
+		SlowMean:     slowMean,
+		SlowFraction: slowFraction,
+	}
+}
+
+// Rejects latencies that can't be sampled, i.e. of an unknown kind or that
+// could be negative.
+func (l Latency) Validate() error {
+	switch l.Kind {
+	case UniformLatency:
+		if l.Min < 0 || l.Max < l.Min {
+			return fmt.Errorf("The bounds of a uniform latency must satisfy 0 <= min <= max, got: %v", l)
+		}
+	case ExponentialLatency:
+		if l.Mean < 0 {
+			return fmt.Errorf("The mean of an exponential latency can't be negative, got: %v", l)
+		}
+	case BimodalLatency:
+		if l.Mean < 0 || l.SlowMean < 0 || l.SlowFraction < 0 || l.SlowFraction > 1 {
+			return fmt.Errorf("The means of a bimodal latency can't be negative and the fraction must be between 0 and 1, got: %v", l)
+		}
+	default:
+		return fmt.Errorf("Unknown latency kind: %q", l.Kind)
+	}
+	return nil
+}
+
+// Draws a latency, `uniform` draws from the uniform distribution over [0, 1).
+// The schedulers pass their own generator, so that they draw the same
+// latencies from the same seed. Latencies that `Validate` accepts can always be
+// sampled.
+func (l Latency) Sample(uniform func() float64) time.Duration {
+	// The draw is taken from (0, 1], as the logarithm of 0 is infinite.
+	exponential := func(mean time.Duration) time.Duration {
+		return time.Duration(-float64(mean) * math.Log(1-uniform()))
+	}
+	switch l.Kind {
+	case UniformLatency:
+		return l.Min + time.Duration(uniform()*float64(l.Max-l.Min))
+	case ExponentialLatency:
+		return exponential(l.Mean)
+	case BimodalLatency:
+		if uniform() < l.SlowFraction {
+			return exponential(l.SlowMean)
+		}
+		return exponential(l.Mean)
+	default:
+		panic(fmt.Sprintf("Unknown latency kind: %s", l.Kind))
+	}
+}
+
+func (l Latency) String() string {
+	switch l.Kind {
+	case UniformLatency:
+		return fmt.Sprintf("uniform(%v, %v)", l.Min, l.Max)
+	case BimodalLatency:
+		return fmt.Sprintf("bimodal(%v, %v, %g)", l.Mean, l.SlowMean, l.SlowFraction)
+	default:
+		return fmt.Sprintf("%s(%v)", l.Kind, l.Mean)
+	}
+}
+
+type LinkModel struct {
+	// The scheduler's default latency is used if it's nil.
+	Latency *Latency `json:"latency,omitempty"`
+	// Messages on a FIFO link, like on a TCP connection, are delivered in the
+	// order they were sent. Messages on other links can overtake each other.
+	FIFO bool `json:"fifo,omitempty"`
+	// In bytes per second, zero means unlimited. Messages take up the link
+	// for the time it takes to transmit their arguments, and wait for the
+	// messages before them to be transmitted.
+	Bandwidth int64 `json:"bandwidth,omitempty"`
+}
+
+// How long it takes to put a message of `size` bytes on the link.
+func (m LinkModel) Transmission(size int) time.Duration {
+	if m.Bandwidth <= 0 {
+		return 0
+	}
+	return time.Duration(float64(size) / float64(m.Bandwidth) * float64(time.Second))
+}
+
+func (m LinkModel) String() string {
+	var parts []string
+	if m.Latency != nil {
+		parts = append(parts, m.Latency.String())
+	}
+	if m.FIFO {
+		parts = append(parts, "fifo")
+	}
+	if m.Bandwidth > 0 {
+		parts = append(parts, fmt.Sprintf("%dB/s", m.Bandwidth))
+	}
+	if len(parts) == 0 {
+		return "default"
+	}
+	return strings.Join(parts, " ")
+}
+
+type Link struct {
+	// An empty `From` or `To` matches any reactor.
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	// Whether the model also applies to the messages from `To` to `From`.
+	Bidirectional bool `json:"bidirectional,omitempty"`
+	LinkModel
+}
+
+func (l Link) matches(from string, to string) bool {
+	match := func(pattern string, reactor string) bool {
+		return pattern == "" || pattern == reactor
+	}
+	return match(l.From, from) && match(l.To, to) ||
+		l.Bidirectional && match(l.From, to) && match(l.To, from)
+}
+
+type NetworkModel struct {
+	Default LinkModel `json:"default"`
+	// The first link that matches takes precedence over the default.
+	Links []Link `json:"links,omitempty"`
+}
+
+// The model of the link from `from` to `to`, a nil network model has the
+// default link everywhere.
+func (m *NetworkModel) Link(from string, to string) LinkModel {
+	if m == nil {
+		return LinkModel{}
+	}
+	for _, link := range m.Links {
+		if link.matches(from, to) {
+			return link.LinkModel
+		}
+	}
+	return m.Default
+}
+
+// Rejects network models with latencies that can't be sampled, see
+// `Latency.Validate`. A nil network model is valid.
+func ValidateNetwork(m *NetworkModel) error {
+	if m == nil {
+		return nil
+	}
+	if l := m.Default.Latency; l != nil {
+		if err := l.Validate(); err != nil {
+			return fmt.Errorf("The default link: %v", err)
+		}
+	}
+	for i, link := range m.Links {
+		if l := link.Latency; l != nil {
+			if err := l.Validate(); err != nil {
+				return fmt.Errorf("Link %d: %v", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (m *NetworkModel) String() string {
+	if m == nil {
+		return "default"
+	}
+	s := m.Default.String()
+	pattern := func(reactor string) string {
+		if reactor == "" {
+			return "*"
+		}
+		return reactor
+	}
+	for _, link := range m.Links {
+		arrow := "->"
+		if link.Bidirectional {
+			arrow = "<->"
+		}
+		s += fmt.Sprintf(", %s%s%s: %s", pattern(link.From), arrow, pattern(link.To), link.LinkModel)
+	}
+	return s
+}
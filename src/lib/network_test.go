@@ -0,0 +1,101 @@
+package lib
+
+import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNetworkModel(t *testing.T) {
+	model := &NetworkModel{
+		Default: LinkModel{Latency: Exponential(10 * time.Millisecond)},
+		Links: []Link{
+			{From: "a", To: "b", Bidirectional: true, LinkModel: LinkModel{FIFO: true}},
+			{To: "c", LinkModel: LinkModel{Latency: Uniform(time.Millisecond, 2*time.Millisecond)}},
+		},
+	}
+	for _, test := range []struct {
+		from     string
+		to       string
+		expected LinkModel
+	}{
+		{"a", "b", LinkModel{FIFO: true}},
+		{"b", "a", LinkModel{FIFO: true}},
+		{"b", "c", model.Links[1].LinkModel},
+		{"c", "b", model.Default},
+	} {
+		if got := model.Link(test.from, test.to); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s->%s: expected %v, got %v", test.from, test.to, test.expected, got)
+		}
+	}
+	if got := (*NetworkModel)(nil).Link("a", "b"); !reflect.DeepEqual(got, LinkModel{}) {
+		t.Errorf("Expected the default link, got %v", got)
+	}
+	if s, expected := model.String(), "exponential(10ms), a<->b: fifo, *->c: uniform(1ms, 2ms)"; s != expected {
+		t.Errorf("Expected %q, got %q", expected, s)
+	}
+
+	bs, err := json.Marshal(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var again NetworkModel
+	if err := json.Unmarshal(bs, &again); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&again, model) {
+		t.Errorf("Expected %v, got %v", model, again)
+	}
+}
+
+func TestLatencySample(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	uniform := Uniform(time.Millisecond, 2*time.Millisecond)
+	bimodal := Bimodal(time.Millisecond, time.Second, 0.1)
+	var slow int
+	for i := 0; i < 1000; i++ {
+		if l := uniform.Sample(r.Float64); l < time.Millisecond || l >= 2*time.Millisecond {
+			t.Errorf("Latency out of range: %v", l)
+		}
+		if bimodal.Sample(r.Float64) > 100*time.Millisecond {
+			slow++
+		}
+	}
+	if slow < 50 || slow > 150 {
+		t.Errorf("Expected about a tenth of the latencies to be slow, got %d", slow)
+	}
+	zero := func() float64 { return 0 }
+	if l := Exponential(time.Millisecond).Sample(zero); l != 0 {
+		t.Errorf("Expected a draw of 0 to give no latency, got %v", l)
+	}
+}
+
+func TestValidateNetwork(t *testing.T) {
+	valid := &NetworkModel{
+		Default: LinkModel{Latency: Exponential(time.Millisecond)},
+		Links: []Link{
+			{From: "a", LinkModel: LinkModel{Latency: Uniform(0, time.Millisecond)}},
+			{To: "b", LinkModel: LinkModel{Latency: Bimodal(time.Millisecond, time.Second, 0.1)}},
+			{From: "c", LinkModel: LinkModel{FIFO: true}},
+		},
+	}
+	if err := ValidateNetwork(valid); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := ValidateNetwork(nil); err != nil {
+		t.Errorf("Unexpected error for the default network: %v", err)
+	}
+	for _, latency := range []*Latency{
+		{Kind: "normal", Mean: time.Millisecond},
+		Uniform(2*time.Millisecond, time.Millisecond),
+		Exponential(-time.Millisecond),
+		Bimodal(time.Millisecond, time.Second, 1.5),
+	} {
+		invalid := &NetworkModel{Links: []Link{{From: "a", LinkModel: LinkModel{Latency: latency}}}}
+		if err := ValidateNetwork(invalid); err == nil {
+			t.Errorf("Expected the latency to be rejected: %v", latency)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package lib
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+)
+
+// ---------------------------------------------------------------------
+// Reactors must not use the global `math/rand`, or seed a generator of their
+// own, since that breaks the reproducibility of runs. `ReactorRand` is the
+// sanctioned source of randomness, reactors written against `Env` get one
+// through `Env.Rand`. Other reactors keep one in their state,
+//
+//   type Node struct {
+//           rand *lib.ReactorRand
+//           ...
+//   }
+//
+// and forward `SetRun` and `DrainRandDraws` to it, and `Restarted` when they
+// restart.
+
+type ReactorRand struct {
+	*rand.Rand
+	name        string
+	run         MetaInfo
+	incarnation int
+	source      *countingSource
+}
+
+// Reactors that implement `RandReporter` have the number of pseudo-random
+// draws they made recorded with every execution step, so that a replay can
+// check that it consumed the same sequence.
+type RandReporter interface {
+	// Returns the number of draws since the last call.
+	DrainRandDraws() int
+}
+
+// A `rand.Source64` that counts how many numbers were drawn from it. Some of
+// the `rand.Rand` methods draw more than once, the count is of the draws from
+// the source.
+type countingSource struct {
+	source rand.Source64
+	draws  int
+}
+
+func (s *countingSource) Int63() int64 {
+	s.draws++
+	return s.source.Int63()
+}
+
+func (s *countingSource) Uint64() uint64 {
+	s.draws++
+	return s.source.Uint64()
+}
+
+func (s *countingSource) Seed(seed int64) {
+	s.source.Seed(seed)
+}
+
+// Until the first run is set, the generator is seeded as if the run's seed
+// was 0.
+func NewReactorRand(name string) *ReactorRand {
+	r := &ReactorRand{name: name}
+	r.reseed()
+	return r
+}
+
+// Mixes the reactor's name, and incarnation if it has restarted, into the run's
+// seed, so that reactors don't all draw the same numbers and a restarted
+// reactor doesn't repeat the draws of its previous incarnation.
+func reactorSeed(seed Seed, name string, incarnation int) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	if incarnation > 0 {
+		fmt.Fprintf(h, "#%d", incarnation)
+	}
+	return int64(h.Sum64()) ^ int64(seed)
+}
+
+// Draws which weren't drained yet are carried over.
+func (r *ReactorRand) reseed() {
+	var draws int
+	if r.source != nil {
+		draws = r.source.draws
+	}
+	r.source = &countingSource{
+		source: rand.NewSource(reactorSeed(r.run.Seed, r.name, r.incarnation)).(rand.Source64),
+		draws:  draws,
+	}
+	r.Rand = rand.New(r.source)
+}
+
+// Reseeds the generator when a new run starts, the incarnation starts over.
+func (r *ReactorRand) SetRun(meta MetaInfo) {
+	if meta.TestId == r.run.TestId && meta.RunId == r.run.RunId && meta.Seed == r.run.Seed {
+		return
+	}
+	r.run = meta
+	r.incarnation = 0
+	r.reseed()
+}
+
+// Reseeds the generator for the reactor's next incarnation.
+func (r *ReactorRand) Restarted() {
+	r.incarnation++
+	r.reseed()
+}
+
+func (r *ReactorRand) Incarnation() int {
+	return r.incarnation
+}
+
+func (r *ReactorRand) DrainRandDraws() int {
+	draws := r.source.draws
+	r.source.draws = 0
+	return draws
+}
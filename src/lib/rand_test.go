@@ -0,0 +1,52 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func sample(r *ReactorRand) []int {
+	return []int{r.Intn(1000), r.Intn(1000), r.Intn(1000)}
+}
+
+func TestReactorRand(t *testing.T) {
+	run := MetaInfo{TestId: TestId{1}, RunId: RunId{0}, Seed: 4}
+	r := NewReactorRand("a")
+	r.SetRun(run)
+	first := sample(r)
+	if draws := r.DrainRandDraws(); draws != 3 {
+		t.Errorf("Expected 3 draws, got %d", draws)
+	}
+	if draws := r.DrainRandDraws(); draws != 0 {
+		t.Errorf("Expected the draws to be drained, got %d", draws)
+	}
+
+	r.Restarted()
+	if r.Incarnation() != 1 {
+		t.Errorf("Expected the second incarnation, got %d", r.Incarnation())
+	}
+	second := sample(r)
+	if reflect.DeepEqual(first, second) {
+		t.Error("Expected a restarted reactor to draw different numbers")
+	}
+
+	// Replaying the run draws the same numbers, incarnation by incarnation.
+	replay := NewReactorRand("a")
+	replay.SetRun(run)
+	if got := sample(replay); !reflect.DeepEqual(got, first) {
+		t.Errorf("Expected %v, got %v", first, got)
+	}
+	replay.Restarted()
+	if got := sample(replay); !reflect.DeepEqual(got, second) {
+		t.Errorf("Expected %v, got %v", second, got)
+	}
+
+	// A new run starts over with the first incarnation.
+	replay.SetRun(MetaInfo{TestId: TestId{1}, RunId: RunId{1}, Seed: 4})
+	if replay.Incarnation() != 0 {
+		t.Errorf("Expected the first incarnation, got %d", replay.Incarnation())
+	}
+	if got := sample(replay); !reflect.DeepEqual(got, first) {
+		t.Errorf("Expected %v, got %v", first, got)
+	}
+}
@@ -0,0 +1,150 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ---------------------------------------------------------------------
+// A `Registry` keeps track of the request, message and response types of a
+// SUT by their event name, so that the `Marshaler` doesn't have to be written
+// by hand.
+
+type unmarshaler[T any] func(json.RawMessage) (T, error)
+
+type Registry struct {
+	requests  map[string]unmarshaler[Request]
+	messages  map[string]unmarshaler[Message]
+	responses map[string]unmarshaler[Response]
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		requests:  make(map[string]unmarshaler[Request]),
+		messages:  make(map[string]unmarshaler[Message]),
+		responses: make(map[string]unmarshaler[Response]),
+	}
+}
+
+// Registers `T` as a request, message and/or response, depending on which of
+// `RequestEvent`, `MessageEvent` and `ResponseEvent` it implements, under the
+// event name that the zero value of `T` returns. Nothing is registered if an
+// error is returned.
+func RegisterType[T any](r *Registry) error {
+	var zero T
+	var adds []func()
+	if req, ok := any(zero).(Request); ok {
+		add, err := registerKind(r.requests, "request", req.RequestEvent(), decode[T, Request])
+		if err != nil {
+			return err
+		}
+		adds = append(adds, add)
+	}
+	if msg, ok := any(zero).(Message); ok {
+		add, err := registerKind(r.messages, "message", msg.MessageEvent(), decode[T, Message])
+		if err != nil {
+			return err
+		}
+		adds = append(adds, add)
+	}
+	if resp, ok := any(zero).(Response); ok {
+		add, err := registerKind(r.responses, "response", resp.ResponseEvent(), decode[T, Response])
+		if err != nil {
+			return err
+		}
+		adds = append(adds, add)
+	}
+	if len(adds) == 0 {
+		return fmt.Errorf("RegisterType: %T is neither a request, message nor response", zero)
+	}
+	for _, add := range adds {
+		add()
+	}
+	return nil
+}
+
+// Like `RegisterType`, but panics on error. Meant for building a registry at
+// start up, where a duplicate name is a programming error.
+func MustRegisterType[T any](r *Registry) {
+	if err := RegisterType[T](r); err != nil {
+		panic(err)
+	}
+}
+
+// Registers `decode` for the messages named `name`, for messages that
+// `RegisterType` can't handle: those whose event name depends on their
+// contents, e.g. a message that wraps a request, and those that aren't JSON.
+func RegisterMessage(r *Registry, name string, decode func(raw json.RawMessage) (Message, error)) error {
+	add, err := registerKind(r.messages, "message", name, decode)
+	if err != nil {
+		return err
+	}
+	add()
+	return nil
+}
+
+func MustRegisterMessage(r *Registry, name string, decode func(raw json.RawMessage) (Message, error)) {
+	if err := RegisterMessage(r, name, decode); err != nil {
+		panic(err)
+	}
+}
+
+func decode[T any, I any](raw json.RawMessage) (I, error) {
+	var v T
+	var i I
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return i, err
+	}
+	return any(v).(I), nil
+}
+
+// Event names are case insensitive, like in the hand-written marshalers.
+func registerKind[I any](m map[string]unmarshaler[I], kind string, name string, u unmarshaler[I]) (func(), error) {
+	key := strings.ToLower(name)
+	if key == "" {
+		return nil, fmt.Errorf("RegisterType: empty %s name", kind)
+	}
+	if _, ok := m[key]; ok {
+		return nil, fmt.Errorf("RegisterType: duplicate %s name: %s", kind, name)
+	}
+	return func() { m[key] = u }, nil
+}
+
+func lookup[I any](m map[string]unmarshaler[I], kind string, name string, raw json.RawMessage) (I, error) {
+	u, ok := m[strings.ToLower(name)]
+	if !ok {
+		var i I
+		return i, fmt.Errorf("Unknown %s type: %s\n%s", kind, name, raw)
+	}
+	v, err := u(raw)
+	if err != nil {
+		return v, fmt.Errorf("Cannot unmarshal %s %s: %w", kind, name, err)
+	}
+	return v, nil
+}
+
+func (r *Registry) UnmarshalRequest(request string, raw json.RawMessage, req *Request) error {
+	v, err := lookup(r.requests, "request", request, raw)
+	if err != nil {
+		return err
+	}
+	*req = v
+	return nil
+}
+
+func (r *Registry) UnmarshalMessage(message string, raw json.RawMessage, msg *Message) error {
+	v, err := lookup(r.messages, "message", message, raw)
+	if err != nil {
+		return err
+	}
+	*msg = v
+	return nil
+}
+
+func (r *Registry) UnmarshalResponse(response string, raw json.RawMessage) (Response, error) {
+	return lookup(r.responses, "response", response, raw)
+}
+
+// The registry implements `Marshaler`.
+var _ Marshaler = (*Registry)(nil)
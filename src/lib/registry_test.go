@@ -0,0 +1,98 @@
+package lib
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type registryWrite struct {
+	Value int `json:"value"`
+}
+
+func (_ registryWrite) RequestEvent() string { return "Write" }
+func (_ registryWrite) MessageEvent() string { return "Write" }
+
+type registryAck struct{}
+
+func (_ registryAck) ResponseEvent() string { return "ack" }
+
+type registryOtherWrite struct{}
+
+func (_ registryOtherWrite) MessageEvent() string { return "write" }
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	if err := RegisterType[registryWrite](r); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterType[registryAck](r); err != nil {
+		t.Fatal(err)
+	}
+
+	var req Request
+	if err := r.UnmarshalRequest("write", json.RawMessage(`{"value":1}`), &req); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(req, registryWrite{1}) {
+		t.Errorf("Unexpected request: %#v", req)
+	}
+	var msg Message
+	if err := r.UnmarshalMessage("Write", json.RawMessage(`{"value":2}`), &msg); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(msg, registryWrite{2}) {
+		t.Errorf("Unexpected message: %#v", msg)
+	}
+	resp, err := r.UnmarshalResponse("ack", json.RawMessage(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resp, registryAck{}) {
+		t.Errorf("Unexpected response: %#v", resp)
+	}
+
+	if err := r.UnmarshalMessage("ack", json.RawMessage(`{}`), &msg); err == nil {
+		t.Error("Expected an error for an unknown message")
+	}
+	if err := r.UnmarshalRequest("write", json.RawMessage(`{"value":"1"}`), &req); err == nil {
+		t.Error("Expected an error for a malformed request")
+	}
+}
+
+func TestRegistryRejectsDuplicates(t *testing.T) {
+	r := NewRegistry()
+	if err := RegisterType[registryOtherWrite](r); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterType[registryWrite](r); err == nil {
+		t.Error("Expected an error for a duplicate message name")
+	}
+	// Nothing gets registered if there's an error.
+	var req Request
+	if err := r.UnmarshalRequest("write", json.RawMessage(`{"value":1}`), &req); err == nil {
+		t.Error("Expected the request not to be registered")
+	}
+	if err := RegisterType[int](r); err == nil {
+		t.Error("Expected an error for a type that isn't an event")
+	}
+}
+
+func TestRegisterMessage(t *testing.T) {
+	r := NewRegistry()
+	if err := RegisterMessage(r, "Raw", func(raw json.RawMessage) (Message, error) {
+		return registryOtherWrite{}, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var msg Message
+	if err := r.UnmarshalMessage("raw", json.RawMessage(`not json`), &msg); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(msg, registryOtherWrite{}) {
+		t.Errorf("Unexpected message: %#v", msg)
+	}
+	if err := RegisterMessage(r, "raw", nil); err == nil {
+		t.Error("Expected an error for a duplicate message name")
+	}
+}
@@ -1,6 +1,7 @@
 package lib
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strconv"
@@ -30,29 +31,33 @@ type QueueSize struct {
 
 type Seed int
 
-func LoadTest(testId TestId) QueueSize {
-	var queueSize QueueSize
-	PostParse("load-test!", struct {
-		TestId TestId `json:"test-id"`
-	}{testId}, &queueSize)
-	return queueSize
-}
-
-func RegisterExecutor(executorId string, components []string) {
-	Post("register-executor!", struct {
-		ExecutorId string   `json:"executor-id"`
-		Components []string `json:"components"`
-	}{
-		ExecutorId: executorId,
-		Components: components,
-	})
+// The functions below use the `DefaultSchedulerClient`, use a
+// `SchedulerClient` directly to pass a context or to retry commands.
+
+func LoadTest(testId TestId) (QueueSize, error) {
+	return DefaultSchedulerClient().LoadTest(context.Background(), testId)
+}
+
+func RegisterExecutor(executorId string, components []string) error {
+	return DefaultSchedulerClient().RegisterExecutor(context.Background(), executorId, components)
 }
 
+// Omissions, crashes and the other faults that are between two reactors use
+// `From`, `To` and `At`. Partitions use `Groups`, `At` and `Until`, delays also
+// use `ByNs`. Pauses, restarts and disk faults of the reactor `From` use `AtNs`
+// and `UntilNs`, which are in simulated time since the start of the run, disk
+// latencies also use `ByNs`. Clock skews use `AtNs`, `ByNs` and, if they slew,
+// `UntilNs`.
 type SchedulerFault struct {
-	Kind string `json:"kind"`
-	From string `json:"from"`
-	To   string `json:"to"`
-	At   int    `json:"at"` // should be time.Time?
+	Kind    string        `json:"kind"`
+	From    string        `json:"from"`
+	To      string        `json:"to"`
+	At      int           `json:"at"` // should be time.Time?
+	Until   int           `json:"until,omitempty"`
+	Groups  [][]string    `json:"groups,omitempty"`
+	ByNs    time.Duration `json:"by-ns,omitempty"`
+	AtNs    time.Duration `json:"at-ns,omitempty"`
+	UntilNs time.Duration `json:"until-ns,omitempty"`
 }
 
 func toSchedulerFaults(faults Faults) []SchedulerFault {
@@ -70,6 +75,63 @@ func toSchedulerFaults(faults Faults) []SchedulerFault {
 			schedulerFault.From = ev.From
 			schedulerFault.To = ""    // Not used.
 			schedulerFault.At = ev.At // convert?
+		case Partition:
+			schedulerFault.Kind = fault.Kind
+			schedulerFault.Groups = ev.Groups
+			schedulerFault.At = ev.From
+			schedulerFault.Until = ev.To
+		case Duplicate:
+			schedulerFault.Kind = fault.Kind
+			schedulerFault.From = ev.From
+			schedulerFault.To = ev.To
+			schedulerFault.At = ev.At
+		case Delay:
+			schedulerFault.Kind = fault.Kind
+			schedulerFault.From = ev.From
+			schedulerFault.To = ev.To
+			schedulerFault.At = ev.At
+			schedulerFault.ByNs = ev.By
+		case Reorder:
+			schedulerFault.Kind = fault.Kind
+			schedulerFault.From = ev.From
+			schedulerFault.To = ev.To
+			schedulerFault.At = ev.At
+		case Pause:
+			schedulerFault.Kind = fault.Kind
+			schedulerFault.From = ev.Node
+			schedulerFault.AtNs = ev.From
+			schedulerFault.UntilNs = ev.To
+		case Restart:
+			schedulerFault.Kind = fault.Kind
+			schedulerFault.From = ev.Node
+			schedulerFault.AtNs = ev.At
+		case DiskFull:
+			schedulerFault.Kind = fault.Kind
+			schedulerFault.From = ev.Node
+			schedulerFault.AtNs = ev.From
+			schedulerFault.UntilNs = ev.To
+		case TornWrite:
+			schedulerFault.Kind = fault.Kind
+			schedulerFault.From = ev.Node
+			schedulerFault.AtNs = ev.At
+		case CorruptRead:
+			schedulerFault.Kind = fault.Kind
+			schedulerFault.From = ev.Node
+			schedulerFault.AtNs = ev.At
+		case DiskLatency:
+			schedulerFault.Kind = fault.Kind
+			schedulerFault.From = ev.Node
+			schedulerFault.AtNs = ev.From
+			schedulerFault.UntilNs = ev.To
+			schedulerFault.ByNs = ev.By
+		case ClockSkew:
+			schedulerFault.Kind = fault.Kind
+			schedulerFault.From = ev.Node
+			schedulerFault.AtNs = ev.At
+			schedulerFault.ByNs = ev.By
+			if ev.Over > 0 {
+				schedulerFault.UntilNs = ev.At + ev.Over
+			}
 		default:
 			panic(fmt.Sprintf("Unknown fault type: %#v\n", fault))
 		}
@@ -85,68 +147,83 @@ type CreateRunEvent struct {
 	TickFrequency float64
 	MinTimeNs     time.Duration
 	MaxTimeNs     time.Duration
-}
-
-func CreateRun(testId TestId, event CreateRunEvent) RunId {
-	var runId struct {
-		RunId RunId `json:"run-id"`
+	// The reactors' clocks, see `Clock`.
+	Clocks map[string]Clock
+	// How messages travel between reactors, see `NetworkModel`.
+	Network *NetworkModel
+}
+
+// The parameters of the `create-run!` command, these are also what the
+// scheduler stores in the `CreateRun` event.
+type CreateRunRequest struct {
+	TestId        TestId           `json:"test-id"`
+	Seed          Seed             `json:"seed"`
+	Faults        []SchedulerFault `json:"faults"`
+	TickFrequency float64          `json:"tick-frequency"`
+	MinTimeNs     time.Duration    `json:"min-time-ns"`
+	MaxTimeNs     time.Duration    `json:"max-time-ns"`
+	Clocks        map[string]Clock `json:"clocks,omitempty"`
+	Network       *NetworkModel    `json:"network,omitempty"`
+}
+
+func NewCreateRunRequest(testId TestId, event CreateRunEvent) CreateRunRequest {
+	return CreateRunRequest{
+		TestId:        testId,
+		Seed:          event.Seed,
+		Faults:        toSchedulerFaults(event.Faults),
+		TickFrequency: event.TickFrequency,
+		MinTimeNs:     event.MinTimeNs,
+		MaxTimeNs:     event.MaxTimeNs,
+		Clocks:        event.Clocks,
+		Network:       event.Network,
 	}
-	PostParse("create-run!", struct {
-		TestId        TestId           `json:"test-id"`
-		Seed          Seed             `json:"seed"`
-		Faults        []SchedulerFault `json:"faults"`
-		TickFrequency float64          `json:"tick-frequency"`
-		MinTimeNs     time.Duration    `json:"min-time-ns"`
-		MaxTimeNs     time.Duration    `json:"max-time-ns"`
-	}{testId, event.Seed, toSchedulerFaults(event.Faults), event.TickFrequency, event.MinTimeNs, event.MaxTimeNs}, &runId)
-	return runId.RunId
+}
 
+func CreateRun(testId TestId, event CreateRunEvent) (RunId, error) {
+	return DefaultSchedulerClient().CreateRun(context.Background(), testId, event)
 }
 
-func Run() {
-	Post("run!", struct{}{})
+func Run() error {
+	return DefaultSchedulerClient().Run(context.Background())
 }
 
-func Status() map[string]interface{} {
-	var status map[string]interface{}
-	PostParse("status", struct{}{}, &status)
-	return status
+func Status() (map[string]interface{}, error) {
+	return DefaultSchedulerClient().Status(context.Background())
 }
 
-func Reset() {
-	Post("reset", struct{}{})
+func Reset() error {
+	return DefaultSchedulerClient().Reset(context.Background())
 }
 
-func Step() json.RawMessage {
-	var result json.RawMessage
-	PostParse("step!", struct{}{}, &result)
-	return result
+func Step() (json.RawMessage, error) {
+	return DefaultSchedulerClient().Step(context.Background())
 }
 
-func reactorsFromDeployment(testId TestId) ([]string, error) {
+func executorsFromDeployment(testId TestId) ([]string, map[string][]string, error) {
 	deploys, err := DeploymentInfoForTest(testId)
 
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	reactors := make([]string, 0, len(deploys))
+	executors := make(map[string]string)
 
 	for _, dep := range deploys {
 		reactors = append(reactors, dep.Reactor)
+		executors[dep.Reactor] = dep.ExecutorUrl()
 	}
 
-	return reactors, nil
+	urls, grouped := groupByExecutor(reactors, func(reactor string) string {
+		return executors[reactor]
+	})
+	return urls, grouped, nil
 }
 
-func Register(testId TestId) {
-	// TODO(stevan): Make executorUrl part of topology/deployment.
-	const executorUrl string = "http://localhost:3001/api/v1/"
-
-	reactors, err := reactorsFromDeployment(testId)
-	if err != nil {
-		panic(err)
-	}
+func Register(testId TestId) error {
+	return DefaultSchedulerClient().Register(context.Background(), testId)
+}
 
-	RegisterExecutor(executorUrl, reactors)
+func RegisterTopology(topology Topology) error {
+	return DefaultSchedulerClient().RegisterTopology(context.Background(), topology)
 }
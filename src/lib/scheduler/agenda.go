@@ -0,0 +1,120 @@
+package scheduler
+
+import (
+	"container/heap"
+)
+
+// The agenda is a priority queue ordered by the time at which the entries
+// should be delivered. Entries with the same time are delivered in the order
+// they were enqueued.
+
+type agendaItem struct {
+	entry entry
+	order uint64
+}
+
+type agendaHeap []agendaItem
+
+func (h agendaHeap) Len() int { return len(h) }
+
+func (h agendaHeap) Less(i, j int) bool {
+	ti, tj := h[i].entry.At.Time(), h[j].entry.At.Time()
+	if ti.Equal(tj) {
+		return h[i].order < h[j].order
+	}
+	return ti.Before(tj)
+}
+
+func (h agendaHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *agendaHeap) Push(x interface{}) {
+	*h = append(*h, x.(agendaItem))
+}
+
+func (h *agendaHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+type agenda struct {
+	items agendaHeap
+	next  uint64
+}
+
+func (a *agenda) Len() int {
+	return a.items.Len()
+}
+
+func (a *agenda) enqueue(e entry) {
+	heap.Push(&a.items, agendaItem{entry: e, order: a.next})
+	a.next++
+}
+
+func (a *agenda) peek() (entry, bool) {
+	if a.items.Len() == 0 {
+		return entry{}, false
+	}
+	return a.items[0].entry, true
+}
+
+func (a *agenda) dequeue() (entry, bool) {
+	if a.items.Len() == 0 {
+		return entry{}, false
+	}
+	return heap.Pop(&a.items).(agendaItem).entry, true
+}
+
+// Swaps `e` with the first pending entry that matches, the entries swap their
+// delivery times too, and returns the matching entry. Returns `e` if no entry
+// matches.
+func (a *agenda) swapFirst(e entry, match func(entry) bool) (entry, bool) {
+	first := -1
+	for i, item := range a.items {
+		if match(item.entry) && (first == -1 || a.items.Less(i, first)) {
+			first = i
+		}
+	}
+	if first == -1 {
+		return e, false
+	}
+	next := a.items[first].entry
+	e.At, next.At = next.At, e.At
+	a.items[first].entry = e
+	return next, true
+}
+
+// Removes the pending timer `id` of `reactor`, if any.
+func (a *agenda) removeTimer(reactor string, id string) {
+	items := a.items[:0]
+	for _, item := range a.items {
+		if !isTimerOf(item.entry, reactor, id) {
+			items = append(items, item)
+		}
+	}
+	a.items = items
+	heap.Init(&a.items)
+}
+
+func isTimerOf(e entry, reactor string, id string) bool {
+	return e.isTimer() && e.To == reactor && e.timerId() == id
+}
+
+// Entries in the order they would be dequeued.
+func (a *agenda) entries() []entry {
+	clone := a.clone()
+	entries := make([]entry, 0, clone.Len())
+	for clone.Len() > 0 {
+		e, _ := clone.dequeue()
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func (a *agenda) clone() agenda {
+	items := make(agendaHeap, len(a.items))
+	copy(items, a.items)
+	return agenda{items: items, next: a.next}
+}
@@ -0,0 +1,154 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/symbiont-io/detsys-testkit/src/lib"
+)
+
+func loadTest(db *sql.DB, testId lib.TestId) ([]entry, error) {
+	var blob []byte
+	err := db.QueryRow(`SELECT agenda FROM test_info WHERE test_id = ?`,
+		testId.TestId).Scan(&blob)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("loadTest: no test with id: %d", testId.TestId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []entry
+	if err := json.Unmarshal(blob, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// The number of executors that run the test's reactors, i.e. how many
+// executors the scheduler needs to wait for before it can create a run.
+func loadTotalExecutors(db *sql.DB, testId lib.TestId) (int, error) {
+	var blob []byte
+	err := db.QueryRow(`SELECT deployment FROM test_info WHERE test_id = ?`,
+		testId.TestId).Scan(&blob)
+	if err != nil {
+		return 0, err
+	}
+	// Tests created without a deployment run on a single executor.
+	if blob == nil {
+		return 1, nil
+	}
+	var deployment []lib.DeploymentInfo
+	if err := json.Unmarshal(blob, &deployment); err != nil {
+		return 0, err
+	}
+	executors := make(map[string]bool)
+	for _, d := range deployment {
+		executors[d.ExecutorUrl()] = true
+	}
+	if len(executors) == 0 {
+		return 1, nil
+	}
+	return len(executors), nil
+}
+
+// Picks the next run id of the test and claims it by writing the run's
+// `CreateRun` event. Both happen in one transaction, which takes the database's
+// write lock up front, so that other schedulers, also the Clojure one and those
+// in other processes, can't pick the same run id.
+func createRunEvent(db *sql.DB, testId lib.TestId, event json.RawMessage) (lib.RunId, error) {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return lib.RunId{}, err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `BEGIN IMMEDIATE`); err != nil {
+		return lib.RunId{}, err
+	}
+	runId, err := func() (lib.RunId, error) {
+		var runId lib.RunId
+		if err := conn.QueryRowContext(ctx,
+			`SELECT IFNULL(MAX(run_id), -1) + 1 FROM run_info WHERE test_id = ?`,
+			testId.TestId).Scan(&runId.RunId); err != nil {
+			return lib.RunId{}, err
+		}
+		meta, err := eventMeta(testId, runId)
+		if err != nil {
+			return lib.RunId{}, err
+		}
+		if _, err := conn.ExecContext(ctx, `INSERT INTO event_log(event, meta, data) VALUES(?,?,?)`,
+			"CreateRun", meta, []byte(event)); err != nil {
+			return lib.RunId{}, err
+		}
+		return runId, nil
+	}()
+	if err != nil {
+		conn.ExecContext(ctx, `ROLLBACK`)
+		return lib.RunId{}, err
+	}
+	if _, err := conn.ExecContext(ctx, `COMMIT`); err != nil {
+		conn.ExecContext(ctx, `ROLLBACK`)
+		return lib.RunId{}, err
+	}
+	return runId, nil
+}
+
+func eventMeta(testId lib.TestId, runId lib.RunId) ([]byte, error) {
+	return json.Marshal(struct {
+		Component string     `json:"component"`
+		TestId    lib.TestId `json:"test-id"`
+		RunId     lib.RunId  `json:"run-id"`
+	}{
+		Component: "scheduler",
+		TestId:    testId,
+		RunId:     runId,
+	})
+}
+
+// An event that a command writes to the `event_log`. The events are only
+// written once the command succeeds, see `Scheduler.command`.
+type pendingEvent struct {
+	testId lib.TestId
+	runId  lib.RunId
+	event  string
+	data   interface{}
+}
+
+// Writes the events in a single transaction.
+func appendEvents(db *sql.DB, events []pendingEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		meta, err := eventMeta(e.testId, e.runId)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		data, err := json.Marshal(e.data)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO event_log(event, meta, data) VALUES(?,?,?)`,
+			e.event, meta, data); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (d *data) appendEvent(event string, data interface{}) {
+	d.events = append(d.events, pendingEvent{d.testId, d.runId, event, data})
+}
+
+func (d *data) appendNetworkTrace(trace networkTrace) {
+	d.appendEvent("NetworkTrace", trace)
+}
@@ -0,0 +1,153 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+
+	"github.com/symbiont-io/detsys-testkit/src/lib"
+)
+
+// An event as returned by an executor, before it has been given a time and
+// put on the agenda.
+type event struct {
+	Kind            string          `json:"kind"`
+	Event           string          `json:"event,omitempty"`
+	Args            json.RawMessage `json:"args"`
+	From            string          `json:"from"`
+	To              string          `json:"to,omitempty"`
+	SentLogicalTime *int            `json:"sent-logical-time,omitempty"`
+	DurationNs      *int64          `json:"duration-ns,omitempty"`
+}
+
+type entry struct {
+	event
+	At   instant       `json:"at"`
+	Meta *lib.MetaInfo `json:"meta,omitempty"`
+}
+
+func (e entry) isTimer() bool {
+	return e.Kind == "timer"
+}
+
+func (e entry) isCancelTimer() bool {
+	return e.Kind == "cancel-timer"
+}
+
+// I/O requests are sent back to the reactor which made them, as I/O
+// completions, after their latency.
+func (e entry) isIO() bool {
+	return e.Kind == "io"
+}
+
+// The id of a named timer, or of the timer to cancel, empty for anonymous
+// timers and other entries.
+func (e entry) timerId() string {
+	if !e.isTimer() && !e.isCancelTimer() {
+		return ""
+	}
+	var args struct {
+		Id string `json:"id"`
+	}
+	if err := json.Unmarshal(e.Args, &args); err != nil {
+		return ""
+	}
+	return args.Id
+}
+
+// Executors may address a message to a set of receivers, the scheduler
+// expands such events into one event per receiver.
+type receivers []string
+
+func (rs *receivers) UnmarshalJSON(bs []byte) error {
+	var to string
+	if err := json.Unmarshal(bs, &to); err == nil {
+		*rs = receivers{to}
+		return nil
+	}
+	var tos []string
+	if err := json.Unmarshal(bs, &tos); err != nil {
+		return err
+	}
+	*rs = receivers(tos)
+	return nil
+}
+
+type executorEvent struct {
+	Kind       string          `json:"kind"`
+	Event      string          `json:"event"`
+	Args       json.RawMessage `json:"args"`
+	From       string          `json:"from"`
+	To         receivers       `json:"to"`
+	DurationNs *int64          `json:"duration-ns"`
+}
+
+func expandEvents(evs []executorEvent) []event {
+	events := make([]event, 0, len(evs))
+	for _, ev := range evs {
+		if ev.Kind == "timer" || ev.Kind == "cancel-timer" {
+			events = append(events, event{
+				Kind:       ev.Kind,
+				Args:       ev.Args,
+				From:       ev.From,
+				DurationNs: ev.DurationNs,
+			})
+			continue
+		}
+		for _, to := range ev.To {
+			events = append(events, event{
+				Kind:       ev.Kind,
+				Event:      ev.Event,
+				Args:       ev.Args,
+				From:       ev.From,
+				To:         to,
+				DurationNs: ev.DurationNs,
+			})
+		}
+	}
+	return events
+}
+
+var clientRegexp = regexp.MustCompile(`^client:(\d+)$`)
+
+func isClient(s string) bool {
+	return clientRegexp.MatchString(s)
+}
+
+func parseClientId(s string) int {
+	match := clientRegexp.FindStringSubmatch(s)
+	if match == nil {
+		return -1
+	}
+	id, err := strconv.Atoi(match[1])
+	if err != nil {
+		return -1
+	}
+	return id
+}
+
+// The data of the `NetworkTrace` events, which the `network_trace` and
+// `jepsen_history` views are built on.
+type networkTrace struct {
+	Message           string          `json:"message"`
+	Args              json.RawMessage `json:"args"`
+	From              string          `json:"from"`
+	To                string          `json:"to"`
+	Kind              string          `json:"kind"`
+	SentLogicalTime   *int            `json:"sent-logical-time"`
+	RecvLogicalTime   int             `json:"recv-logical-time"`
+	RecvSimulatedTime instant         `json:"recv-simulated-time"`
+	RecvLocalTime     instant         `json:"recv-local-time"`
+	Dropped           bool            `json:"dropped"`
+	JepsenType        string          `json:"jepsen-type,omitempty"`
+	JepsenProcess     *int            `json:"jepsen-process,omitempty"`
+}
+
+func jepsenProcess(client string) *int {
+	id := parseClientId(client)
+	return &id
+}
+
+func intPtr(i int) *int {
+	return &i
+}
@@ -0,0 +1,135 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/symbiont-io/detsys-testkit/src/lib"
+)
+
+// The scheduler speaks the same protocol as `detsys-scheduler`, i.e. it
+// accepts `lib.SchedulerRequest`s, so the functions in `lib` that talk to the
+// scheduler work against it as well.
+func (s *Scheduler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if r.Method != "POST" {
+		http.Error(w, jsonError("Method is not supported."), http.StatusNotFound)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, jsonError(err.Error()), http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		Command    string          `json:"command"`
+		Parameters json.RawMessage `json:"parameters"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil ||
+		req.Command == "" || len(req.Parameters) == 0 || req.Parameters[0] != '{' {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		http.Error(w, fmt.Sprintf("Invalid command: %s", body), http.StatusBadRequest)
+		return
+	}
+
+	output, err := s.dispatch(req.Command, req.Parameters)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch err.(type) {
+		case StateError, ParameterError:
+			status = http.StatusBadRequest
+		}
+		http.Error(w, jsonError(err.Error()), status)
+		return
+	}
+	bs, err := json.Marshal(output)
+	if err != nil {
+		http.Error(w, jsonError(err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Write(bs)
+}
+
+func (s *Scheduler) dispatch(command string, parameters json.RawMessage) (interface{}, error) {
+	switch command {
+	case "load-test!":
+		var params struct {
+			TestId lib.TestId `json:"test-id"`
+		}
+		if err := json.Unmarshal(parameters, &params); err != nil {
+			return nil, err
+		}
+		return s.command(func(d *data) (interface{}, error) {
+			return s.loadTest(d, params.TestId)
+		})
+	case "register-executor!":
+		var params struct {
+			ExecutorId string   `json:"executor-id"`
+			Components []string `json:"components"`
+		}
+		if err := json.Unmarshal(parameters, &params); err != nil {
+			return nil, err
+		}
+		return s.command(func(d *data) (interface{}, error) {
+			return s.registerExecutor(d, params.ExecutorId, params.Components)
+		})
+	case "create-run!":
+		return s.command(func(d *data) (interface{}, error) {
+			return s.createRun(d, parameters)
+		})
+	case "step!":
+		return s.command(s.step)
+	case "run!":
+		return s.run()
+	case "status":
+		return s.command(s.status)
+	case "reset":
+		return s.command(s.reset)
+	default:
+		return nil, fmt.Errorf("Unknown command: %s", command)
+	}
+}
+
+func jsonError(s string) string {
+	bs, _ := json.Marshal(struct {
+		Error string `json:"error"`
+	}{s})
+	return string(bs)
+}
+
+// Serves the scheduler on the same port as `detsys-scheduler` would, i.e.
+// `DETSYS_SCHEDULER_PORT` or 3000.
+func (s *Scheduler) Deploy(srv *http.Server) {
+	port, ok := os.LookupEnv("DETSYS_SCHEDULER_PORT")
+	if !ok {
+		port = "3000"
+	}
+	srv.Addr = ":" + port
+	srv.Handler = s
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		panic(err)
+	}
+}
+
+// Serves a new scheduler on a port picked by the OS and returns a client for
+// it and a function that stops it, see `lib.Spec.NewScheduler`.
+func Start() (*lib.SchedulerClient, func(), error) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return nil, nil, err
+	}
+	db := lib.OpenDB()
+	srv := &http.Server{Handler: New(db)}
+	go srv.Serve(l)
+	stop := func() {
+		srv.Close()
+		db.Close()
+	}
+	url := fmt.Sprintf("http://%s", l.Addr())
+	return lib.NewSchedulerClient(url, &http.Client{}), stop, nil
+}
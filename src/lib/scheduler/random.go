@@ -0,0 +1,41 @@
+package scheduler
+
+import (
+	"math"
+)
+
+// ---------------------------------------------------------------------
+// A port of `java.util.Random`, which is what the Clojure scheduler uses to
+// timestamp events. Using the same generator means that a given seed yields
+// the same run no matter which scheduler executed it.
+
+const (
+	javaMultiplier int64 = 0x5DEECE66D
+	javaAddend     int64 = 0xB
+	javaMask       int64 = (1 << 48) - 1
+)
+
+type javaRandom struct {
+	seed int64
+}
+
+func newJavaRandom(seed int64) *javaRandom {
+	return &javaRandom{(seed ^ javaMultiplier) & javaMask}
+}
+
+func (r *javaRandom) next(bits uint) int32 {
+	r.seed = (r.seed*javaMultiplier + javaAddend) & javaMask
+	return int32(uint64(r.seed) >> (48 - bits))
+}
+
+func (r *javaRandom) nextLong() int64 {
+	return int64(r.next(32))<<32 + int64(r.next(32))
+}
+
+func (r *javaRandom) nextDouble() float64 {
+	return float64(int64(r.next(26))<<27+int64(r.next(27))) * (1.0 / float64(int64(1)<<53))
+}
+
+func exponential(r *javaRandom, mean float64) float64 {
+	return -mean * math.Log(r.nextDouble())
+}
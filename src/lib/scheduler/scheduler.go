@@ -0,0 +1,1106 @@
+package scheduler
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/symbiont-io/detsys-testkit/src/lib"
+)
+
+// ---------------------------------------------------------------------
+// A Go implementation of the scheduler, i.e. `detsys-scheduler`, which can run
+// inside the test process. It implements the same commands, talks to the
+// executors using the same HTTP API and writes the same `NetworkTrace` and
+// `CreateRun` events to the `event_log`, so the debugger, LDFI and the
+// checkers work the same no matter which scheduler was used.
+
+type state string
+
+const (
+	started               state = "started"
+	testPrepared          state = "test-prepared"
+	waitingForExecutors   state = "waiting-for-executors"
+	executorsPrepared     state = "executors-prepared"
+	initsPrepared         state = "inits-prepared"
+	ready                 state = "ready"
+	requesting            state = "requesting"
+	responding            state = "responding"
+	finished              state = "finished"
+	failed                state = "failed"
+	errorCannotLoadTest   state = "error-cannot-load-test-in-this-state"
+	errorCannotRegister   state = "error-cannot-register-in-this-state"
+	errorTooManyExecutors state = "error-too-many-executors"
+	errorCannotCreateRun  state = "error-cannot-create-run-in-this-state"
+	errorCannotEnqueue    state = "error-cannot-enqueue-in-this-state"
+	errorCannotExecute    state = "error-cannot-execute-in-this-state"
+)
+
+const (
+	defaultTotalExecutors int     = 1
+	defaultTickFrequency  float64 = 50
+	clientTimeoutMs       float64 = 30 * 1000
+	clientDelayMs         float64 = 1 * 1000
+	meanDelayMs           float64 = 20
+)
+
+// A command that isn't allowed in the current state of the scheduler. The
+// state of the scheduler is left unchanged.
+type StateError struct {
+	State string
+}
+
+func (e StateError) Error() string {
+	return e.State
+}
+
+func stateError(s state) error {
+	return StateError{string(s)}
+}
+
+// A command with parameters that the scheduler can't use. The state of the
+// scheduler is left unchanged.
+type ParameterError struct {
+	Reason string
+}
+
+func (e ParameterError) Error() string {
+	return e.Reason
+}
+
+type data struct {
+	totalExecutors     int
+	connectedExecutors int
+	topology           map[string]string
+	agenda             agenda
+	seed               int64
+	runSeed            lib.Seed
+	faults             []lib.SchedulerFault
+	appliedFaults      []bool // Delays, reorderings and duplicates only apply once, indexed like `faults`.
+	clocks             map[string]lib.Clock
+	network            *lib.NetworkModel
+	links              map[link]linkState
+	clock              time.Time
+	nextTick           time.Time
+	tickFrequency      float64
+	minTimeNs          float64
+	maxTimeNs          float64
+	clientRequests     []entry
+	logicalClock       int
+	stopped            bool // An executor asked to stop the run, as an invariant was violated.
+	contacted          bool // An executor acted on a request of the current command.
+	state              state
+	testId             lib.TestId
+	runId              lib.RunId
+	events             []pendingEvent // Written to the database once the command succeeds.
+}
+
+func initData() data {
+	return data{
+		totalExecutors:     defaultTotalExecutors,
+		connectedExecutors: 0,
+		topology:           make(map[string]string),
+		agenda:             agenda{},
+		seed:               1,
+		faults:             []lib.SchedulerFault{},
+		appliedFaults:      []bool{},
+		clocks:             map[string]lib.Clock{},
+		links:              map[link]linkState{},
+		clock:              initClock(),
+		nextTick:           initClock(),
+		tickFrequency:      defaultTickFrequency,
+		minTimeNs:          0,
+		maxTimeNs:          0,
+		clientRequests:     []entry{},
+		logicalClock:       0,
+		state:              started,
+	}
+}
+
+func (d data) clone() data {
+	topology := make(map[string]string, len(d.topology))
+	for reactor, executor := range d.topology {
+		topology[reactor] = executor
+	}
+	d.topology = topology
+	d.agenda = d.agenda.clone()
+	d.faults = append([]lib.SchedulerFault{}, d.faults...)
+	d.appliedFaults = append([]bool{}, d.appliedFaults...)
+	links := make(map[link]linkState, len(d.links))
+	for l, state := range d.links {
+		links[l] = state
+	}
+	d.links = links
+	d.clientRequests = append([]entry{}, d.clientRequests...)
+	d.events = nil
+	return d
+}
+
+type Scheduler struct {
+	mu     sync.Mutex
+	db     *sql.DB
+	client *http.Client
+	data   data
+}
+
+func New(db *sql.DB) *Scheduler {
+	return &Scheduler{
+		db:     db,
+		client: &http.Client{Timeout: 30 * time.Second},
+		data:   initData(),
+	}
+}
+
+// Runs a command against a copy of the scheduler's state, the copy only
+// replaces the current state, and the events the command emitted are only
+// written, if the command succeeds. Requests that an executor has acted on
+// can't be taken back, which is why a command makes them last and `run!`
+// consists of one command per step. If the command fails after an executor
+// acted, e.g. a tick that reached some of the executors, the state and the
+// events so far are kept and the run fails, rather than letting the
+// scheduler's view and the executors' drift apart.
+func (s *Scheduler) command(f func(d *data) (interface{}, error)) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.data.clone()
+	d.contacted = false
+	output, err := f(&d)
+	if err == nil {
+		if err = appendEvents(s.db, d.events); err == nil {
+			d.events = nil
+			s.data = d
+			return output, nil
+		}
+	} else if d.contacted {
+		if err := appendEvents(s.db, d.events); err != nil {
+			log.Printf("Couldn't log the progress of run %d: %v\n", d.runId.RunId, err)
+		}
+	}
+	if d.contacted {
+		log.Printf("Run %d failed after an executor acted: %v\n", d.runId.RunId, err)
+		d.events = nil
+		d.state = failed
+		s.data = d
+	}
+	return nil, err
+}
+
+// ---------------------------------------------------------------------
+// Commands
+
+type loadTestOutput struct {
+	QueueSize int `json:"queue-size"`
+}
+
+func (s *Scheduler) loadTest(d *data, testId lib.TestId) (interface{}, error) {
+	if d.state != started {
+		return nil, stateError(errorCannotLoadTest)
+	}
+	entries, err := loadTest(s.db, testId)
+	if err != nil {
+		return nil, err
+	}
+	totalExecutors, err := loadTotalExecutors(s.db, testId)
+	if err != nil {
+		return nil, err
+	}
+	d.totalExecutors = totalExecutors
+	for _, e := range entries {
+		d.agenda.enqueue(e)
+	}
+	d.state = testPrepared
+	return loadTestOutput{d.agenda.Len()}, nil
+}
+
+func (s *Scheduler) LoadTest(testId lib.TestId) (lib.QueueSize, error) {
+	output, err := s.command(func(d *data) (interface{}, error) {
+		return s.loadTest(d, testId)
+	})
+	if err != nil {
+		return lib.QueueSize{}, err
+	}
+	return lib.QueueSize{QueueSize: output.(loadTestOutput).QueueSize}, nil
+}
+
+type registerExecutorOutput struct {
+	RemainingExecutors int `json:"remaining-executors"`
+}
+
+func (s *Scheduler) registerExecutor(d *data, executorId string, components []string) (interface{}, error) {
+	if d.state != testPrepared && d.state != waitingForExecutors {
+		return nil, stateError(errorCannotRegister)
+	}
+	d.connectedExecutors++
+	for _, component := range components {
+		d.topology[component] = executorId
+	}
+	switch {
+	case d.connectedExecutors < d.totalExecutors:
+		d.state = waitingForExecutors
+	case d.connectedExecutors == d.totalExecutors:
+		d.state = executorsPrepared
+	default:
+		return nil, stateError(errorTooManyExecutors)
+	}
+	remaining := d.totalExecutors - d.connectedExecutors
+	if remaining < 0 {
+		remaining = 0
+	}
+	return registerExecutorOutput{remaining}, nil
+}
+
+func (s *Scheduler) RegisterExecutor(executorId string, components []string) error {
+	_, err := s.command(func(d *data) (interface{}, error) {
+		return s.registerExecutor(d, executorId, components)
+	})
+	return err
+}
+
+type createRunOutput struct {
+	RunId lib.RunId `json:"run-id"`
+}
+
+// The reactors are initialised once the run is created, so that `Init` sees
+// the run's seed, and the initial events are timestamped like any other events,
+// i.e. with the run's clocks and network model.
+func (s *Scheduler) createRun(d *data, raw json.RawMessage) (interface{}, error) {
+	if d.state != executorsPrepared {
+		return nil, stateError(errorCannotCreateRun)
+	}
+	var req lib.CreateRunRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return nil, err
+	}
+	if err := lib.ValidateClocks(req.Clocks); err != nil {
+		return nil, ParameterError{err.Error()}
+	}
+	if err := lib.ValidateNetwork(req.Network); err != nil {
+		return nil, ParameterError{err.Error()}
+	}
+	// The run id is claimed by writing the event right away, the executors
+	// need it when initialising their reactors.
+	runId, err := createRunEvent(s.db, req.TestId, raw)
+	if err != nil {
+		return nil, err
+	}
+	d.runId = runId
+	d.testId = req.TestId
+	d.seed = int64(req.Seed)
+	d.runSeed = req.Seed
+	d.tickFrequency = req.TickFrequency
+	d.minTimeNs = float64(req.MinTimeNs)
+	d.maxTimeNs = float64(req.MaxTimeNs)
+	d.faults = req.Faults
+	if d.faults == nil {
+		d.faults = []lib.SchedulerFault{}
+	}
+	d.appliedFaults = make([]bool, len(d.faults))
+	d.clocks = req.Clocks
+	d.network = req.Network
+	d.links = map[link]linkState{}
+	d.stopped = false
+	executors := make([]string, 0, d.totalExecutors)
+	for _, executorId := range d.topology {
+		executors = append(executors, executorId)
+	}
+	sort.Strings(executors)
+	for i, executorId := range executors {
+		if i > 0 && executorId == executors[i-1] {
+			continue
+		}
+		if err := s.getInitialEvents(d, executorId); err != nil {
+			return nil, err
+		}
+	}
+	for _, e := range d.faultEntries() {
+		d.agenda.enqueue(e)
+	}
+	d.state = ready
+	return createRunOutput{runId}, nil
+}
+
+func (s *Scheduler) CreateRun(testId lib.TestId, event lib.CreateRunEvent) (lib.RunId, error) {
+	raw, err := json.Marshal(lib.NewCreateRunRequest(testId, event))
+	if err != nil {
+		return lib.RunId{}, err
+	}
+	output, err := s.command(func(d *data) (interface{}, error) {
+		return s.createRun(d, raw)
+	})
+	if err != nil {
+		return lib.RunId{}, err
+	}
+	return output.(createRunOutput).RunId, nil
+}
+
+type stepOutput struct {
+	Events    []event `json:"events"`
+	QueueSize int     `json:"queue-size"`
+}
+
+func (s *Scheduler) step(d *data) (interface{}, error) {
+	if d.state != ready && d.state != requesting {
+		return nil, stateError(errorCannotExecute)
+	}
+	events, err := s.executeOrTick(d)
+	if err != nil {
+		return nil, err
+	}
+	entries := d.timestampEntries(events, d.clock)
+	queueSize, err := s.enqueueTimestampedEntries(d, entries)
+	if err != nil {
+		return nil, err
+	}
+	return stepOutput{events, queueSize}, nil
+}
+
+func (s *Scheduler) Step() (json.RawMessage, error) {
+	output, err := s.command(s.step)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(output)
+}
+
+type runOutput struct {
+	Steps int `json:"steps"`
+}
+
+// Every step is a command of its own, so that a step that fails doesn't undo
+// the steps that the executors have already taken.
+func (s *Scheduler) run() (interface{}, error) {
+	steps := 0
+	for {
+		output, err := s.command(func(d *data) (interface{}, error) {
+			if d.state == finished {
+				return nil, nil
+			}
+			return s.step(d)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if output == nil {
+			return runOutput{steps}, nil
+		}
+		steps++
+	}
+}
+
+func (s *Scheduler) Run() error {
+	_, err := s.run()
+	return err
+}
+
+type statusOutput struct {
+	TotalExecutors     int                  `json:"total-executors"`
+	ConnectedExecutors int                  `json:"connected-executors"`
+	Topology           map[string]string    `json:"topology"`
+	Agenda             []entry              `json:"agenda"`
+	Seed               int64                `json:"seed"`
+	Faults             []lib.SchedulerFault `json:"faults"`
+	Clock              instant              `json:"clock"`
+	NextTick           instant              `json:"next-tick"`
+	TickFrequency      float64              `json:"tick-frequency"`
+	MinTimeNs          float64              `json:"min-time-ns"`
+	MaxTimeNs          float64              `json:"max-time-ns"`
+	ClientRequests     []entry              `json:"client-requests"`
+	ClientTimeoutMs    float64              `json:"client-timeout-ms"`
+	ClientDelayMs      float64              `json:"client-delay-ms"`
+	LogicalClock       int                  `json:"logical-clock"`
+	State              state                `json:"state"`
+	TestId             lib.TestId           `json:"test-id"`
+	RunId              lib.RunId            `json:"run-id"`
+}
+
+func (s *Scheduler) status(d *data) (interface{}, error) {
+	return statusOutput{
+		TotalExecutors:     d.totalExecutors,
+		ConnectedExecutors: d.connectedExecutors,
+		Topology:           d.topology,
+		Agenda:             d.agenda.entries(),
+		Seed:               d.seed,
+		Faults:             d.faults,
+		Clock:              instant(d.clock),
+		NextTick:           instant(d.nextTick),
+		TickFrequency:      d.tickFrequency,
+		MinTimeNs:          d.minTimeNs,
+		MaxTimeNs:          d.maxTimeNs,
+		ClientRequests:     d.clientRequests,
+		ClientTimeoutMs:    clientTimeoutMs,
+		ClientDelayMs:      clientDelayMs,
+		LogicalClock:       d.logicalClock,
+		State:              d.state,
+		TestId:             d.testId,
+		RunId:              d.runId,
+	}, nil
+}
+
+func (s *Scheduler) Status() (map[string]interface{}, error) {
+	output, err := s.command(s.status)
+	if err != nil {
+		return nil, err
+	}
+	bs, err := json.Marshal(output)
+	if err != nil {
+		return nil, err
+	}
+	var status map[string]interface{}
+	if err := json.Unmarshal(bs, &status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+func (s *Scheduler) reset(d *data) (interface{}, error) {
+	*d = initData()
+	return "reset", nil
+}
+
+func (s *Scheduler) Reset() error {
+	_, err := s.command(s.reset)
+	return err
+}
+
+// ---------------------------------------------------------------------
+// Stepping
+
+func (s *Scheduler) executeOrTick(d *data) ([]event, error) {
+	if next, ok := d.agenda.peek(); ok {
+		if d.nextTick.Before(next.At.Time()) {
+			return s.tick(d)
+		}
+		s.expireClients(d, d.expiredClients(next.At.Time()))
+		return s.execute(d)
+	}
+	// NOTE: Unlike `detsys-scheduler` we also tick when the next tick is
+	// exactly at the minimum time, otherwise the clock never gets past the
+	// minimum time and the run never finishes.
+	if !d.nextTick.After(plusNanos(initClock(), d.minTimeNs)) {
+		return s.tick(d)
+	}
+	d.clock = d.nextTick
+	d.state = responding
+	return []event{}, nil
+}
+
+func (d *data) hasClientRequestFrom(from string) bool {
+	for _, req := range d.clientRequests {
+		if req.From == from {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *data) componentCrashed(to string) bool {
+	for _, fault := range d.faults {
+		if fault.Kind == "crash" && fault.From == to && fault.At <= d.logicalClock {
+			return true
+		}
+	}
+	return false
+}
+
+// I/O completions don't travel over the network, so only crashes drop them.
+func (d *data) shouldDrop(e entry) bool {
+	if e.isIO() {
+		return d.componentCrashed(e.To)
+	}
+	for _, fault := range d.faults {
+		if fault.Kind == "omission" &&
+			fault.From == e.From &&
+			fault.To == e.To &&
+			fault.At == d.logicalClock {
+			return true
+		}
+		partition := lib.Partition{Groups: fault.Groups, From: fault.At, To: fault.Until}
+		if fault.Kind == "partition" && partition.Separates(e.From, e.To, d.logicalClock) {
+			return true
+		}
+	}
+	return d.componentCrashed(e.To)
+}
+
+// The index of the fault of the given kind that applies to the entry, if it
+// would be received at logical time `at`. Link faults don't apply to I/O
+// completions.
+func (d *data) linkFault(kind string, e entry, at int) (int, bool) {
+	if e.isIO() {
+		return -1, false
+	}
+	for i, fault := range d.faults {
+		if fault.Kind == kind &&
+			fault.From == e.From &&
+			fault.To == e.To &&
+			fault.At == at &&
+			!d.appliedFaults[i] {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// Swaps the entry with the next entry on the same link, if a reorder fault
+// applies to it.
+func (d *data) reorder(e entry) entry {
+	i, ok := d.linkFault("reorder", e, d.logicalClock+1)
+	if !ok {
+		return e
+	}
+	next, ok := d.agenda.swapFirst(e, func(other entry) bool {
+		return other.From == e.From && other.To == e.To && !other.isTimer() && !other.isIO()
+	})
+	if !ok {
+		return e
+	}
+	d.appliedFaults[i] = true
+	return next
+}
+
+// Restarts and disk faults are delivered to the executors like the other
+// events, so that they show up in the network trace. Their arguments are the
+// fault itself.
+func (d *data) faultEntries() []entry {
+	var entries []entry
+	for _, fault := range d.faults {
+		switch fault.Kind {
+		case "restart", "disk-full", "torn-write", "corrupt-read", "disk-latency":
+		default:
+			continue
+		}
+		args, err := json.Marshal(fault)
+		if err != nil {
+			panic(err)
+		}
+		entries = append(entries, entry{
+			event: event{
+				Kind:  "fault",
+				Event: fault.Kind,
+				Args:  args,
+				From:  fault.From,
+				To:    fault.From,
+			},
+			At: instant(plusNanos(initClock(), float64(fault.AtNs))),
+		})
+	}
+	return entries
+}
+
+// The reactor's local time at the scheduler's time `at`, see `lib.Clock`.
+func (d *data) localTime(reactor string, at time.Time) time.Time {
+	return lib.LocalTime(d.clocks[reactor], lib.ClockSkews(d.faults, reactor), at)
+}
+
+// When the reactor resumes, if it's paused at the given time.
+func (d *data) pausedUntil(reactor string, at time.Time) (time.Time, bool) {
+	for _, fault := range d.faults {
+		if fault.Kind != "pause" || fault.From != reactor {
+			continue
+		}
+		from := plusNanos(initClock(), float64(fault.AtNs))
+		until := plusNanos(initClock(), float64(fault.UntilNs))
+		if !at.Before(from) && at.Before(until) {
+			return until, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Puts the entry back on the agenda, if a delay fault applies to it.
+func (d *data) delayed(e entry) bool {
+	i, ok := d.linkFault("delay", e, d.logicalClock+1)
+	if !ok {
+		return false
+	}
+	d.appliedFaults[i] = true
+	delayed := e
+	delayed.At = instant(e.At.Time().Add(time.Duration(d.faults[i].ByNs)))
+	d.agenda.enqueue(delayed)
+	return true
+}
+
+func (d *data) shouldDuplicate(e entry) bool {
+	i, ok := d.linkFault("duplicate", e, d.logicalClock)
+	if ok {
+		d.appliedFaults[i] = true
+	}
+	return ok
+}
+
+func (s *Scheduler) execute(d *data) ([]event, error) {
+	logicalClockBefore := d.logicalClock
+	e, _ := d.agenda.dequeue()
+	// Entries for a paused reactor queue up until it resumes.
+	if until, ok := d.pausedUntil(e.To, e.At.Time()); ok {
+		held := e
+		held.At = instant(until)
+		d.agenda.enqueue(held)
+		d.clock = e.At.Time()
+		d.state = responding
+		return []event{}, nil
+	}
+	e = d.reorder(e)
+	if d.delayed(e) {
+		d.clock = e.At.Time()
+		d.state = responding
+		return []event{}, nil
+	}
+	delay := d.hasClientRequestFrom(e.From)
+	d.clock = e.At.Time()
+	if delay {
+		delayed := e
+		delayed.At = instant(plusMillis(e.At.Time(), clientDelayMs))
+		d.agenda.enqueue(delayed)
+	} else {
+		d.logicalClock++
+	}
+	d.state = responding
+
+	url, ok := d.topology[e.To]
+	if !ok {
+		return nil, fmt.Errorf("Target `%s' isn't in topology.", e.To)
+	}
+	body := e
+	body.Meta = &lib.MetaInfo{
+		TestId:        d.testId,
+		RunId:         d.runId,
+		LogicalTime:   d.logicalClock,
+		Seed:          d.runSeed,
+		SimulatedTime: e.At.Time(),
+	}
+	// Reactors see their own clock's time, the network trace has both.
+	body.At = instant(d.localTime(e.To, e.At.Time()))
+	dropped := d.shouldDrop(e)
+	if !dropped && delay {
+		return []event{}, nil
+	}
+
+	fromClient := isClient(body.From)
+	sentLogicalTime := body.SentLogicalTime
+	if sentLogicalTime == nil && fromClient {
+		sentLogicalTime = intPtr(logicalClockBefore)
+	}
+	trace := networkTrace{
+		Message:           body.Event,
+		Args:              body.Args,
+		From:              body.From,
+		To:                body.To,
+		Kind:              body.Kind,
+		SentLogicalTime:   sentLogicalTime,
+		RecvLogicalTime:   d.logicalClock,
+		RecvSimulatedTime: instant(d.clock),
+		RecvLocalTime:     body.At,
+		Dropped:           dropped,
+	}
+	if fromClient {
+		trace.JepsenType = "invoke"
+		trace.JepsenProcess = jepsenProcess(body.From)
+	}
+	d.appendNetworkTrace(trace)
+
+	if dropped {
+		return []event{}, nil
+	}
+	// The duplicate is delivered after the entries that are already due at
+	// the same time.
+	if d.shouldDuplicate(e) {
+		d.agenda.enqueue(e)
+	}
+
+	path := "event"
+	if body.isTimer() {
+		path = "timer"
+	} else if body.Kind == "fault" {
+		path = "fault"
+	}
+	evs, stop, err := s.request("POST", url, path, body)
+	if err != nil {
+		return nil, err
+	}
+	d.contacted = true
+	if stop != "" {
+		log.Printf("Stopping run %d: %s\n", d.runId.RunId, stop)
+		d.stopped = true
+	}
+
+	var clientResponses, internal []event
+	for _, ev := range expandEvents(evs) {
+		if ev.Kind == "ok" && isClient(ev.To) {
+			clientResponses = append(clientResponses, ev)
+		} else {
+			ev.SentLogicalTime = intPtr(d.logicalClock)
+			internal = append(internal, ev)
+		}
+	}
+	if internal == nil {
+		internal = []event{}
+	}
+
+	sent := d.logicalClock
+	if fromClient {
+		d.clientRequests = append(d.clientRequests, body)
+	}
+	if len(clientResponses) > 0 {
+		d.logicalClock++
+	}
+	d.removeClientRequests(clientResponses)
+
+	for _, resp := range clientResponses {
+		var args struct {
+			Response json.RawMessage `json:"response"`
+		}
+		if err := json.Unmarshal(resp.Args, &args); err != nil {
+			return nil, err
+		}
+		d.appendNetworkTrace(networkTrace{
+			Message:           resp.Event,
+			Args:              args.Response,
+			From:              resp.From,
+			To:                resp.To,
+			Kind:              "ok",
+			SentLogicalTime:   intPtr(sent),
+			RecvLogicalTime:   d.logicalClock,
+			RecvSimulatedTime: instant(d.clock),
+			RecvLocalTime:     instant(d.clock),
+			Dropped:           false,
+			JepsenType:        "ok",
+			JepsenProcess:     jepsenProcess(resp.To),
+		})
+	}
+	return internal, nil
+}
+
+func (d *data) removeClientRequests(responses []event) {
+	clients := make(map[string]bool)
+	for _, resp := range responses {
+		clients[resp.To] = true
+	}
+	requests := make([]entry, 0, len(d.clientRequests))
+	for _, req := range d.clientRequests {
+		if !clients[req.From] {
+			requests = append(requests, req)
+		}
+	}
+	d.clientRequests = requests
+}
+
+func (d *data) expiredClients(now time.Time) []entry {
+	var expired []entry
+	keep := make([]entry, 0, len(d.clientRequests))
+	for _, req := range d.clientRequests {
+		if plusMillis(req.At.Time(), clientTimeoutMs).Before(now) {
+			expired = append(expired, req)
+		} else {
+			keep = append(keep, req)
+		}
+	}
+	d.clientRequests = keep
+	return expired
+}
+
+func (s *Scheduler) expireClients(d *data, expired []entry) {
+	for _, client := range expired {
+		d.appendNetworkTrace(networkTrace{
+			Message:           client.Event,
+			Args:              client.Args,
+			From:              client.To,
+			To:                client.From,
+			Kind:              client.Kind,
+			SentLogicalTime:   intPtr(d.logicalClock),
+			RecvLogicalTime:   d.logicalClock,
+			RecvSimulatedTime: instant(d.clock),
+			RecvLocalTime:     instant(d.clock),
+			Dropped:           false,
+			JepsenType:        "info",
+			JepsenProcess:     jepsenProcess(client.From),
+		})
+	}
+}
+
+func (s *Scheduler) tick(d *data) ([]event, error) {
+	reactors := make([]string, 0, len(d.topology))
+	for reactor := range d.topology {
+		reactors = append(reactors, reactor)
+	}
+	sort.Strings(reactors)
+
+	events := []event{}
+	for _, reactor := range reactors {
+		if _, paused := d.pausedUntil(reactor, d.nextTick); paused {
+			continue
+		}
+		evs, stop, err := s.request("PUT", d.topology[reactor], "tick", struct {
+			At      instant      `json:"at"`
+			Reactor string       `json:"reactor"`
+			Meta    lib.MetaInfo `json:"meta"`
+		}{instant(d.localTime(reactor, d.nextTick)), reactor, lib.MetaInfo{
+			TestId:        d.testId,
+			RunId:         d.runId,
+			LogicalTime:   d.logicalClock,
+			Seed:          d.runSeed,
+			SimulatedTime: d.nextTick,
+		}})
+		if err != nil {
+			return nil, err
+		}
+		d.contacted = true
+		if stop != "" {
+			log.Printf("Stopping run %d: %s\n", d.runId.RunId, stop)
+			d.stopped = true
+		}
+		events = append(events, expandEvents(evs)...)
+	}
+	for i := range events {
+		events[i].SentLogicalTime = intPtr(d.logicalClock + 1)
+	}
+
+	d.clock = d.nextTick
+	d.nextTick = plusMillis(d.nextTick, d.tickFrequency)
+	if len(events) > 0 {
+		d.logicalClock++
+	}
+	d.state = responding
+	return events, nil
+}
+
+type link struct {
+	from string
+	to   string
+}
+
+type linkState struct {
+	// When the last message on the link is done being transmitted.
+	free time.Time
+	// When the last message on the link is delivered.
+	last time.Time
+}
+
+// When a message sent at `timestamp` is delivered, according to the network
+// model. Without a latency for the link, the latency is drawn like it is for
+// timers and I/O.
+// The size of a message is that of its arguments as compact JSON, without
+// escaping HTML and keeping numbers as they are, which is how the Clojure
+// scheduler's `json/write` measures it.
+func messageSize(args json.RawMessage) int {
+	dec := json.NewDecoder(bytes.NewReader(args))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return len(args)
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return len(args)
+	}
+	// Without the newline that `Encode` appends.
+	return buf.Len() - 1
+}
+
+func (d *data) deliverAt(r *javaRandom, e entry, timestamp time.Time) time.Time {
+	model := d.network.Link(e.From, e.To)
+	if model.Latency == nil && !model.FIFO && model.Bandwidth <= 0 {
+		return plusMillis(timestamp, exponential(r, meanDelayMs))
+	}
+	l := link{e.From, e.To}
+	state := d.links[l]
+	start := timestamp
+	if state.free.After(start) {
+		start = state.free
+	}
+	state.free = start.Add(model.Transmission(messageSize(e.Args)))
+	var at time.Time
+	if model.Latency == nil {
+		at = plusMillis(state.free, exponential(r, meanDelayMs))
+	} else {
+		at = state.free.Add(model.Latency.Sample(r.nextDouble))
+	}
+	// Entries with the same time are delivered in the order they were
+	// enqueued.
+	if model.FIFO && at.Before(state.last) {
+		at = state.last
+	}
+	state.last = at
+	d.links[l] = state
+	return at
+}
+
+// Each event gets delivered after an exponentially distributed delay, timers
+// additionally wait for their duration.
+func (d *data) timestampEntries(events []event, timestamp time.Time) []entry {
+	r := newJavaRandom(d.seed)
+	newSeed := r.nextLong()
+	entries := make([]entry, 0, len(events))
+	for _, ev := range events {
+		e := entry{event: ev}
+		var duration int64
+		if ev.DurationNs != nil && *ev.DurationNs > 0 {
+			duration = *ev.DurationNs
+		}
+		var at time.Time
+		switch {
+		case e.isTimer():
+			// Timers are set on the reactor's clock.
+			at = plusMillis(timestamp, exponential(r, meanDelayMs))
+			at = plusNanos(at, float64(d.clocks[ev.From].Global(time.Duration(duration))))
+			e.To = ev.From
+			e.Event = "timer"
+		case e.isIO():
+			at = plusMillis(timestamp, exponential(r, meanDelayMs))
+			at = plusNanos(at, float64(duration))
+			e.To = ev.From
+		case e.isCancelTimer():
+			at = plusMillis(timestamp, exponential(r, meanDelayMs))
+		default:
+			at = d.deliverAt(r, e, timestamp)
+		}
+		e.At = instant(at)
+		entries = append(entries, e)
+	}
+	d.seed = newSeed
+	return entries
+}
+
+func (d *data) minTime() bool {
+	return plusNanos(initClock(), d.minTimeNs).Before(d.clock)
+}
+
+func (d *data) maxTime() bool {
+	return d.maxTimeNs != 0 && plusNanos(initClock(), d.maxTimeNs).Before(d.clock)
+}
+
+// Cancelling a named timer, or setting it again, removes the reactor's pending
+// timer with the same id. Cancellations are applied before the entries are
+// enqueued, so that a run whose last pending timer got cancelled finishes.
+func (d *data) cancelTimers(entries []entry) []entry {
+	keep := make([]entry, 0, len(entries))
+	without := func(reactor string, id string) {
+		d.agenda.removeTimer(reactor, id)
+		kept := keep[:0]
+		for _, e := range keep {
+			if !isTimerOf(e, reactor, id) {
+				kept = append(kept, e)
+			}
+		}
+		keep = kept
+	}
+	for _, e := range entries {
+		switch {
+		case e.isCancelTimer():
+			if e.timerId() != "" {
+				without(e.From, e.timerId())
+			}
+		case e.isTimer() && e.timerId() != "":
+			without(e.To, e.timerId())
+			keep = append(keep, e)
+		default:
+			keep = append(keep, e)
+		}
+	}
+	return keep
+}
+
+func (s *Scheduler) enqueueTimestampedEntries(d *data, entries []entry) (int, error) {
+	entries = d.cancelTimers(entries)
+	if (len(entries) == 0 && d.agenda.Len() == 0 && d.minTime()) || d.maxTime() || d.stopped {
+		s.expireClients(d, d.clientRequests)
+		switch d.state {
+		case responding:
+			d.state = finished
+		case executorsPrepared, initsPrepared:
+			d.state = initsPrepared
+		case waitingForExecutors:
+		default:
+			return 0, stateError(errorCannotEnqueue)
+		}
+		return d.agenda.Len(), nil
+	}
+
+	switch d.state {
+	case responding:
+		d.state = requesting
+	case executorsPrepared, initsPrepared:
+		d.state = initsPrepared
+	case waitingForExecutors:
+	default:
+		return 0, stateError(errorCannotEnqueue)
+	}
+	for _, e := range entries {
+		d.agenda.enqueue(e)
+	}
+	return d.agenda.Len(), nil
+}
+
+func (s *Scheduler) getInitialEvents(d *data, executorId string) error {
+	evs, _, err := s.request("POST", executorId, "inits", struct {
+		Meta lib.MetaInfo `json:"meta"`
+	}{lib.MetaInfo{
+		TestId:        d.testId,
+		RunId:         d.runId,
+		LogicalTime:   d.logicalClock,
+		Seed:          d.runSeed,
+		SimulatedTime: d.clock,
+	}})
+	if err != nil {
+		return err
+	}
+	d.contacted = true
+	events := expandEvents(evs)
+	for i := range events {
+		events[i].SentLogicalTime = intPtr(d.logicalClock)
+	}
+	_, err = s.enqueueTimestampedEntries(d, d.timestampEntries(events, d.clock))
+	return err
+}
+
+// ---------------------------------------------------------------------
+// Executor communication
+
+func (s *Scheduler) request(method string, executorId string, path string, body interface{}) ([]executorEvent, string, error) {
+	var reqBody []byte
+	if body != nil {
+		bs, err := json.Marshal(body)
+		if err != nil {
+			return nil, "", err
+		}
+		reqBody = bs
+	}
+	url := strings.TrimSuffix(executorId, "/") + "/" + path
+	req, err := http.NewRequest(method, url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("%s %s: %s\n%s", method, url, resp.Status, respBody)
+	}
+	var events struct {
+		Events []executorEvent `json:"events"`
+		// Why the run should stop, if it should.
+		Stop string `json:"stop"`
+	}
+	if err := json.Unmarshal(respBody, &events); err != nil {
+		log.Printf("%s %s: couldn't parse response: %s\n", method, url, respBody)
+		return nil, "", err
+	}
+	return events.Events, events.Stop, nil
+}
@@ -0,0 +1,848 @@
+package scheduler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/symbiont-io/detsys-testkit/src/lib"
+)
+
+func TestJavaRandom(t *testing.T) {
+	// Expected values taken from `new java.util.Random(42)`.
+	r := newJavaRandom(42)
+	if got := r.nextLong(); got != -5025562857975149833 {
+		t.Errorf("nextLong: %d", got)
+	}
+	r = newJavaRandom(42)
+	if got := r.nextDouble(); got != 0.7275636800328681 {
+		t.Errorf("nextDouble: %v", got)
+	}
+}
+
+func TestInstantString(t *testing.T) {
+	tests := map[time.Duration]string{
+		0:                     "1970-01-01T00:00:00Z",
+		20 * time.Millisecond: "1970-01-01T00:00:00.020Z",
+		20 * time.Microsecond: "1970-01-01T00:00:00.000020Z",
+		3*time.Second + 7:     "1970-01-01T00:00:03.000000007Z",
+	}
+	for d, expected := range tests {
+		if got := instant(initClock().Add(d)).String(); got != expected {
+			t.Errorf("Expected %s, got %s", expected, got)
+		}
+	}
+}
+
+func TestAgendaIsStable(t *testing.T) {
+	var a agenda
+	at := instant(initClock())
+	a.enqueue(entry{event: event{Event: "b"}, At: instant(initClock().Add(time.Second))})
+	a.enqueue(entry{event: event{Event: "a1"}, At: at})
+	a.enqueue(entry{event: event{Event: "a2"}, At: at})
+	for _, expected := range []string{"a1", "a2", "b"} {
+		e, ok := a.dequeue()
+		if !ok || e.Event != expected {
+			t.Errorf("Expected %s, got %+v", expected, e)
+		}
+	}
+}
+
+func timerEntry(reactor string, id string, at time.Duration) entry {
+	args := json.RawMessage(fmt.Sprintf(`{"id":%q}`, id))
+	if id == "" {
+		args = json.RawMessage(`{}`)
+	}
+	return entry{
+		event: event{Kind: "timer", Event: "timer", Args: args, From: reactor, To: reactor},
+		At:    instant(initClock().Add(at)),
+	}
+}
+
+func TestCancelTimers(t *testing.T) {
+	d := &data{}
+	d.agenda.enqueue(timerEntry("a", "resend", time.Second))
+	d.agenda.enqueue(timerEntry("a", "", time.Second))
+	d.agenda.enqueue(timerEntry("b", "resend", 2*time.Second))
+	d.agenda.enqueue(timerEntry("a", "heartbeat", 3*time.Second))
+
+	entries := d.cancelTimers([]entry{
+		// Resets a's "resend" timer.
+		timerEntry("a", "resend", 4*time.Second),
+		{event: event{Kind: "cancel-timer", Args: json.RawMessage(`{"id":"heartbeat"}`), From: "a"}},
+		// An empty id doesn't cancel the anonymous timers.
+		{event: event{Kind: "cancel-timer", Args: json.RawMessage(`{"id":""}`), From: "a"}},
+		timerEntry("b", "once", 5*time.Second),
+		{event: event{Kind: "cancel-timer", Args: json.RawMessage(`{"id":"once"}`), From: "b"}},
+	})
+	for _, e := range entries {
+		d.agenda.enqueue(e)
+	}
+
+	var got []string
+	for _, e := range d.agenda.entries() {
+		got = append(got, fmt.Sprintf("%s:%s@%s", e.To, e.timerId(), e.At))
+	}
+	expected := []string{
+		"a:@1970-01-01T00:00:01Z",
+		"b:resend@1970-01-01T00:00:02Z",
+		"a:resend@1970-01-01T00:00:04Z",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+type write struct{}
+
+func (_ write) MessageEvent() string { return "write" }
+
+func TestClocks(t *testing.T) {
+	d := &data{
+		seed:   1,
+		clocks: map[string]lib.Clock{"a": {Offset: time.Minute, Drift: 1}},
+		faults: []lib.SchedulerFault{
+			{Kind: "clock-skew", From: "a", AtNs: time.Second, ByNs: time.Hour},
+		},
+	}
+	at := initClock().Add(2 * time.Second)
+	if got, expected := d.localTime("a", at), initClock().Add(time.Hour+time.Minute+4*time.Second); !got.Equal(expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	if got := d.localTime("b", at); !got.Equal(at) {
+		t.Errorf("Expected reactors without a clock to see the scheduler's time, got %v", got)
+	}
+
+	// A timer of two seconds on a clock which runs twice as fast takes one.
+	duration := int64(2 * time.Second)
+	timers := d.timestampEntries([]event{
+		{Kind: "timer", Args: json.RawMessage(`{}`), From: "a", DurationNs: &duration},
+		{Kind: "timer", Args: json.RawMessage(`{}`), From: "b", DurationNs: &duration},
+	}, initClock())
+	if timers[0].At.Time().After(initClock().Add(1500*time.Millisecond)) ||
+		timers[1].At.Time().Before(initClock().Add(2*time.Second)) {
+		t.Errorf("Unexpected timers: %+v", timers)
+	}
+}
+
+func TestNetworkModel(t *testing.T) {
+	d := initData()
+	d.network = &lib.NetworkModel{
+		Default: lib.LinkModel{Latency: lib.Uniform(0, time.Second), FIFO: true},
+		Links: []lib.Link{
+			{From: "a", To: "c", LinkModel: lib.LinkModel{Latency: lib.Uniform(0, 0), Bandwidth: 1000}},
+		},
+	}
+	args := json.RawMessage(fmt.Sprintf("%q", strings.Repeat("x", 98)))
+	var events []event
+	for i := 0; i < 10; i++ {
+		events = append(events, event{Kind: "message", Args: args, From: "a", To: "b"})
+	}
+	for i := 0; i < 3; i++ {
+		events = append(events, event{Kind: "message", Args: args, From: "a", To: "c"})
+	}
+	entries := d.timestampEntries(events, initClock())
+
+	// FIFO links deliver in the order the messages were sent.
+	for i := 1; i < 10; i++ {
+		if entries[i].At.Time().Before(entries[i-1].At.Time()) {
+			t.Errorf("Message %d overtook message %d: %v", i, i-1, entries)
+		}
+	}
+	// Every message of 100 bytes takes up the link for 100ms.
+	for i := 0; i < 3; i++ {
+		expected := initClock().Add(time.Duration(i+1) * 100 * time.Millisecond)
+		if at := entries[10+i].At.Time(); !at.Equal(expected) {
+			t.Errorf("Expected %v, got %v", expected, at)
+		}
+	}
+	// The link is still busy with the last message.
+	later := d.timestampEntries(events[10:11], initClock().Add(50*time.Millisecond))
+	if at, expected := later[0].At.Time(), initClock().Add(400*time.Millisecond); !at.Equal(expected) {
+		t.Errorf("Expected %v, got %v", expected, at)
+	}
+}
+
+func TestMessageSize(t *testing.T) {
+	tests := []struct {
+		args string
+		size int
+	}{
+		{`{"value": 1.0, "tags": ["<a>"]}`, len(`{"tags":["<a>"],"value":1.0}`)},
+		{`{"s":"\u003c"}`, len(`{"s":"<"}`)},
+		{`not json`, len(`not json`)},
+	}
+	for _, test := range tests {
+		if got := messageSize(json.RawMessage(test.args)); got != test.size {
+			t.Errorf("%s: expected %d, got %d", test.args, test.size, got)
+		}
+	}
+}
+
+func TestIOCompletions(t *testing.T) {
+	bs := lib.MarshalUnscheduledEvents("node", 0, []lib.OutEvent{
+		{To: lib.Singleton("node"), Args: &lib.IORequest{Id: 1, Request: write{}, Latency: time.Second}},
+	})
+	var out struct {
+		Events []executorEvent `json:"events"`
+	}
+	if err := json.Unmarshal(bs, &out); err != nil {
+		t.Fatal(err)
+	}
+	d := &data{seed: 1}
+	entries := d.timestampEntries(expandEvents(out.Events), initClock())
+	if len(entries) != 1 {
+		t.Fatalf("Expected one entry, got: %+v", entries)
+	}
+	e := entries[0]
+	if !e.isIO() || e.Event != "write" || e.From != "node" || e.To != "node" ||
+		string(e.Args) != `{"id":1,"request":{}}` {
+		t.Errorf("Unexpected I/O entry: %+v", e)
+	}
+	if e.At.Time().Before(initClock().Add(time.Second)) {
+		t.Errorf("Expected the I/O to complete after its latency, at: %v", e.At)
+	}
+}
+
+func messageEntry(from string, to string, name string, at time.Duration) entry {
+	return entry{
+		event: event{Kind: "message", Event: name, Args: json.RawMessage(`{}`), From: from, To: to},
+		At:    instant(initClock().Add(at)),
+	}
+}
+
+func TestPartition(t *testing.T) {
+	d := &data{faults: []lib.SchedulerFault{
+		{Kind: "partition", Groups: [][]string{{"a"}, {"b", "c"}}, At: 2, Until: 4},
+	}}
+	tests := []struct {
+		from, to string
+		at       int
+		dropped  bool
+	}{
+		{"a", "b", 1, false},
+		{"a", "b", 2, true},
+		{"c", "a", 3, true},
+		{"b", "c", 3, false},
+		{"client:0", "a", 3, false},
+		{"a", "b", 4, false},
+	}
+	for _, test := range tests {
+		d.logicalClock = test.at
+		if got := d.shouldDrop(messageEntry(test.from, test.to, "m", 0)); got != test.dropped {
+			t.Errorf("%s -> %s at %d: expected dropped to be %v", test.from, test.to, test.at, test.dropped)
+		}
+	}
+}
+
+func TestReorderAndDelay(t *testing.T) {
+	d := &data{
+		faults: []lib.SchedulerFault{
+			{Kind: "reorder", From: "a", To: "b", At: 1},
+			{Kind: "delay", From: "a", To: "b", At: 1, ByNs: time.Second},
+		},
+		appliedFaults: make([]bool, 2),
+	}
+	d.agenda.enqueue(messageEntry("a", "c", "other", 2*time.Millisecond))
+	d.agenda.enqueue(messageEntry("a", "b", "second", 3*time.Millisecond))
+
+	// The first message swaps places with the second, which then gets
+	// delayed.
+	next := d.reorder(messageEntry("a", "b", "first", time.Millisecond))
+	if next.Event != "second" || next.At != instant(initClock().Add(time.Millisecond)) {
+		t.Errorf("Unexpected reordering: %+v", next)
+	}
+	if !d.delayed(next) {
+		t.Errorf("Expected %+v to be delayed", next)
+	}
+	// The faults only apply once.
+	if again := d.reorder(next); again.Event != "second" || d.delayed(next) {
+		t.Errorf("Expected the faults to be applied once")
+	}
+
+	var got []string
+	for _, e := range d.agenda.entries() {
+		got = append(got, fmt.Sprintf("%s@%s", e.Event, e.At))
+	}
+	expected := []string{
+		"other@1970-01-01T00:00:00.002Z",
+		"first@1970-01-01T00:00:00.003Z",
+		"second@1970-01-01T00:00:01.001Z",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestDuplicate(t *testing.T) {
+	d := &data{
+		faults:        []lib.SchedulerFault{{Kind: "duplicate", From: "a", To: "b", At: 1}},
+		appliedFaults: make([]bool, 1),
+		logicalClock:  1,
+	}
+	e := messageEntry("a", "b", "m", 0)
+	if !d.shouldDuplicate(e) {
+		t.Errorf("Expected %+v to be duplicated", e)
+	}
+	// The duplicate itself isn't duplicated again.
+	if d.shouldDuplicate(e) {
+		t.Errorf("Expected the fault to be applied once")
+	}
+}
+
+func TestLinkFaultsSkipIO(t *testing.T) {
+	d := &data{
+		faults: []lib.SchedulerFault{
+			{Kind: "omission", From: "a", To: "a", At: 1},
+			{Kind: "duplicate", From: "a", To: "a", At: 1},
+			{Kind: "delay", From: "a", To: "a", At: 2, ByNs: time.Second},
+			{Kind: "reorder", From: "a", To: "a", At: 2},
+		},
+		appliedFaults: make([]bool, 4),
+		logicalClock:  1,
+	}
+	e := messageEntry("a", "a", "write", 0)
+	e.Kind = "io"
+	if d.shouldDrop(e) || d.shouldDuplicate(e) || d.delayed(e) {
+		t.Errorf("Expected link faults not to apply to %+v", e)
+	}
+	later := e
+	later.At = instant(initClock().Add(time.Second))
+	d.agenda.enqueue(later)
+	if next := d.reorder(e); next.At != e.At {
+		t.Errorf("Expected %+v not to be reordered, got %+v", e, next)
+	}
+}
+
+func TestPauseAndRestart(t *testing.T) {
+	d := &data{faults: []lib.SchedulerFault{
+		{Kind: "pause", From: "a", AtNs: time.Second, UntilNs: 3 * time.Second},
+		{Kind: "restart", From: "b", AtNs: 2 * time.Second},
+		{Kind: "disk-full", From: "b", AtNs: time.Second, UntilNs: 2 * time.Second},
+	}}
+	resume := initClock().Add(3 * time.Second)
+	tests := []struct {
+		reactor string
+		at      time.Duration
+		paused  bool
+	}{
+		{"a", 999 * time.Millisecond, false},
+		{"a", time.Second, true},
+		{"b", 2 * time.Second, false},
+		{"a", 2999 * time.Millisecond, true},
+		{"a", 3 * time.Second, false},
+	}
+	for _, test := range tests {
+		until, paused := d.pausedUntil(test.reactor, initClock().Add(test.at))
+		if paused != test.paused || (paused && !until.Equal(resume)) {
+			t.Errorf("%s at %v: expected paused to be %v, got %v until %v",
+				test.reactor, test.at, test.paused, paused, until)
+		}
+	}
+
+	entries := d.faultEntries()
+	if len(entries) != 2 || entries[0].Kind != "fault" || entries[0].Event != "restart" ||
+		entries[0].To != "b" || entries[0].At != instant(initClock().Add(2*time.Second)) {
+		t.Fatalf("Unexpected fault entries: %+v", entries)
+	}
+	// Disk faults are handed to the executor as they are.
+	var fault lib.Fault
+	if err := json.Unmarshal(entries[1].Args, &fault); err != nil {
+		t.Fatal(err)
+	}
+	expected := lib.DiskFull{Node: "b", From: time.Second, To: 2 * time.Second}
+	if entries[1].Event != "disk-full" || fault.Args != expected ||
+		entries[1].At != instant(initClock().Add(time.Second)) {
+		t.Errorf("Unexpected disk fault entry: %+v, %+v", entries[1], fault)
+	}
+}
+
+// The views used by the scheduler are tables here, so that the test doesn't
+// depend on SQLite's JSON extension.
+func openTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "detsys.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE event_log (id INTEGER PRIMARY KEY, event TEXT, meta JSON, data JSON)`,
+		`CREATE TABLE test_info (test_id INTEGER, agenda JSON, deployment JSON)`,
+		`CREATE TABLE run_info (test_id INTEGER, run_id INTEGER)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func status(t *testing.T, s *Scheduler) map[string]interface{} {
+	status, err := s.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return status
+}
+
+// An executor with a single reactor, "node", that acknowledges every client
+// request, and asks for the run to be stopped after every request or tick
+// unless `stop` is empty.
+func fakeExecutor(t *testing.T, stop string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/inits", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"events":[]}`)
+	})
+	mux.HandleFunc("/event", func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		var e entry
+		if err := json.Unmarshal(body, &e); err != nil {
+			t.Error(err)
+		}
+		fmt.Fprintf(w, `{"events":[{"kind":"ok","event":"ack","from":"node","to":["%s"],"args":{"id":0,"response":{}}}],"stop":%q}`,
+			e.From, stop)
+	})
+	mux.HandleFunc("/tick", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"events":[],"stop":%q}`, stop)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestRun(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	executor := fakeExecutor(t, "")
+	defer executor.Close()
+
+	if _, err := db.Exec(`INSERT INTO test_info VALUES (0, ?, ?)`,
+		`[{"kind":"invoke","event":"write","args":{"value":1},"from":"client:0","to":"node","at":"1970-01-01T00:00:00Z"}]`,
+		`[{"reactor":"node","type":"node","args":{}}]`); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(db)
+	testId := lib.TestId{TestId: 0}
+	qs, err := s.LoadTest(testId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if qs.QueueSize != 1 {
+		t.Errorf("Expected queue size 1, got %d", qs.QueueSize)
+	}
+	if _, err := s.LoadTest(testId); err == nil {
+		t.Error("Expected loading a test twice to fail")
+	}
+	if err := s.RegisterExecutor(executor.URL+"/", []string{"node"}); err != nil {
+		t.Fatal(err)
+	}
+	runId, err := s.CreateRun(testId, lib.CreateRunEvent{
+		Seed:          lib.Seed(4),
+		Faults:        lib.Faults{},
+		TickFrequency: 1000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runId.RunId != 0 {
+		t.Errorf("Expected run id 0, got %d", runId.RunId)
+	}
+	// The run id is claimed by the run's event.
+	var createRuns int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM event_log WHERE event = 'CreateRun'`).Scan(&createRuns); err != nil {
+		t.Fatal(err)
+	}
+	if createRuns != 1 {
+		t.Errorf("Expected one CreateRun event, got %d", createRuns)
+	}
+	if err := s.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if state := status(t, s)["state"]; state != string(finished) {
+		t.Errorf("Expected the run to be finished, but the state is: %s", state)
+	}
+
+	rows, err := db.Query(`SELECT data FROM event_log WHERE event = 'NetworkTrace' ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var traces []networkTrace
+	for rows.Next() {
+		var blob []byte
+		if err := rows.Scan(&blob); err != nil {
+			t.Fatal(err)
+		}
+		var trace networkTrace
+		if err := json.Unmarshal(blob, &trace); err != nil {
+			t.Fatal(err)
+		}
+		traces = append(traces, trace)
+	}
+	if len(traces) != 2 {
+		t.Fatalf("Expected an invoke and an ok in the network trace, got: %+v", traces)
+	}
+	if traces[0].JepsenType != "invoke" || traces[0].RecvLogicalTime != 1 {
+		t.Errorf("Unexpected invoke: %+v", traces[0])
+	}
+	if traces[1].JepsenType != "ok" || traces[1].To != "client:0" || traces[1].RecvLogicalTime != 2 {
+		t.Errorf("Unexpected ok: %+v", traces[1])
+	}
+}
+
+// A step that fails leaves neither the scheduler's state nor the event log
+// changed, so that it can be retried.
+func TestFailedStep(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	fail := true
+	mux := http.NewServeMux()
+	mux.HandleFunc("/inits", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"events":[]}`)
+	})
+	mux.HandleFunc("/event", func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"events":[]}`)
+	})
+	executor := httptest.NewServer(mux)
+	defer executor.Close()
+
+	if _, err := db.Exec(`INSERT INTO test_info VALUES (0, ?, ?)`,
+		`[{"kind":"invoke","event":"write","args":{"value":1},"from":"client:0","to":"node","at":"1970-01-01T00:00:00Z"}]`,
+		`[{"reactor":"node","type":"node","args":{}}]`); err != nil {
+		t.Fatal(err)
+	}
+	s := New(db)
+	testId := lib.TestId{TestId: 0}
+	if _, err := s.LoadTest(testId); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RegisterExecutor(executor.URL+"/", []string{"node"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateRun(testId, lib.CreateRunEvent{
+		Seed:          lib.Seed(4),
+		Faults:        lib.Faults{},
+		TickFrequency: 1000,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	traces := func() int {
+		var n int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM event_log WHERE event = 'NetworkTrace'`).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	before := status(t, s)
+	if _, err := s.Step(); err == nil {
+		t.Fatal("Expected the step to fail")
+	}
+	if after := status(t, s); !reflect.DeepEqual(before, after) {
+		t.Errorf("Expected the state to be unchanged, got: %v", after)
+	}
+	if n := traces(); n != 0 {
+		t.Errorf("Expected no network trace, got %d events", n)
+	}
+
+	fail = false
+	if _, err := s.Step(); err != nil {
+		t.Fatal(err)
+	}
+	if n := traces(); n != 1 {
+		t.Errorf("Expected the invoke in the network trace, got %d events", n)
+	}
+}
+
+// A tick that reaches one executor but not the other fails the run, since the
+// first executor can't take the tick back.
+func TestFailedTick(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	executor1 := fakeExecutor(t, "")
+	defer executor1.Close()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/inits", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"events":[]}`)
+	})
+	mux.HandleFunc("/tick", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+	executor2 := httptest.NewServer(mux)
+	defer executor2.Close()
+
+	deployment, err := json.Marshal([]lib.DeploymentInfo{
+		{Reactor: "a", Type: "node", Args: json.RawMessage(`{}`), Executor: executor1.URL},
+		{Reactor: "b", Type: "node", Args: json.RawMessage(`{}`), Executor: executor2.URL},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO test_info VALUES (0, '[]', ?)`, deployment); err != nil {
+		t.Fatal(err)
+	}
+	s := New(db)
+	testId := lib.TestId{TestId: 0}
+	if _, err := s.LoadTest(testId); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RegisterExecutor(executor1.URL+"/", []string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RegisterExecutor(executor2.URL+"/", []string{"b"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateRun(testId, lib.CreateRunEvent{
+		Seed:          lib.Seed(4),
+		Faults:        lib.Faults{},
+		TickFrequency: 1000,
+		MinTimeNs:     5000000000,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Step(); err == nil {
+		t.Fatal("Expected the tick to fail")
+	}
+	if state := status(t, s)["state"]; state != string(failed) {
+		t.Errorf("Expected the run to have failed, but the state is: %s", state)
+	}
+	if _, err := s.Step(); err == nil {
+		t.Error("Expected stepping a failed run to fail")
+	}
+}
+
+func TestStop(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	executor := fakeExecutor(t, "bounded: drew 12")
+	defer executor.Close()
+
+	if _, err := db.Exec(`INSERT INTO test_info VALUES (0, ?, ?)`,
+		`[{"kind":"invoke","event":"write","args":{"value":1},"from":"client:0","to":"node","at":"1970-01-01T00:00:00Z"},
+		  {"kind":"invoke","event":"write","args":{"value":2},"from":"client:1","to":"node","at":"1970-01-01T00:00:01Z"}]`,
+		`[{"reactor":"node","type":"node","args":{}}]`); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(db)
+	testId := lib.TestId{TestId: 0}
+	if _, err := s.LoadTest(testId); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RegisterExecutor(executor.URL+"/", []string{"node"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateRun(testId, lib.CreateRunEvent{
+		Seed:          lib.Seed(4),
+		Faults:        lib.Faults{},
+		TickFrequency: 1000,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if state := status(t, s)["state"]; state != string(finished) {
+		t.Errorf("Expected the run to be finished, but the state is: %s", state)
+	}
+
+	// The run stops after the first request, so the second one is never sent.
+	rows, err := db.Query(`SELECT data FROM event_log WHERE event = 'NetworkTrace' ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var invokes int
+	for rows.Next() {
+		var blob []byte
+		if err := rows.Scan(&blob); err != nil {
+			t.Fatal(err)
+		}
+		var trace networkTrace
+		if err := json.Unmarshal(blob, &trace); err != nil {
+			t.Fatal(err)
+		}
+		if trace.JepsenType == "invoke" {
+			invokes++
+		}
+	}
+	if invokes != 1 {
+		t.Errorf("Expected a single invoke before the run stopped, got %d", invokes)
+	}
+}
+
+func TestStopOnTick(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	executor := fakeExecutor(t, "bounded: drew 12")
+	defer executor.Close()
+
+	if _, err := db.Exec(`INSERT INTO test_info VALUES (0, ?, ?)`,
+		`[{"kind":"invoke","event":"write","args":{"value":1},"from":"client:0","to":"node","at":"1970-01-01T00:00:05Z"}]`,
+		`[{"reactor":"node","type":"node","args":{}}]`); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(db)
+	testId := lib.TestId{TestId: 0}
+	if _, err := s.LoadTest(testId); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RegisterExecutor(executor.URL+"/", []string{"node"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateRun(testId, lib.CreateRunEvent{
+		Seed:          lib.Seed(4),
+		Faults:        lib.Faults{},
+		TickFrequency: 1000,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if state := status(t, s)["state"]; state != string(finished) {
+		t.Errorf("Expected the run to be finished, but the state is: %s", state)
+	}
+
+	// The run stops at the first tick, before the request is due.
+	var traces int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM event_log WHERE event = 'NetworkTrace'`).Scan(&traces); err != nil {
+		t.Fatal(err)
+	}
+	if traces != 0 {
+		t.Errorf("Expected the run to stop before the request, got %d network traces", traces)
+	}
+}
+
+func TestRegisterMultipleExecutors(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	executor1 := fakeExecutor(t, "")
+	defer executor1.Close()
+	executor2 := fakeExecutor(t, "")
+	defer executor2.Close()
+
+	deployment, err := json.Marshal([]lib.DeploymentInfo{
+		{Reactor: "frontend", Type: "frontend", Args: json.RawMessage(`{}`), Executor: executor1.URL},
+		{Reactor: "register1", Type: "register", Args: json.RawMessage(`{}`), Executor: executor2.URL},
+		{Reactor: "register2", Type: "register", Args: json.RawMessage(`{}`), Executor: executor2.URL},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO test_info VALUES (0, '[]', ?)`, deployment); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(db)
+	testId := lib.TestId{TestId: 0}
+	if _, err := s.LoadTest(testId); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RegisterExecutor(executor1.URL, []string{"frontend"}); err != nil {
+		t.Fatal(err)
+	}
+	if state := status(t, s)["state"]; state != string(waitingForExecutors) {
+		t.Errorf("Expected to wait for the second executor, but the state is: %s", state)
+	}
+	if _, err := s.CreateRun(testId, lib.CreateRunEvent{}); err == nil {
+		t.Error("Expected creating a run before all executors are registered to fail")
+	}
+	if err := s.RegisterExecutor(executor2.URL, []string{"register1", "register2"}); err != nil {
+		t.Fatal(err)
+	}
+	if state := status(t, s)["state"]; state != string(executorsPrepared) {
+		t.Errorf("Expected the executors to be prepared, but the state is: %s", state)
+	}
+	expected := map[string]interface{}{
+		"frontend":  executor1.URL,
+		"register1": executor2.URL,
+		"register2": executor2.URL,
+	}
+	if topology := status(t, s)["topology"]; !reflect.DeepEqual(topology, expected) {
+		t.Errorf("Expected topology %v, got %v", expected, topology)
+	}
+	if err := s.RegisterExecutor(executor2.URL, []string{"register1"}); err == nil {
+		t.Error("Expected registering too many executors to fail")
+	}
+}
+
+// Reactors without an executor run on the default one, whether it's named or
+// not.
+func TestLoadTotalExecutors(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	deployment, err := json.Marshal([]lib.DeploymentInfo{
+		{Reactor: "a", Type: "node", Args: json.RawMessage(`{}`)},
+		{Reactor: "b", Type: "node", Args: json.RawMessage(`{}`), Executor: lib.DefaultExecutorUrl},
+		{Reactor: "c", Type: "node", Args: json.RawMessage(`{}`), Executor: "http://localhost:4000/api/v1/"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO test_info VALUES (0, '[]', ?)`, deployment); err != nil {
+		t.Fatal(err)
+	}
+	n, err := loadTotalExecutors(db, lib.TestId{TestId: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 executors, got %d", n)
+	}
+}
+
+func TestCreateRunInvalidNetwork(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	executor := fakeExecutor(t, "")
+	defer executor.Close()
+
+	if _, err := db.Exec(`INSERT INTO test_info VALUES (0, '[]', ?)`,
+		`[{"reactor":"node","type":"node","args":{}}]`); err != nil {
+		t.Fatal(err)
+	}
+	s := New(db)
+	testId := lib.TestId{TestId: 0}
+	if _, err := s.LoadTest(testId); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RegisterExecutor(executor.URL+"/", []string{"node"}); err != nil {
+		t.Fatal(err)
+	}
+	state := status(t, s)["state"]
+
+	params, err := json.Marshal(lib.NewCreateRunRequest(testId, lib.CreateRunEvent{
+		Seed:          lib.Seed(4),
+		TickFrequency: 1000,
+		Network:       &lib.NetworkModel{Default: lib.LinkModel{Latency: &lib.Latency{Kind: "normal"}}},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := fmt.Sprintf(`{"command":"create-run!","parameters":%s}`, params)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected the network to be rejected, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := status(t, s)["state"]; got != state {
+		t.Errorf("Expected the state to be left at %s, got %s", state, got)
+	}
+}
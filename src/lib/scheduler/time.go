@@ -0,0 +1,64 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// The scheduler's clock starts at the epoch, same as `scheduler.time/init-clock`.
+func initClock() time.Time {
+	return time.Unix(0, 0).UTC()
+}
+
+// `plus-millis` in the Clojure scheduler converts to nanoseconds and truncates.
+func plusMillis(t time.Time, ms float64) time.Time {
+	return t.Add(time.Duration(int64(ms * 1000000)))
+}
+
+func plusNanos(t time.Time, ns float64) time.Time {
+	return t.Add(time.Duration(int64(ns)))
+}
+
+// Instant is serialised like `java.time.Instant.toString`, i.e. the fraction is
+// printed in groups of three digits, so that the timestamps in the event log
+// look the same regardless of which scheduler produced them.
+type instant time.Time
+
+func (i instant) Time() time.Time {
+	return time.Time(i)
+}
+
+func (i instant) String() string {
+	t := time.Time(i).UTC()
+	nanos := t.Nanosecond()
+	var fraction string
+	switch {
+	case nanos == 0:
+		fraction = ""
+	case nanos%1000000 == 0:
+		fraction = fmt.Sprintf(".%03d", nanos/1000000)
+	case nanos%1000 == 0:
+		fraction = fmt.Sprintf(".%06d", nanos/1000)
+	default:
+		fraction = fmt.Sprintf(".%09d", nanos)
+	}
+	return t.Format("2006-01-02T15:04:05") + fraction + "Z"
+}
+
+func (i instant) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.String())
+}
+
+func (i *instant) UnmarshalJSON(bs []byte) error {
+	var s string
+	if err := json.Unmarshal(bs, &s); err != nil {
+		return err
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return err
+	}
+	*i = instant(t.UTC())
+	return nil
+}
@@ -0,0 +1,210 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+)
+
+const defaultSchedulerUrl string = "http://localhost:3000"
+
+// The URL of the scheduler, can be overridden with the `DETSYS_SCHEDULER_URL`
+// environment variable.
+func SchedulerUrl() string {
+	url, ok := os.LookupEnv("DETSYS_SCHEDULER_URL")
+	if !ok {
+		url = defaultSchedulerUrl
+	}
+	return url
+}
+
+// The scheduler replied with something other than 200 OK. State errors, e.g.
+// trying to step a run that hasn't been created yet, are replied to with 400
+// Bad Request and `{"error": <state>}` as body.
+type SchedulerError struct {
+	Command    string
+	StatusCode int
+	Body       string
+}
+
+func (e *SchedulerError) Error() string {
+	return fmt.Sprintf("scheduler: %s: %d %s: %s", e.Command, e.StatusCode,
+		http.StatusText(e.StatusCode), e.Message())
+}
+
+// The error message sent by the scheduler, or the whole body if the body isn't
+// of the form `{"error": ...}`.
+func (e *SchedulerError) Message() string {
+	var body struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(e.Body), &body); err != nil || body.Error == "" {
+		return e.Body
+	}
+	return body.Error
+}
+
+type SchedulerClient struct {
+	BaseUrl    string
+	HTTPClient *http.Client
+	// How many times a command is retried if the scheduler can't be reached,
+	// and how long to wait before each retry. Note that a command is also
+	// retried if the connection broke after the scheduler received it, so
+	// retries should only be used against a scheduler that is still starting
+	// up or for commands that don't change the scheduler's state.
+	Retries    int
+	RetryDelay time.Duration
+}
+
+func NewSchedulerClient(baseUrl string, httpClient *http.Client) *SchedulerClient {
+	return &SchedulerClient{
+		BaseUrl:    baseUrl,
+		HTTPClient: httpClient,
+		Retries:    0,
+		RetryDelay: 100 * time.Millisecond,
+	}
+}
+
+func DefaultSchedulerClient() *SchedulerClient {
+	return NewSchedulerClient(SchedulerUrl(), http.DefaultClient)
+}
+
+func (c *SchedulerClient) Post(ctx context.Context, command string, parameters interface{}) ([]byte, error) {
+	bs, err := json.Marshal(SchedulerRequest{
+		Command:    command,
+		Parameters: parameters})
+	if err != nil {
+		return nil, err
+	}
+	for attempt := 0; ; attempt++ {
+		body, err := c.post(ctx, command, bs)
+		if err == nil {
+			return body, nil
+		}
+		if _, ok := err.(*SchedulerError); ok || ctx.Err() != nil || attempt >= c.Retries {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.RetryDelay):
+		}
+	}
+}
+
+func (c *SchedulerClient) post(ctx context.Context, command string, bs []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseUrl, bytes.NewBuffer(bs))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &SchedulerError{
+			Command:    command,
+			StatusCode: resp.StatusCode,
+			Body:       string(body),
+		}
+	}
+	return body, nil
+}
+
+func (c *SchedulerClient) PostParse(ctx context.Context, command string, parameters interface{}, target interface{}) error {
+	body, err := c.Post(ctx, command, parameters)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, target); err != nil {
+		return fmt.Errorf("scheduler: %s: couldn't parse response: %w", command, err)
+	}
+	return nil
+}
+
+func (c *SchedulerClient) LoadTest(ctx context.Context, testId TestId) (QueueSize, error) {
+	var queueSize QueueSize
+	err := c.PostParse(ctx, "load-test!", struct {
+		TestId TestId `json:"test-id"`
+	}{testId}, &queueSize)
+	return queueSize, err
+}
+
+func (c *SchedulerClient) RegisterExecutor(ctx context.Context, executorId string, components []string) error {
+	_, err := c.Post(ctx, "register-executor!", struct {
+		ExecutorId string   `json:"executor-id"`
+		Components []string `json:"components"`
+	}{
+		ExecutorId: executorId,
+		Components: components,
+	})
+	return err
+}
+
+// Registers the executors of the reactors in the test's deployment.
+func (c *SchedulerClient) Register(ctx context.Context, testId TestId) error {
+	urls, executors, err := executorsFromDeployment(testId)
+	if err != nil {
+		return err
+	}
+	for _, url := range urls {
+		if err := c.RegisterExecutor(ctx, url, executors[url]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Registers the executors of the reactors in the topology, use this rather
+// than `Register` if the executors' addresses are only known once they are
+// deployed.
+func (c *SchedulerClient) RegisterTopology(ctx context.Context, topology Topology) error {
+	urls, executors := topology.Executors()
+	for _, url := range urls {
+		if err := c.RegisterExecutor(ctx, url, executors[url]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *SchedulerClient) CreateRun(ctx context.Context, testId TestId, event CreateRunEvent) (RunId, error) {
+	var runId struct {
+		RunId RunId `json:"run-id"`
+	}
+	err := c.PostParse(ctx, "create-run!", NewCreateRunRequest(testId, event), &runId)
+	return runId.RunId, err
+}
+
+func (c *SchedulerClient) Run(ctx context.Context) error {
+	_, err := c.Post(ctx, "run!", struct{}{})
+	return err
+}
+
+func (c *SchedulerClient) Step(ctx context.Context) (json.RawMessage, error) {
+	var result json.RawMessage
+	err := c.PostParse(ctx, "step!", struct{}{}, &result)
+	return result, err
+}
+
+func (c *SchedulerClient) Status(ctx context.Context) (map[string]interface{}, error) {
+	var status map[string]interface{}
+	err := c.PostParse(ctx, "status", struct{}{}, &status)
+	return status, err
+}
+
+func (c *SchedulerClient) Reset(ctx context.Context) error {
+	_, err := c.Post(ctx, "reset", struct{}{})
+	return err
+}
@@ -0,0 +1,119 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSchedulerClientError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":"error-cannot-execute-in-this-state"}`)
+	}))
+	defer srv.Close()
+
+	c := NewSchedulerClient(srv.URL, srv.Client())
+	_, err := c.Step(context.Background())
+	var schedulerErr *SchedulerError
+	if !errors.As(err, &schedulerErr) {
+		t.Fatalf("Expected a SchedulerError, got: %v", err)
+	}
+	if schedulerErr.StatusCode != http.StatusBadRequest ||
+		schedulerErr.Message() != "error-cannot-execute-in-this-state" {
+		t.Errorf("Unexpected error: %#v", schedulerErr)
+	}
+}
+
+func TestSchedulerClientRetries(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			// Drop the connection without replying.
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn.Close()
+			return
+		}
+		fmt.Fprint(w, `{"queue-size":2}`)
+	}))
+	defer srv.Close()
+
+	c := NewSchedulerClient(srv.URL, srv.Client())
+	if _, err := c.LoadTest(context.Background(), TestId{0}); err == nil {
+		t.Fatal("Expected an error without retries")
+	}
+
+	requests = 0
+	c.Retries = 1
+	c.RetryDelay = time.Millisecond
+	queueSize, err := c.LoadTest(context.Background(), TestId{0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queueSize.QueueSize != 2 || requests != 2 {
+		t.Errorf("Unexpected queue size %d after %d requests", queueSize.QueueSize, requests)
+	}
+}
+
+func TestSchedulerClientContext(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	c := NewSchedulerClient(srv.URL, srv.Client())
+	c.Retries = 3
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := c.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to be exceeded, got: %v", err)
+	}
+}
+
+func TestSchedulerClientRegisterTopology(t *testing.T) {
+	var registered []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Command    string `json:"command"`
+			Parameters struct {
+				ExecutorId string   `json:"executor-id"`
+				Components []string `json:"components"`
+			} `json:"parameters"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		registered = append(registered, fmt.Sprintf("%s %s %v", req.Command,
+			req.Parameters.ExecutorId, req.Parameters.Components))
+		fmt.Fprint(w, `{"remaining-executors":0}`)
+	}))
+	defer srv.Close()
+
+	topology := NewTopology(Item{"a", nil}, Item{"b", nil})
+	topology.SetExecutorUrl("http://localhost:4000/api/v1/")
+	topology.Insert("c", nil)
+
+	c := NewSchedulerClient(srv.URL, srv.Client())
+	if err := c.RegisterTopology(context.Background(), topology); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"register-executor! http://localhost:3001/api/v1/ [c]",
+		"register-executor! http://localhost:4000/api/v1/ [a b]",
+	}
+	if !reflect.DeepEqual(registered, expected) {
+		t.Errorf("Expected %v, got %v", expected, registered)
+	}
+}
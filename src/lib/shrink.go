@@ -0,0 +1,268 @@
+package lib
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+)
+
+// ---------------------------------------------------------------------
+// `Shrink` takes a run that failed the check and looks for a smaller test and
+// run that fail the same checkers, by removing faults and agenda items using
+// delta debugging. A smaller agenda is a new test, made with
+// `GenerateTestFromTopologyAndAgenda`, and every candidate is run like the
+// original run, e.g. with its seed, clocks and network.
+
+type ShrinkReport struct {
+	// The run that was shrunk.
+	OriginalTestId TestId
+	OriginalRunId  RunId
+	OriginalAgenda int
+	OriginalFaults int
+	// The smallest run found that still fails the same checkers.
+	TestId TestId
+	RunId  RunId
+	Agenda Agenda
+	Faults []Fault
+	Reason string
+	// How many runs shrinking took.
+	Runs    int
+	Elapsed time.Duration
+}
+
+func (r ShrinkReport) String() string {
+	return fmt.Sprintf("shrunk test %d run %d (%d agenda items, %d faults) to test %d run %d (%d agenda items, %d faults) in %d runs",
+		r.OriginalTestId.TestId, r.OriginalRunId.RunId, r.OriginalAgenda, r.OriginalFaults,
+		r.TestId.TestId, r.RunId.RunId, len(r.Agenda), len(r.Faults), r.Runs)
+}
+
+// Uses the spec's topology, marshaler, deploy, checkers and scheduler, the
+// spec's `Run` and `Seeds` are replaced by how the run that's shrunk was
+// created. Returns the smallest run found so far along with the error, if a run
+// fails to complete.
+func Shrink(ctx context.Context, spec Spec, testId TestId, runId RunId) (ShrinkReport, error) {
+	start := time.Now()
+	agenda, err := loadAgenda(testId, spec.Marshaler)
+	if err != nil {
+		return ShrinkReport{}, err
+	}
+	run, err := loadRun(testId, runId)
+	if err != nil {
+		return ShrinkReport{}, err
+	}
+	spec.Run = run
+	seed, faults := run.Seed, run.Faults.Faults
+	report := ShrinkReport{
+		OriginalTestId: testId,
+		OriginalRunId:  runId,
+		OriginalAgenda: len(agenda),
+		OriginalFaults: len(faults),
+		TestId:         testId,
+		RunId:          runId,
+		Agenda:         agenda,
+		Faults:         faults,
+	}
+
+	scheduler := spec.Scheduler
+	if spec.NewScheduler != nil {
+		var stop func()
+		scheduler, stop, err = spec.NewScheduler()
+		if err != nil {
+			return report, err
+		}
+		defer stop()
+	}
+	if scheduler == nil {
+		scheduler = DefaultSchedulerClient()
+	}
+	// The checkers that the original run fails, smaller runs that fail other
+	// checkers found another bug rather than the same one.
+	var failed []string
+	replayed := false
+	fails := func(testId TestId, agenda Agenda, faults []Fault) (bool, error) {
+		run, err := runJob(ctx, spec, scheduler, testId, Job{Seed: seed, Faults: faults})
+		if err != nil {
+			return false, err
+		}
+		report.Runs++
+		if run.Passed {
+			return false, nil
+		}
+		if !replayed {
+			failed = failedCheckers(run.Results)
+			replayed = true
+		} else if !reflect.DeepEqual(failedCheckers(run.Results), failed) {
+			return false, nil
+		}
+		report.TestId = testId
+		report.RunId = run.RunId
+		report.Agenda = agenda
+		report.Faults = faults
+		report.Reason = run.Reason
+		return true, nil
+	}
+	shrinkFaults := func() error {
+		_, err := ddmin(report.Faults, func(faults []Fault) (bool, error) {
+			return fails(report.TestId, report.Agenda, faults)
+		})
+		return err
+	}
+	done := func(err error) (ShrinkReport, error) {
+		report.Elapsed = time.Since(start)
+		log.Println(report)
+		return report, err
+	}
+
+	// Runs that don't fail again can't be shrunk, e.g. because the spec
+	// doesn't match the run.
+	if ok, err := fails(testId, agenda, faults); err != nil || !ok {
+		if err == nil {
+			err = fmt.Errorf("Shrink: test %d run %d doesn't fail again", testId.TestId, runId.RunId)
+		}
+		return done(err)
+	}
+	if err := shrinkFaults(); err != nil {
+		return done(err)
+	}
+	shrunk := len(report.Agenda)
+	if _, err := ddmin(report.Agenda, func(agenda Agenda) (bool, error) {
+		if len(agenda) == 0 {
+			return false, nil
+		}
+		testId := GenerateTestFromTopologyAndAgenda(spec.Topology(), agenda)
+		return fails(testId, agenda, report.Faults)
+	}); err != nil {
+		return done(err)
+	}
+	// Removing agenda items can make more faults redundant.
+	if len(report.Agenda) < shrunk {
+		if err := shrinkFaults(); err != nil {
+			return done(err)
+		}
+	}
+	return done(nil)
+}
+
+// The checkers and models that failed, the reasons can differ between runs
+// that fail the same way.
+func failedCheckers(results []CheckResult) []string {
+	var failed []string
+	for _, result := range results {
+		if !result.Valid {
+			failed = append(failed, result.Checker+" "+result.Model)
+		}
+	}
+	return failed
+}
+
+// Zeller's ddmin, as in "Simplifying and Isolating Failure-Inducing Input",
+// restricted to removing chunks: removes ever smaller chunks of the items as
+// long as the remaining items still fail.
+func ddmin[T any](items []T, fails func([]T) (bool, error)) ([]T, error) {
+	n := 2
+	for len(items) > 0 {
+		if n > len(items) {
+			n = len(items)
+		}
+		size := (len(items) + n - 1) / n
+		reduced := false
+		for start := 0; start < len(items); start += size {
+			end := start + size
+			if end > len(items) {
+				end = len(items)
+			}
+			complement := append(append([]T{}, items[:start]...), items[end:]...)
+			ok, err := fails(complement)
+			if err != nil {
+				return items, err
+			}
+			if ok {
+				items = complement
+				reduced = true
+				if n > 2 {
+					n--
+				}
+				break
+			}
+		}
+		if !reduced {
+			if n >= len(items) {
+				break
+			}
+			n *= 2
+		}
+	}
+	return items, nil
+}
+
+func loadAgenda(testId TestId, m Marshaler) (Agenda, error) {
+	db := OpenDB()
+	defer db.Close()
+
+	var blob []byte
+	if err := db.QueryRow(`SELECT agenda FROM test_info WHERE test_id = ?`,
+		testId.TestId).Scan(&blob); err != nil {
+		return nil, err
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(blob, &items); err != nil {
+		return nil, err
+	}
+	agenda := make(Agenda, 0, len(items))
+	for _, item := range items {
+		var sev ScheduledEvent
+		if err := UnmarshalScheduledEvent(m, item, &sev); err != nil {
+			return nil, err
+		}
+		// Agendas hold their events as values, like workloads generate them
+		// and `GenerateTestFromTopologyAndAgenda` expects them.
+		switch ev := sev.Event.(type) {
+		case *ClientRequest:
+			sev.Event = *ev
+		case *InternalMessage:
+			sev.Event = *ev
+		}
+		agenda = append(agenda, sev)
+	}
+	return agenda, nil
+}
+
+// Loads how the run was created.
+func loadRun(testId TestId, runId RunId) (CreateRunEvent, error) {
+	db := OpenDB()
+	defer db.Close()
+
+	var run CreateRunEvent
+	var tickFrequency, minTimeNs, maxTimeNs sql.NullFloat64
+	var faults, clocks, network []byte
+	if err := db.QueryRow(`SELECT seed, faults, tick_frequency, min_time_ns, max_time_ns, clocks, network
+                               FROM run_info WHERE test_id = ? AND run_id = ?`,
+		testId.TestId, runId.RunId).Scan(&run.Seed, &faults, &tickFrequency,
+		&minTimeNs, &maxTimeNs, &clocks, &network); err != nil {
+		return CreateRunEvent{}, err
+	}
+	run.TickFrequency = tickFrequency.Float64
+	run.MinTimeNs = time.Duration(minTimeNs.Float64)
+	run.MaxTimeNs = time.Duration(maxTimeNs.Float64)
+	if faults != nil {
+		if err := json.Unmarshal(faults, &run.Faults.Faults); err != nil {
+			return CreateRunEvent{}, err
+		}
+	}
+	if clocks != nil {
+		if err := json.Unmarshal(clocks, &run.Clocks); err != nil {
+			return CreateRunEvent{}, err
+		}
+	}
+	if network != nil {
+		run.Network = &NetworkModel{}
+		if err := json.Unmarshal(network, run.Network); err != nil {
+			return CreateRunEvent{}, err
+		}
+	}
+	return run, nil
+}
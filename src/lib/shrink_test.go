@@ -0,0 +1,205 @@
+package lib
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDdmin(t *testing.T) {
+	items := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	contains := func(items []int, x int) bool {
+		for _, item := range items {
+			if item == x {
+				return true
+			}
+		}
+		return false
+	}
+	tries := 0
+	min, err := ddmin(items, func(items []int) (bool, error) {
+		tries++
+		return contains(items, 3) && contains(items, 7), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(min, []int{3, 7}) {
+		t.Errorf("Expected [3 7], got %v after %d tries", min, tries)
+	}
+
+	min, err = ddmin(items, func(items []int) (bool, error) { return true, nil })
+	if err != nil || len(min) != 0 {
+		t.Errorf("Expected everything to be removed, got %v, %v", min, err)
+	}
+
+	failure := errors.New("run failed to complete")
+	min, err = ddmin(items, func(items []int) (bool, error) { return false, failure })
+	if err != failure || !reflect.DeepEqual(min, items) {
+		t.Errorf("Expected the error and the items, got %v, %v", min, err)
+	}
+}
+
+// A database with the tables that `Shrink` reads, in place of the views.
+func openShrinkDB(t *testing.T) *sql.DB {
+	t.Setenv("DETSYS_DB", filepath.Join(t.TempDir(), "detsys.db"))
+	db := OpenDB()
+	for _, stmt := range []string{
+		`CREATE TABLE event_log (id INTEGER PRIMARY KEY, event TEXT, meta JSON, data JSON)`,
+		`CREATE TABLE test_info (test_id INTEGER, agenda JSON, deployment JSON)`,
+		`CREATE TABLE run_info (test_id INTEGER, run_id INTEGER, seed INTEGER, faults JSON,
+                                        tick_frequency REAL, max_time_ns INTEGER, min_time_ns INTEGER,
+                                        clocks JSON, network JSON)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+// A scheduler that only records the runs that are created, by run id.
+func shrinkScheduler(t *testing.T, runs map[int]CreateRunRequest) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Command    string          `json:"command"`
+			Parameters json.RawMessage `json:"parameters"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		switch req.Command {
+		case "create-run!":
+			var run CreateRunRequest
+			if err := json.Unmarshal(req.Parameters, &run); err != nil {
+				t.Error(err)
+			}
+			runs[len(runs)+1] = run
+			fmt.Fprintf(w, `{"run-id":%d}`, len(runs))
+		default:
+			fmt.Fprint(w, `{}`)
+		}
+	}))
+}
+
+func TestShrink(t *testing.T) {
+	db := openShrinkDB(t)
+	defer db.Close()
+	if _, err := db.Exec(`INSERT INTO test_info VALUES (0, ?, '[]')`,
+		`[{"kind":"invoke","event":"Write","args":{"value":1},"from":"client:0","to":"a","at":"1970-01-01T00:00:00Z"},
+		  {"kind":"message","event":"Write","args":{"value":2},"from":"a","to":"b","at":"1970-01-01T00:00:01Z"}]`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO run_info VALUES (0, 0, 7, ?, 10.5, 5000000000, 0, ?, ?)`,
+		`[{"kind":"omission","from":"a","to":"b","at":1},{"kind":"crash","from":"b","at":2}]`,
+		`{"a":{"offset-ns":1000,"drift":0.5}}`,
+		`{"default":{"fifo":true}}`); err != nil {
+		t.Fatal(err)
+	}
+
+	// The runs fail if they drop the message from a to b, whatever the agenda.
+	runs := make(map[int]CreateRunRequest)
+	srv := shrinkScheduler(t, runs)
+	defer srv.Close()
+	defer func(old func(TestId, RunId, CheckResult)) { appendCheckResult = old }(appendCheckResult)
+	appendCheckResult = func(TestId, RunId, CheckResult) {}
+
+	m := NewRegistry()
+	if err := RegisterType[registryWrite](m); err != nil {
+		t.Fatal(err)
+	}
+	report, err := Shrink(context.Background(), Spec{
+		Topology:  func() Topology { return NewTopology() },
+		Marshaler: m,
+		Deploy: func(Topology, Marshaler) (func(), error) {
+			return func() {}, nil
+		},
+		Checkers: []Checker{NewFuncChecker("delivered", func(_ TestId, runId RunId) (bool, string, error) {
+			for _, fault := range runs[runId.RunId].Faults {
+				if fault.Kind == "omission" {
+					return false, "dropped the write", nil
+				}
+			}
+			return true, "", nil
+		})},
+		Scheduler: NewSchedulerClient(srv.URL, srv.Client()),
+	}, TestId{0}, RunId{0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	omission := []Fault{{Kind: "omission", Args: Omission{From: "a", To: "b", At: 1}}}
+	if !reflect.DeepEqual(report.Faults, omission) || len(report.Agenda) != 1 ||
+		report.OriginalAgenda != 2 || report.OriginalFaults != 2 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	// Every run is created like the original one.
+	for runId, run := range runs {
+		if run.Seed != 7 || run.TickFrequency != 10.5 || run.MaxTimeNs != 5*time.Second ||
+			run.Clocks["a"] != (Clock{Offset: 1000, Drift: 0.5}) ||
+			run.Network == nil || !run.Network.Default.FIFO {
+			t.Errorf("Run %d wasn't created like the original run: %+v", runId, run)
+		}
+	}
+}
+
+func TestShrinkSameCheckers(t *testing.T) {
+	db := openShrinkDB(t)
+	defer db.Close()
+	if _, err := db.Exec(`INSERT INTO test_info VALUES (0, '[]', '[]')`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO run_info VALUES (0, 0, 7, ?, 10.5, 5000000000, 0, NULL, NULL)`,
+		`[{"kind":"omission","from":"a","to":"b","at":1},{"kind":"crash","from":"b","at":2}]`); err != nil {
+		t.Fatal(err)
+	}
+
+	runs := make(map[int]CreateRunRequest)
+	srv := shrinkScheduler(t, runs)
+	defer srv.Close()
+	defer func(old func(TestId, RunId, CheckResult)) { appendCheckResult = old }(appendCheckResult)
+	appendCheckResult = func(TestId, RunId, CheckResult) {}
+
+	has := func(runId RunId, kind string) bool {
+		for _, fault := range runs[runId.RunId].Faults {
+			if fault.Kind == kind {
+				return true
+			}
+		}
+		return false
+	}
+	// Only the omission fails "delivered", the crash on its own fails
+	// "recovered", which is another bug.
+	report, err := Shrink(context.Background(), Spec{
+		Topology:  func() Topology { return NewTopology() },
+		Marshaler: NewRegistry(),
+		Deploy: func(Topology, Marshaler) (func(), error) {
+			return func() {}, nil
+		},
+		Checkers: []Checker{
+			NewFuncChecker("delivered", func(_ TestId, runId RunId) (bool, string, error) {
+				return !has(runId, "omission"), "dropped the write", nil
+			}),
+			NewFuncChecker("recovered", func(_ TestId, runId RunId) (bool, string, error) {
+				return !has(runId, "crash") || has(runId, "omission"), "lost the write", nil
+			}),
+		},
+		Scheduler: NewSchedulerClient(srv.URL, srv.Client()),
+	}, TestId{0}, RunId{0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	omission := []Fault{{Kind: "omission", Args: Omission{From: "a", To: "b", At: 1}}}
+	if !reflect.DeepEqual(report.Faults, omission) {
+		t.Errorf("Expected the run to shrink to the omission, got %+v", report.Faults)
+	}
+}
@@ -13,7 +13,7 @@ func (t *TimePico) UnmarshalJSON(data []byte) error {
 	}
 	// Fractional seconds are handled implicitly by Parse.
 	it, err := time.Parse(`"2006-01-02T15:04:05.999999999999Z"`, string(data))
-	if err != nil {
+	if err == nil {
 		*t = TimePico(it)
 	}
 	return err
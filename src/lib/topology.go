@@ -10,7 +10,10 @@ import (
 // sure we use it in a deterministic way.
 
 type Topology struct {
-	topology map[string]Reactor
+	topology   map[string]Reactor
+	executors  map[string]string
+	invariants *[]Invariant
+	rebuild    *func(reactor string) Reactor
 }
 
 type Item struct {
@@ -24,7 +27,10 @@ func NewTopology(init ...Item) Topology {
 		topology[m.Name] = m.Reactor
 	}
 	return Topology{
-		topology: topology,
+		topology:   topology,
+		executors:  make(map[string]string),
+		invariants: &[]Invariant{},
+		rebuild:    new(func(reactor string) Reactor),
 	}
 }
 
@@ -52,3 +58,76 @@ func (t Topology) Reactor(reactorName string) Reactor {
 func (t Topology) Insert(reactorName string, reactor Reactor) {
 	t.topology[reactorName] = reactor
 }
+
+// Tells the executors how to build a reactor from scratch, so that reactors
+// that are neither `Durable` nor `Restartable` can be restarted.
+func (t Topology) SetRebuild(rebuild func(reactor string) Reactor) {
+	*t.rebuild = rebuild
+}
+
+// Replaces the reactor with one built from scratch, if the topology knows how
+// to build it, see `SetRebuild`.
+func (t Topology) Rebuild(reactorName string) (Reactor, bool) {
+	if t.rebuild == nil || *t.rebuild == nil {
+		return nil, false
+	}
+	reactor := (*t.rebuild)(reactorName)
+	t.Insert(reactorName, reactor)
+	return reactor, true
+}
+
+// The executor that is used for reactors which haven't been assigned one.
+const DefaultExecutorUrl string = "http://localhost:3001/api/v1/"
+
+// The URL of the executor that runs the reactor, as the scheduler should use
+// it.
+func (t Topology) ExecutorUrl(reactorName string) string {
+	url, ok := t.executors[reactorName]
+	if !ok {
+		return DefaultExecutorUrl
+	}
+	return url
+}
+
+// Assigns all reactors currently in the topology to the executor at `url`.
+func (t Topology) SetExecutorUrl(url string) {
+	for reactor := range t.topology {
+		t.executors[reactor] = url
+	}
+}
+
+// Assigns the reactors in the topology that haven't been assigned an executor
+// yet to the executor at `url`.
+func (t Topology) SetDefaultExecutorUrl(url string) {
+	for reactor := range t.topology {
+		if _, ok := t.executors[reactor]; !ok {
+			t.executors[reactor] = url
+		}
+	}
+}
+
+// Assigns a single reactor to the executor at `url`, so that the reactors of a
+// test can be spread over several executors.
+func (t Topology) SetReactorExecutorUrl(reactorName string, url string) {
+	t.executors[reactorName] = url
+}
+
+// The reactors grouped by the executor that runs them, the executors are
+// sorted by their URL.
+func (t Topology) Executors() ([]string, map[string][]string) {
+	return groupByExecutor(t.Reactors(), t.ExecutorUrl)
+}
+
+func groupByExecutor(reactors []string, executorUrl func(string) string) ([]string, map[string][]string) {
+	urls := make([]string, 0)
+	executors := make(map[string][]string)
+	for _, reactor := range reactors {
+		url := executorUrl(reactor)
+		if _, ok := executors[url]; !ok {
+			urls = append(urls, url)
+		}
+		executors[url] = append(executors[url], reactor)
+	}
+	sort.Strings(urls)
+	return urls, executors
+}
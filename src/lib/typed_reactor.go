@@ -0,0 +1,259 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ---------------------------------------------------------------------
+// A `TypedReactor` is a `Reactor` built from a state and one handler per
+// request and message type, rather than from type switches over `InEvent`.
+//
+//   r := lib.NewTypedReactor(Register{})
+//   lib.OnMessage(r, func(s *Register, at time.Time, from string, msg Write) []lib.OutEvent {
+//           ...
+//   })
+
+// Reactors that implement `ErrorReporter` can fail to handle an event without
+// crashing the executor, the executor records the errors with the execution
+// step they happened in.
+type ErrorReporter interface {
+	// Returns the errors since the last call.
+	DrainErrors() []error
+}
+
+// An error that a reactor reported, as it's recorded with the execution step.
+type StepError struct {
+	// The Go type of the error, e.g. "*lib.UnhandledEventError".
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	// The fields of the error, if it has any that marshal to JSON.
+	Details json.RawMessage `json:"details,omitempty"`
+}
+
+func NewStepError(err error) StepError {
+	e := StepError{
+		Type:    fmt.Sprintf("%T", err),
+		Message: err.Error(),
+	}
+	if bs, err := json.Marshal(err); err == nil && string(bs) != "{}" && string(bs) != "null" {
+		e.Details = bs
+	}
+	return e
+}
+
+// The errors the reactor reported, if it implements `ErrorReporter`.
+func DrainStepErrors(reactor Reactor) []StepError {
+	r, ok := reactor.(ErrorReporter)
+	if !ok {
+		return nil
+	}
+	var errs []StepError
+	for _, err := range r.DrainErrors() {
+		errs = append(errs, NewStepError(err))
+	}
+	return errs
+}
+
+// A request or message which the reactor has no handler for.
+type UnhandledEventError struct {
+	At    time.Time   `json:"at"`
+	From  string      `json:"from"`
+	Kind  string      `json:"kind"` // "request" or "message".
+	Event interface{} `json:"event"`
+}
+
+func (e *UnhandledEventError) Error() string {
+	return fmt.Sprintf("unhandled %s %T from %s at %s: %+v",
+		e.Kind, e.Event, e.From, e.At.Format(time.RFC3339Nano), e.Event)
+}
+
+type requestHandler[S any] func(s *S, at time.Time, from string, id uint64, req Request) ([]OutEvent, bool)
+type messageHandler[S any] func(s *S, at time.Time, from string, msg Message) ([]OutEvent, bool)
+
+type TypedReactor[S any] struct {
+	State S
+
+	requests    []requestHandler[S]
+	requestType map[reflect.Type]bool
+	messages    []messageHandler[S]
+	messageType map[reflect.Type]bool
+	init        func(s *S) []OutEvent
+	tick        func(s *S, at time.Time) []OutEvent
+	timer       func(s *S, at time.Time) []OutEvent
+	timers      map[string]func(s *S, at time.Time, payload Message) []OutEvent
+	io          func(s *S, at time.Time, id uint64, request Message) []OutEvent
+	errors      []error
+}
+
+func NewTypedReactor[S any](state S) *TypedReactor[S] {
+	return &TypedReactor[S]{
+		State:       state,
+		requestType: make(map[reflect.Type]bool),
+		messageType: make(map[reflect.Type]bool),
+		timers:      make(map[string]func(s *S, at time.Time, payload Message) []OutEvent),
+	}
+}
+
+// Handles client requests of type `R`. The handlers are tried in the order
+// they were added, so a handler for an interface type should come after the
+// handlers for the types that implement it. Adding a second handler for the
+// same type is a programming error and panics.
+func OnRequest[S any, R Request](r *TypedReactor[S], h func(s *S, at time.Time, from string, id uint64, req R) []OutEvent) {
+	typ := reflect.TypeOf((*R)(nil)).Elem()
+	if r.requestType[typ] {
+		panic(fmt.Sprintf("OnRequest: duplicate handler for %v", typ))
+	}
+	r.requestType[typ] = true
+	r.requests = append(r.requests, func(s *S, at time.Time, from string, id uint64, req Request) ([]OutEvent, bool) {
+		typed, ok := req.(R)
+		if !ok {
+			return nil, false
+		}
+		return h(s, at, from, id, typed), true
+	})
+}
+
+// Handles internal messages of type `M`, see `OnRequest`.
+func OnMessage[S any, M Message](r *TypedReactor[S], h func(s *S, at time.Time, from string, msg M) []OutEvent) {
+	typ := reflect.TypeOf((*M)(nil)).Elem()
+	if r.messageType[typ] {
+		panic(fmt.Sprintf("OnMessage: duplicate handler for %v", typ))
+	}
+	r.messageType[typ] = true
+	r.messages = append(r.messages, func(s *S, at time.Time, from string, msg Message) ([]OutEvent, bool) {
+		typed, ok := msg.(M)
+		if !ok {
+			return nil, false
+		}
+		return h(s, at, from, typed), true
+	})
+}
+
+func (r *TypedReactor[S]) OnInit(h func(s *S) []OutEvent) {
+	r.init = h
+}
+
+func (r *TypedReactor[S]) OnTick(h func(s *S, at time.Time) []OutEvent) {
+	r.tick = h
+}
+
+// Handles the timers without an id, and the named timers that have no handler
+// of their own.
+func (r *TypedReactor[S]) OnTimer(h func(s *S, at time.Time) []OutEvent) {
+	r.timer = h
+}
+
+// Handles the named timer `id`, the handler gets the timer's payload, which is
+// nil if the timer was set without one. Adding a second handler for the same
+// id panics, like `OnRequest`.
+func (r *TypedReactor[S]) OnNamedTimer(id string, h func(s *S, at time.Time, payload Message) []OutEvent) {
+	if _, ok := r.timers[id]; ok {
+		panic(fmt.Sprintf("OnNamedTimer: duplicate handler for %q", id))
+	}
+	r.timers[id] = h
+}
+
+// Handles the completions of the reactor's I/O requests, see `IORequest`.
+func (r *TypedReactor[S]) OnIOCompletion(h func(s *S, at time.Time, id uint64, request Message) []OutEvent) {
+	r.io = h
+}
+
+func (r *TypedReactor[S]) Receive(at time.Time, from string, event InEvent) []OutEvent {
+	switch ev := event.(type) {
+	case *ClientRequest:
+		return r.receiveRequest(at, from, *ev)
+	case ClientRequest:
+		return r.receiveRequest(at, from, ev)
+	case *InternalMessage:
+		return r.receiveMessage(at, from, *ev)
+	case InternalMessage:
+		return r.receiveMessage(at, from, ev)
+	case *IOCompletion:
+		if r.io != nil {
+			return r.io(&r.State, at, ev.Id, ev.Request)
+		}
+	}
+	return r.unhandled(at, from, "event", event)
+}
+
+func (r *TypedReactor[S]) receiveRequest(at time.Time, from string, ev ClientRequest) []OutEvent {
+	for _, h := range r.requests {
+		if oevs, ok := h(&r.State, at, from, ev.Id, ev.Request); ok {
+			return oevs
+		}
+	}
+	return r.unhandled(at, from, "request", ev.Request)
+}
+
+func (r *TypedReactor[S]) receiveMessage(at time.Time, from string, ev InternalMessage) []OutEvent {
+	for _, h := range r.messages {
+		if oevs, ok := h(&r.State, at, from, ev.Message); ok {
+			return oevs
+		}
+	}
+	return r.unhandled(at, from, "message", ev.Message)
+}
+
+func (r *TypedReactor[S]) unhandled(at time.Time, from string, kind string, event interface{}) []OutEvent {
+	r.errors = append(r.errors, &UnhandledEventError{
+		At:    at,
+		From:  from,
+		Kind:  kind,
+		Event: event,
+	})
+	return nil
+}
+
+func (r *TypedReactor[S]) Tick(at time.Time) []OutEvent {
+	if r.tick == nil {
+		return nil
+	}
+	return r.tick(&r.State, at)
+}
+
+func (r *TypedReactor[S]) Timer(at time.Time) []OutEvent {
+	if r.timer == nil {
+		return nil
+	}
+	return r.timer(&r.State, at)
+}
+
+func (r *TypedReactor[S]) TimerFired(at time.Time, timer Timer) []OutEvent {
+	if h, ok := r.timers[timer.Id]; ok && timer.Id != "" {
+		return h(&r.State, at, timer.Payload)
+	}
+	return r.Timer(at)
+}
+
+func (r *TypedReactor[S]) Init() []OutEvent {
+	if r.init == nil {
+		return nil
+	}
+	return r.init(&r.State)
+}
+
+func (r *TypedReactor[S]) DrainErrors() []error {
+	errs := r.errors
+	r.errors = nil
+	return errs
+}
+
+// The executor records the reactor's state by marshaling the reactor, so we
+// marshal the state as if it was the reactor.
+func (r *TypedReactor[S]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.State)
+}
+
+// The type recorded in the deployment, i.e. the lower-cased name of the state's
+// type.
+func (r *TypedReactor[S]) ReactorType() string {
+	typ := reflect.TypeOf((*S)(nil)).Elem()
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return strings.ToLower(typ.Name())
+}
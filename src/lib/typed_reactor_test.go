@@ -0,0 +1,213 @@
+package lib
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type counter struct {
+	Count int `json:"count"`
+}
+
+type incr struct{}
+
+func (_ incr) RequestEvent() string { return "incr" }
+
+type ping struct{}
+
+func (_ ping) MessageEvent() string { return "ping" }
+
+type pong struct{}
+
+func (_ pong) MessageEvent() string { return "pong" }
+
+func newCounter() *TypedReactor[counter] {
+	r := NewTypedReactor(counter{})
+	OnRequest(r, func(s *counter, _ time.Time, from string, id uint64, _ incr) []OutEvent {
+		s.Count++
+		return []OutEvent{{To: Singleton(from), Args: &ClientResponse{Id: id}}}
+	})
+	OnMessage(r, func(s *counter, _ time.Time, from string, _ ping) []OutEvent {
+		return []OutEvent{{To: Singleton(from), Args: &InternalMessage{pong{}}}}
+	})
+	return r
+}
+
+func TestTypedReactor(t *testing.T) {
+	r := newCounter()
+	at := time.Unix(0, 0).UTC()
+
+	oevs := r.Receive(at, "client:0", &ClientRequest{Id: 1, Request: incr{}})
+	if len(oevs) != 1 || oevs[0].Args.(*ClientResponse).Id != 1 || r.State.Count != 1 {
+		t.Errorf("Unexpected response to request: %+v, state: %+v", oevs, r.State)
+	}
+	oevs = r.Receive(at, "node", &InternalMessage{ping{}})
+	if len(oevs) != 1 || oevs[0].Args.(*InternalMessage).Message != (pong{}) {
+		t.Errorf("Unexpected response to message: %+v", oevs)
+	}
+	if errs := r.DrainErrors(); len(errs) != 0 {
+		t.Errorf("Unexpected errors: %v", errs)
+	}
+
+	if oevs := r.Receive(at, "node", &InternalMessage{pong{}}); oevs != nil {
+		t.Errorf("Expected no events for an unhandled message, got: %+v", oevs)
+	}
+	errs := r.DrainErrors()
+	var unhandled *UnhandledEventError
+	if len(errs) != 1 || !errors.As(errs[0], &unhandled) ||
+		unhandled.Kind != "message" || unhandled.From != "node" {
+		t.Errorf("Expected an unhandled message error, got: %v", errs)
+	}
+	if errs := r.DrainErrors(); len(errs) != 0 {
+		t.Errorf("Expected the errors to be drained, got: %v", errs)
+	}
+}
+
+func TestTypedReactorMarshalsState(t *testing.T) {
+	r := newCounter()
+	r.State.Count = 2
+	bs, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bs) != `{"count":2}` {
+		t.Errorf("Unexpected JSON: %s", bs)
+	}
+	if typ := reactorType(r); typ != "counter" {
+		t.Errorf("Unexpected reactor type: %s", typ)
+	}
+}
+
+func TestTypedReactorDuplicateHandler(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a duplicate handler to panic")
+		}
+	}()
+	r := newCounter()
+	OnMessage(r, func(_ *counter, _ time.Time, _ string, _ ping) []OutEvent { return nil })
+}
+
+func TestTypedReactorNamedTimers(t *testing.T) {
+	r := newCounter()
+	var fired []string
+	OnMessage(r, func(s *counter, _ time.Time, _ string, _ pong) []OutEvent {
+		return []OutEvent{{
+			To:   Singleton("node"),
+			Args: &Timer{Duration: time.Second, Id: "resend", Payload: ping{}},
+		}}
+	})
+	r.OnNamedTimer("resend", func(s *counter, _ time.Time, payload Message) []OutEvent {
+		fired = append(fired, "resend:"+payload.MessageEvent())
+		return []OutEvent{{To: Singleton("node"), Args: &CancelTimer{Id: "resend"}}}
+	})
+	r.OnTimer(func(s *counter, _ time.Time) []OutEvent {
+		fired = append(fired, "anonymous")
+		return nil
+	})
+
+	// The timer's arguments make a round trip through the scheduler.
+	oevs := r.Receive(time.Unix(0, 0).UTC(), "node", &InternalMessage{pong{}})
+	bs, err := json.Marshal(OutEventsToEvents("node", oevs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []struct {
+		Kind string          `json:"kind"`
+		Args json.RawMessage `json:"args"`
+	}
+	if err := json.Unmarshal(bs, &events); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Kind != "timer" ||
+		string(events[0].Args) != `{"id":"resend","payload-event":"ping","payload":{}}` {
+		t.Fatalf("Unexpected timer event: %s", bs)
+	}
+	registry := NewRegistry()
+	MustRegisterType[ping](registry)
+	timer, err := UnmarshalTimer(registry, events[0].Args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := time.Unix(1, 0).UTC()
+	oevs = FireTimer(r, at, timer)
+	bs, err = json.Marshal(OutEventsToEvents("node", oevs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bs) != `[{"kind":"cancel-timer","args":{"id":"resend"},"from":"node"}]` {
+		t.Errorf("Unexpected cancel event: %s", bs)
+	}
+	FireTimer(r, at, Timer{})
+	FireTimer(r, at, Timer{Id: "unknown"})
+	if fmt.Sprint(fired) != "[resend:ping anonymous anonymous]" {
+		t.Errorf("Unexpected timers fired: %v", fired)
+	}
+}
+
+func TestTypedReactorIO(t *testing.T) {
+	r := newCounter()
+	var completed []uint64
+	OnMessage(r, func(s *counter, _ time.Time, _ string, _ pong) []OutEvent {
+		return []OutEvent{{
+			To:   Singleton("node"),
+			Args: &IORequest{Id: 7, Request: ping{}, Latency: time.Millisecond},
+		}}
+	})
+	r.OnIOCompletion(func(s *counter, _ time.Time, id uint64, request Message) []OutEvent {
+		completed = append(completed, id)
+		return nil
+	})
+
+	oevs := r.Receive(time.Unix(0, 0).UTC(), "node", &InternalMessage{pong{}})
+	bs, err := json.Marshal(OutEventsToEvents("node", oevs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `[{"kind":"io","event":"ping","args":{"id":7,"request":{}},"from":"node","to":["node"],"duration-ns":1000000}]`
+	if string(bs) != expected {
+		t.Fatalf("Unexpected I/O event: %s", bs)
+	}
+
+	// The scheduler sends the request back, as a completion, after its latency.
+	registry := NewRegistry()
+	MustRegisterType[ping](registry)
+	var sev ScheduledEvent
+	completion := `{"at":"1970-01-01T00:00:00.001Z","from":"node","to":"node","kind":"io","event":"ping","args":{"id":7,"request":{}}}`
+	if err := UnmarshalScheduledEvent(registry, []byte(completion), &sev); err != nil {
+		t.Fatal(err)
+	}
+	if io, ok := sev.Event.(*IOCompletion); !ok || io.Id != 7 || io.Request != (ping{}) {
+		t.Fatalf("Unexpected completion: %#v", sev.Event)
+	}
+	r.Receive(sev.At, sev.From, sev.Event)
+	if fmt.Sprint(completed) != "[7]" {
+		t.Errorf("Unexpected completions: %v", completed)
+	}
+}
+
+// The errors reactors report keep their structure.
+func TestStepErrors(t *testing.T) {
+	at := time.Unix(1, 0).UTC()
+	got := NewStepError(&UnhandledEventError{
+		At:    at,
+		From:  "client:0",
+		Kind:  "request",
+		Event: counter{1},
+	})
+	if got.Type != "*lib.UnhandledEventError" {
+		t.Errorf("Unexpected type: %s", got.Type)
+	}
+	expected := `{"at":"1970-01-01T00:00:01Z","from":"client:0","kind":"request","event":{"count":1}}`
+	if string(got.Details) != expected {
+		t.Errorf("Unexpected details: %s", got.Details)
+	}
+
+	if got := NewStepError(fmt.Errorf("boom")); got.Message != "boom" || got.Details != nil {
+		t.Errorf("Unexpected error without fields: %+v", got)
+	}
+}
@@ -1,14 +1,12 @@
 package lib
 
 import (
-	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"log"
-	"net/http"
 	"os"
 	"strconv"
 	"time"
@@ -33,38 +31,22 @@ func (testId TestId) MarshalJSON() ([]byte, error) {
 	return []byte(strconv.Itoa(testId.TestId)), nil
 }
 
-const schedulerUrl string = "http://localhost:3000"
-
 type SchedulerRequest struct {
 	Command    string      `json:"command"`
 	Parameters interface{} `json:"parameters"`
 }
 
 func Post(command string, parameters interface{}) []byte {
-	json, err := json.Marshal(SchedulerRequest{
-		Command:    command,
-		Parameters: parameters})
-	if err != nil {
-		log.Panicln(err)
-	}
-	resp, err := http.Post(schedulerUrl, "application/json", bytes.NewBuffer(json))
-	if err != nil {
-		log.Panicln(err)
-	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
+	body, err := DefaultSchedulerClient().Post(context.Background(), command, parameters)
 	if err != nil {
 		log.Panicln(err)
 	}
-	if resp.StatusCode != 200 {
-		log.Panicln(string(body))
-	}
 	return body
 }
 
 func PostParse(command string, parameters interface{}, target interface{}) {
-	body := Post(command, parameters)
-	if err := json.Unmarshal(body, &target); err != nil {
+	err := DefaultSchedulerClient().PostParse(context.Background(), command, parameters, target)
+	if err != nil {
 		log.Panicln(err)
 	}
 }
@@ -93,9 +75,11 @@ func DBPath() string {
 	return path
 }
 
+// Waits for the database to be unlocked rather than failing, since the
+// schedulers and executors of parallel runs write to it at the same time.
 func OpenDB() *sql.DB {
 	path := DBPath()
-	db, err := sql.Open("sqlite3", path)
+	db, err := sql.Open("sqlite3", path+"?_busy_timeout=10000")
 	if err != nil {
 		panic(err)
 	}
@@ -106,6 +90,16 @@ type DeploymentInfo struct {
 	Reactor string          `json:"reactor"`
 	Type    string          `json:"type"`
 	Args    json.RawMessage `json:"args"`
+	// The URL of the executor running the reactor, `DefaultExecutorUrl` is
+	// used if it's empty.
+	Executor string `json:"executor,omitempty"`
+}
+
+func (d DeploymentInfo) ExecutorUrl() string {
+	if d.Executor == "" {
+		return DefaultExecutorUrl
+	}
+	return d.Executor
 }
 
 func DeploymentInfoForTest(testId TestId) ([]DeploymentInfo, error) {
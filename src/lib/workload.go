@@ -0,0 +1,158 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// ---------------------------------------------------------------------
+// A workload describes what the clients of a test do, rather than listing their
+// requests like an `Agenda` does. Every client sends `Requests` requests, one
+// after the other with a think time in between, and picks each request's
+// operation by weight. The agenda is generated from the seed, so the same
+// workload always yields the same agenda.
+//
+// The parameters of the workload, i.e. everything but the operations'
+// generators, are stored with the test by `GenerateTestFromTopologyAndWorkload`
+// and can be read back with `LoadWorkload`.
+
+type Workload struct {
+	Seed    Seed `json:"seed"`
+	Clients int  `json:"clients"`
+	// How many requests every client sends.
+	Requests int `json:"requests"`
+	// The time before each request, nil means no think time. Note that the
+	// scheduler holds back a client's request until the client's previous
+	// request has been responded to.
+	ThinkTime *Latency `json:"think-time,omitempty"`
+	// The reactors the requests are sent to, each request picks one.
+	To         []string    `json:"to"`
+	Operations []Operation `json:"operations"`
+}
+
+type Operation struct {
+	Name   string  `json:"name"`
+	Weight float64 `json:"weight"`
+	// Generates the request, drawing its arguments from `r`.
+	Generate func(r *rand.Rand) Request `json:"-"`
+}
+
+// The agenda of the workload, ordered by time. Every client draws from a
+// generator of its own, so adding clients doesn't change what the other
+// clients do.
+func (w Workload) Agenda() (Agenda, error) {
+	if err := w.validateParameters(); err != nil {
+		return nil, err
+	}
+	if len(w.To) == 0 || len(w.Operations) == 0 {
+		return nil, fmt.Errorf("Workload: needs reactors to send to and operations")
+	}
+	var total float64
+	for _, op := range w.Operations {
+		if op.Generate == nil || op.Weight < 0 {
+			return nil, fmt.Errorf("Workload: operation %q needs a generator and a weight", op.Name)
+		}
+		total += op.Weight
+	}
+	if total <= 0 {
+		return nil, fmt.Errorf("Workload: the operations' weights add up to zero")
+	}
+
+	agenda := make(Agenda, 0, w.Clients*w.Requests)
+	for client := 0; client < w.Clients; client++ {
+		from := fmt.Sprintf("client:%d", client)
+		r := rand.New(rand.NewSource(reactorSeed(w.Seed, from, 0)))
+		at := time.Unix(0, 0).UTC()
+		for i := 0; i < w.Requests; i++ {
+			if w.ThinkTime != nil {
+				at = at.Add(w.ThinkTime.Sample(r.Float64))
+			}
+			op := w.Operations[len(w.Operations)-1]
+			pick := r.Float64() * total
+			for _, candidate := range w.Operations {
+				if pick < candidate.Weight {
+					op = candidate
+					break
+				}
+				pick -= candidate.Weight
+			}
+			agenda = append(agenda, ScheduledEvent{
+				At:   at,
+				From: from,
+				To:   w.To[r.Intn(len(w.To))],
+				Event: ClientRequest{
+					Id:      uint64(client),
+					Request: op.Generate(r),
+				},
+			})
+		}
+	}
+	sort.SliceStable(agenda, func(i, j int) bool {
+		return agenda[i].At.Before(agenda[j].At)
+	})
+	return agenda, nil
+}
+
+// Checks the parameters that are stored with the test, see `LoadWorkload`.
+func (w Workload) validateParameters() error {
+	if w.Clients < 0 || w.Requests < 0 {
+		return fmt.Errorf("Workload: the numbers of clients and requests can't be negative, got %d and %d",
+			w.Clients, w.Requests)
+	}
+	if w.ThinkTime != nil {
+		if err := w.ThinkTime.Validate(); err != nil {
+			return fmt.Errorf("Workload: the think time: %v", err)
+		}
+	}
+	return nil
+}
+
+// Generates the workload's agenda and stores it, along with the workload's
+// parameters, as a new test.
+func GenerateTestFromTopologyAndWorkload(topology Topology, w Workload) (TestId, error) {
+	agenda, err := w.Agenda()
+	if err != nil {
+		return TestId{}, err
+	}
+	return generateTest(topology, agenda, w), nil
+}
+
+// Reads the parameters of the workload that the test was generated from into
+// `w`. The generators of `w`'s operations are kept, matched by name, so that
+// `w.Agenda()` regenerates the test's agenda.
+func LoadWorkload(testId TestId, w *Workload) error {
+	db := OpenDB()
+	defer db.Close()
+
+	var blob []byte
+	if err := db.QueryRow(`SELECT generator FROM test_info WHERE test_id = ?`,
+		testId.TestId).Scan(&blob); err != nil {
+		return err
+	}
+	if blob == nil {
+		return fmt.Errorf("Test %d wasn't generated from a workload", testId.TestId)
+	}
+	generators := make(map[string]func(*rand.Rand) Request, len(w.Operations))
+	for _, op := range w.Operations {
+		generators[op.Name] = op.Generate
+	}
+	var loaded Workload
+	if err := json.Unmarshal(blob, &loaded); err != nil {
+		return err
+	}
+	if err := loaded.validateParameters(); err != nil {
+		return err
+	}
+	for i, op := range loaded.Operations {
+		generate, ok := generators[op.Name]
+		if !ok {
+			return fmt.Errorf("No generator for operation %q", op.Name)
+		}
+		loaded.Operations[i].Generate = generate
+	}
+	*w = loaded
+	return nil
+}
@@ -0,0 +1,137 @@
+package lib
+
+import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type writeRequest struct {
+	Value int `json:"value"`
+}
+
+func (_ writeRequest) RequestEvent() string { return "write" }
+
+type readRequest struct{}
+
+func (_ readRequest) RequestEvent() string { return "read" }
+
+func TestWorkloadAgenda(t *testing.T) {
+	w := Workload{
+		Seed:      1,
+		Clients:   3,
+		Requests:  100,
+		ThinkTime: Exponential(10 * time.Millisecond),
+		To:        []string{"a", "b"},
+		Operations: []Operation{
+			{Name: "write", Weight: 1, Generate: func(r *rand.Rand) Request {
+				return writeRequest{Value: r.Intn(10)}
+			}},
+			{Name: "read", Weight: 3, Generate: func(*rand.Rand) Request {
+				return readRequest{}
+			}},
+		},
+	}
+	agenda, err := w.Agenda()
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := w.Agenda()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(agenda) != 300 || !reflect.DeepEqual(agenda, again) {
+		t.Fatalf("Expected the same 300 requests from the same seed, got %d and %d",
+			len(agenda), len(again))
+	}
+
+	reads := 0
+	for i, sev := range agenda {
+		if i > 0 && sev.At.Before(agenda[i-1].At) {
+			t.Fatalf("Expected the agenda to be ordered by time, got %v before %v",
+				agenda[i-1].At, sev.At)
+		}
+		if sev.Event.(ClientRequest).Request.RequestEvent() == "read" {
+			reads++
+		}
+	}
+	if reads < 180 || reads > 270 {
+		t.Errorf("Expected about 225 of the 300 requests to be reads, got %d", reads)
+	}
+
+	client := func(agenda Agenda, from string) Agenda {
+		var requests Agenda
+		for _, sev := range agenda {
+			if sev.From == from {
+				requests = append(requests, sev)
+			}
+		}
+		return requests
+	}
+	w.Clients = 4
+	more, err := w.Agenda()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(client(agenda, "client:1"), client(more, "client:1")) {
+		t.Errorf("Expected another client not to change what client:1 does")
+	}
+
+	w.Seed = 2
+	other, err := w.Agenda()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(client(more, "client:1"), client(other, "client:1")) {
+		t.Errorf("Expected another seed to change what client:1 does")
+	}
+
+	w.Requests = -1
+	if _, err := w.Agenda(); err == nil {
+		t.Errorf("Expected an error for a negative number of requests")
+	}
+
+	w.Requests = 100
+	w.ThinkTime = &Latency{Kind: "normal", Mean: 10 * time.Millisecond}
+	if _, err := w.Agenda(); err == nil {
+		t.Errorf("Expected an error for a think time that can't be sampled")
+	}
+
+	w.ThinkTime = nil
+	w.To = nil
+	if _, err := w.Agenda(); err == nil {
+		t.Errorf("Expected an error without reactors to send to")
+	}
+}
+
+func TestWorkloadJSON(t *testing.T) {
+	w := Workload{
+		Seed:      3,
+		Clients:   2,
+		Requests:  5,
+		ThinkTime: Uniform(time.Millisecond, 5*time.Millisecond),
+		To:        []string{"a"},
+		Operations: []Operation{{Name: "read", Weight: 1, Generate: func(*rand.Rand) Request {
+			return readRequest{}
+		}}},
+	}
+	blob, err := json.Marshal(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded Workload
+	if err := json.Unmarshal(blob, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	loaded.Operations[0].Generate = w.Operations[0].Generate
+	want, _ := w.Agenda()
+	got, err := loaded.Agenda()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected the stored workload to regenerate the agenda")
+	}
+}
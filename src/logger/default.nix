@@ -2,7 +2,7 @@
 , pkgs ? import sources.nixpkgs {} }:
 with pkgs;
 
-assert lib.versionAtLeast go.version "1.15";
+assert lib.versionAtLeast go.version "1.18";
 
 let
   inherit (import sources.gitignore {}) gitignoreSource;
@@ -223,30 +223,17 @@ func OpenDB() *sql.DB {
 	return db
 }
 
-type Marshaler struct{}
-
-func NewMarshaler() *Marshaler {
-	return &Marshaler{}
-}
-
-func (_ *Marshaler) UnmarshalMessage(message string, raw json.RawMessage, msg *lib.Message) error {
-	switch strings.ToLower(message) {
-	case "log":
-		*msg = Log{
-			Entry: raw,
-		}
-	case "index":
-		*msg = Index{
-			Component: strings.TrimSpace(string(raw)),
-		}
-	default:
-		panic(fmt.Errorf("Unknown message type: %s\n%s", message, raw))
-	}
-	return nil
-}
-
-func (_ *Marshaler) UnmarshalRequest(request string, raw json.RawMessage, req *lib.Request) error {
-	return nil
+// The logger's messages aren't JSON: a log entry is kept as it is and an index
+// request is just the component's name.
+func NewMarshaler() *lib.Registry {
+	r := lib.NewRegistry()
+	lib.MustRegisterMessage(r, "log", func(raw json.RawMessage) (lib.Message, error) {
+		return Log{Entry: raw}, nil
+	})
+	lib.MustRegisterMessage(r, "index", func(raw json.RawMessage) (lib.Message, error) {
+		return Index{Component: strings.TrimSpace(string(raw))}, nil
+	})
+	return r
 }
 
 func DeployReadOnlyPipe(pipeName string, reactor lib.Reactor, m lib.Marshaler) {
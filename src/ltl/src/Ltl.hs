@@ -67,6 +67,19 @@ check' state formula ts = case formula of
     allDec (check' state f) (futures ts) (RAlways . worldTime . NE.head) PAlways
   Eventually f ->
     anyDec (check' state f) (futures ts) (PEventually . worldTime . NE.head) REventually
+  Next f -> case ts of
+    _ :| [] -> No (RNext Nothing)
+    _ :| (t : ts') -> case check' state f (t :| ts') of
+      Yes p -> Yes (PNext p)
+      No r -> No (RNext (Just r))
+  Until f g -> untilDec (futures ts)
+    where
+      untilDec [] = No RUntilNever
+      untilDec (t : rest) = case check' state g t of
+        Yes p -> Yes (PUntil (worldTime (NE.head t)) p)
+        No _ -> case check' state f t of
+          Yes _ -> untilDec rest
+          No r -> No (RUntil (worldTime (NE.head t)) r)
   ForallNode b f ->
     allDec (\n -> check' (updateNenv b n state) f ts) (nodes state) RForallNode PForallNode
   ExistsNode b f ->
@@ -16,6 +16,8 @@ data Proof
   = PP PredicateProof
   | PAlways -- we intentionally don't have proof for all the subterms
   | PEventually Int Proof -- the world it was true in, and proof it was true
+  | PNext Proof -- proof it was true in the next world
+  | PUntil Int Proof -- the world the right-hand side was true in, and proof it was true
   | PForallNode -- intentionally empty
   | PExistsNode Node Proof -- node, and proof this is true for this node
   | PForallInt -- intentionally empty
@@ -31,6 +33,9 @@ data Refutation
   = RP PredicateRefutation
   | RAlways Int Refutation -- R ([] p) =  w * R p @ w
   | REventually -- R (<> p) = {}
+  | RNext (Maybe Refutation) -- R (next p) = 1 + R p @ next, there might not be a next world
+  | RUntil Int Refutation -- R (p until q) = w * R p @ w, before q was true
+  | RUntilNever -- R (p until q) = {}, q was never true
   | RForallNode Node Refutation -- R (\forall n. p) = N * R (p[n:=N])
   | RExistsNode -- R (\exists n. p) = {}
   | RForallInt Integer Refutation -- R (\forall x. p) = i * R (p [x:=i])
@@ -58,6 +58,8 @@ data Formula
   = P Predicate
   | Always Formula
   | Eventually Formula
+  | Next Formula
+  | Until Formula Formula
   | ForallNode NodeVar Formula
   | ExistsNode NodeVar Formula
   | ForallInt [Integer] IntVar Formula
@@ -99,6 +101,8 @@ concreteNodes nodes form = case form of
   ForallNode n f -> ForallNode n (concreteNodes (nodes \\ [n]) f)
   Eventually f -> Eventually (go f)
   Always f -> Always (go f)
+  Next f -> Next (go f)
+  Until l r -> Until (go l) (go r)
   P p -> P $ concreteP nodes p
   where
     go = concreteNodes nodes
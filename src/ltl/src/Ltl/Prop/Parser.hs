@@ -91,6 +91,7 @@ operatorTable =
   [ [prefix ["!", "~"] Neg],
     [ binary ["&&", "/\\"] And,
       binary ["||", "\\/"] Or,
+      binary ["until"] Until,
       PE.Postfix (flip Imp <$ PL.symbol space "->" <*> parser)
     ]
   ]
@@ -108,6 +109,7 @@ parser =
       ForallNode <$ PL.symbol space "forall" <*> PL.lexeme space stringVar <* PL.symbol space "." <*> parser,
       Always <$ P.choice [PL.symbol space name | name <- ["always", "[]"]] <*> parser,
       Eventually <$ P.choice [PL.symbol space name | name <- ["atSomePoint", "eventually", "<>"]] <*> parser,
+      Next <$ PL.symbol space "next" <*> parser,
       PE.makeExprParser pTerm operatorTable
     ]
 
@@ -63,6 +63,14 @@ pp f = maybeParens $ case f of
     box <- QC.elements ["eventually", "<>"]
     fr <- pp f
     pure $ box <> " " <> fr
+  Next f -> do
+    fr <- pp f
+    pure $ "next " <> fr
+  Until l r -> do
+    lr <- pp l
+    rr <- pp r
+    -- Would be good to not always have the parens
+    pure $ "(" <> lr <> ")" <> " until " <> "(" <> rr <> ")"
   ForallInt is i f -> do
     fr <- pp f
     pure $ "forall " <> Text.pack i <> " in " <> Text.pack (show is) <> "." <> fr
@@ -177,8 +185,8 @@ genF = QC.sized $ go
     go n =
       QC.oneof
         [ go 0,
-          QC.elements [Always, Eventually, Neg] <*> go (n `div` 2),
-          QC.elements [Imp, And, Or] <*> go (n `div` 2) <*> go (n `div` 2),
+          QC.elements [Always, Eventually, Next, Neg] <*> go (n `div` 2),
+          QC.elements [Imp, And, Or, Until] <*> go (n `div` 2) <*> go (n `div` 2),
           QC.elements [ForallNode, ExistsNode] <*> stringVar <*> go (n `div` 2),
           QC.elements [ForallInt, ExistsInt] <*> QC.arbitrary <*> stringVar <*> go (n `div` 2)
         ]
@@ -192,6 +200,11 @@ prop_can_parse =
           Left _ -> False
           Right f' -> f == f'
 
+unit_next_scope :: Assertion
+unit_next_scope = case parse "next TT until FF" of
+  Left l -> assertFailure l
+  Right f -> f @?= Next (Until TT FF)
+
 unit_imp_right_assoc :: Assertion
 unit_imp_right_assoc = case parse "TT -> FF -> TT" of
   Left l -> assertFailure l
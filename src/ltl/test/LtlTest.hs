@@ -0,0 +1,40 @@
+{-# LANGUAGE OverloadedStrings #-}
+
+module LtlTest where
+
+import qualified Data.Aeson as Aeson
+import Ltl
+import Ltl.Json
+import Ltl.Proof
+import Ltl.Prop
+import Test.HUnit
+
+-- Whether NodeB's state is `b` after the step, which it is in the first two
+-- worlds of `exampleTrace` but not in the last.
+bstate :: Bool -> Formula
+bstate b =
+  P (Eq (Variable (Var After (ConcreteNode "NodeB") (Lookup This "bstate"))) (Constant (Aeson.Bool b)))
+
+unit_next :: Assertion
+unit_next = check (Next (bstate True)) exampleTrace @?= Yes (PNext (PP (PEq (Aeson.Bool True))))
+
+unit_next_refuted :: Assertion
+unit_next_refuted =
+  check (Next (Next (bstate True))) exampleTrace
+    @?= No (RNext (Just (RNext (Just (RP (REq (Aeson.Bool False) (Aeson.Bool True)))))))
+
+unit_next_last_world :: Assertion
+unit_next_last_world =
+  check (Next (Next (Next TT))) exampleTrace
+    @?= No (RNext (Just (RNext (Just (RNext Nothing)))))
+
+unit_until :: Assertion
+unit_until =
+  check (Until (bstate True) (bstate False)) exampleTrace
+    @?= Yes (PUntil 2 (PP (PEq (Aeson.Bool False))))
+
+unit_until_refuted :: Assertion
+unit_until_refuted = check (Until FF (bstate False)) exampleTrace @?= No (RUntil 0 RFF)
+
+unit_until_never :: Assertion
+unit_until_never = check (Until TT FF) exampleTrace @?= No RUntilNever
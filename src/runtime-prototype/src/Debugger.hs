@@ -98,12 +98,15 @@ display (LogSend (LocalRef i) (RemoteRef a j) msg)
   = show i ++ " --> " ++ show j ++ " @" ++ a
 display (LogResumeContinuation (RemoteRef a i) (LocalRef j) msg)
   = show j ++ " <-- " ++ show i ++ " @" ++ a
+display (LogStepInfo (LocalRef i) (RemoteRef a _j) _msg)
+  = show i ++ " step @" ++ a
 
 displaySelectedMessage :: AppState -> String
 displaySelectedMessage as = case L.listSelectedElement (asLog as) of
   Nothing -> "?"
   Just (_ix, Timestamped (LogSend _from _to msg) _lt _pt) -> show msg
   Just (_ix, Timestamped (LogResumeContinuation _from _to msg) _lt _pt) -> show msg
+  Just (_ix, Timestamped (LogStepInfo _me _sched msg) _lt _pt) -> show msg
 
 customAttr :: AttrName
 customAttr = L.listSelectedAttr <> "custom"
@@ -75,6 +75,12 @@ data LogEntry' msg
     , leLocalRef :: LocalRef
     , leMessage :: msg
     }
+  -- What the reactors of the Go event loop executor did during a step.
+  | LogStepInfo
+    { leLocalRef :: LocalRef
+    , leRemoteRef :: RemoteRef
+    , leMessage :: msg
+    }
   deriving stock (Show, Read, Generic)
 type LogEntry = LogEntry' Message
 
@@ -87,6 +93,7 @@ instance Foldable LogEntry' where
 instance Traversable LogEntry' where
   traverse f (LogSend a b c) = (\x -> LogSend a b x) <$> f c
   traverse f (LogResumeContinuation a b c) = (\x -> LogResumeContinuation a b x) <$> f c
+  traverse f (LogStepInfo a b c) = (\x -> LogStepInfo a b x) <$> f c
 
 leAesonOptions = defaultOptions
   { fieldLabelModifier = \s -> case drop (length ("le" :: String)) s of
@@ -139,7 +146,8 @@ getLog (Codec enc _) (Log es) =
       ( (pair "content" $ pairs
           ((pair "tag" (case x of
             LogSend {} -> text "LogSend"
-            LogResumeContinuation{} -> text "LogResumeContinuation")) <>
+            LogResumeContinuation{} -> text "LogResumeContinuation"
+            LogStepInfo{} -> text "LogStepInfo")) <>
           "localRef" .= leLocalRef x <>
           "remoteRef" .= leRemoteRef x <>
           (pair "message" (unsafeToEncoding (fromLazyByteString (enc (leMessage x)))))
@@ -35,12 +35,35 @@
        json/read
        (mapv #(update % :at time/instant))))
 
-(defn next-run-id!
+(def default-executor-url
+  "The executor of the reactors that don't name one, same as
+  `lib.DefaultExecutorUrl`."
+  "http://localhost:3001/api/v1/")
+
+(defn load-total-executors!
+  "The number of executors that run the test's reactors, reactors that don't
+  name an executor run on the default one."
   [test-id]
-  (jdbc/execute-one!
-   ds
-   ["SELECT IFNULL(MAX(run_id), -1) + 1 as `run-id` FROM run_info WHERE test_id = ?" test-id]
-   {:return-keys true :builder-fn rs/as-unqualified-lower-maps}))
+  (let [deployment (some-> (jdbc/execute!
+                            ds
+                            ["SELECT deployment FROM test_info WHERE test_id = ?" test-id]
+                            {:builder-fn rs/as-unqualified-lower-maps})
+                           first
+                           :deployment
+                           json/read)]
+    (->> deployment
+         (map #(or (not-empty (:executor %)) default-executor-url))
+         distinct
+         count
+         (max 1))))
+
+(defn- next-run-id
+  [connectable test-id]
+  (:run-id
+   (jdbc/execute-one!
+    connectable
+    ["SELECT IFNULL(MAX(run_id), -1) + 1 as `run-id` FROM run_info WHERE test_id = ?" test-id]
+    {:return-keys true :builder-fn rs/as-unqualified-lower-maps})))
 
 (comment
   (setup-db "/tmp/test.sqlite3")
@@ -54,21 +77,36 @@
   (append-history! 1 :invoke "a" "{\"id\": 1}" 0))
 
 (defn append-event!
-  [test-id run-id event data]
+  ([test-id run-id event data]
+   (append-event! ds test-id run-id event data))
+  ([connectable test-id run-id event data]
    (jdbc/execute-one!
-    ds
+    connectable
     ["INSERT INTO event_log (event, meta, data) VALUES (?,?,?)"
      event
      (json/write {:component "scheduler"
                   :test-id test-id
                   :run-id run-id})
      (json/write data)]
-    {:return-keys true :builder-fn rs/as-unqualified-lower-maps}))
+    {:return-keys true :builder-fn rs/as-unqualified-lower-maps})))
 
 (defn append-network-trace!
   [test-id run-id data]
   (append-event! test-id run-id "NetworkTrace" data))
 
-(defn append-create-run-event!
-  [test-id run-id data]
-  (append-event! test-id run-id "CreateRun" data))
+(defn create-run-event!
+  "Picks the next run id of the test and claims it by writing the run's
+  `CreateRun` event. Both happen in one transaction, which takes the database's
+  write lock up front, so that other schedulers, also the Go one and those in
+  other processes, can't pick the same run id."
+  [test-id data]
+  (with-open [conn (jdbc/get-connection ds)]
+    (jdbc/execute! conn ["BEGIN IMMEDIATE"])
+    (try
+      (let [run-id (next-run-id conn test-id)]
+        (append-event! conn test-id run-id "CreateRun" data)
+        (jdbc/execute! conn ["COMMIT"])
+        {:run-id run-id})
+      (catch Exception e
+        (jdbc/execute! conn ["ROLLBACK"])
+        (throw e)))))
@@ -74,6 +74,10 @@
    :agenda              (agenda/empty-agenda)
    :seed                1
    :faults              []
+   :applied-faults      #{}
+   :clocks              {}
+   :network             nil
+   :links               {}
    :clock               (time/init-clock)
    :next-tick           (time/init-clock)
    :tick-frequency      50.0
@@ -92,14 +96,13 @@
 (s/def ::test-id nat-int?)
 (s/def ::queue-size nat-int?)
 
-;; TODO(stevan): test needs to contain executor topology, so we know how many
-;; executors to wait for.
 (>defn load-test!
   [data {:keys [test-id]}]
   [::data (s/keys :req-un [::test-id])
    => (s/tuple ::data (s/keys :req-un [::queue-size]))]
   (-> data
       (update :agenda #(agenda/enqueue-many % (db/load-test! test-id)))
+      (assoc :total-executors (db/load-total-executors! test-id))
       (update :state (fn [state]
                        (case state
                          :started :test-prepared
@@ -131,17 +134,143 @@
                      (<= (:at %) (:at entry))))
        not-empty?))
 
+(defn partitioned?
+  "Reactors that aren't in any of the groups, e.g. clients, are not affected by
+  the partition."
+  [groups from to]
+  (let [group-of (fn [reactor]
+                   (first (keep-indexed (fn [i group]
+                                          (when (some #{reactor} group) i))
+                                        groups)))
+        i (group-of from)
+        j (group-of to)]
+    (and (some? i) (some? j) (not= i j))))
+
+(defn partition-active?
+  [fault logical-clock]
+  (and (= (:kind fault) "partition")
+       (<= (:at fault) logical-clock)
+       (or (zero? (get fault :until 0))
+           (< logical-clock (:until fault)))))
+
 (defn should-drop?
+  "I/O completions don't travel over the network, so only crashes drop them."
   [data entry]
   (let [faults (:faults data)
+        io? (= (:kind entry) "io")
         entry' (-> entry
                    (select-keys [:to :from])
                    (assoc :kind "omission"
                           :at (:logical-clock data)))]
-    (or (some? ((set faults) entry'))
+    (or (and (not io?) (some? ((set faults) entry')))
+        (and (not io?)
+             (some #(and (partition-active? % (:logical-clock data))
+                         (partitioned? (:groups %) (:from entry) (:to entry)))
+                   faults))
         (component-crashed? data entry')
         )))
 
+(defn link-fault
+  "The index of the fault of the given `kind` that applies to the entry, if it
+  would be received at logical time `at`. Delays, reorderings and duplicates
+  only apply once, and not to I/O completions."
+  [data kind entry at]
+  (first (keep-indexed (fn [i fault]
+                         (when (and (not= (:kind entry) "io")
+                                    (= (:kind fault) kind)
+                                    (= (:from fault) (:from entry))
+                                    (= (:to fault) (:to entry))
+                                    (= (:at fault) at)
+                                    (not (contains? (:applied-faults data) i)))
+                           i))
+                       (:faults data))))
+
+(defn paused-until
+  "When the reactor resumes, if it's paused at the given time."
+  [data reactor at]
+  (some (fn [fault]
+          (when (and (= (:kind fault) "pause")
+                     (= (:from fault) reactor))
+            (let [from (time/plus-nanos (time/init-clock) (double (get fault :at-ns 0)))
+                  until (time/plus-nanos (time/init-clock) (double (get fault :until-ns 0)))]
+              (when (and (not (time/before? at from))
+                         (time/before? at until))
+                until))))
+        (:faults data)))
+
+(defn clock-skew
+  "How much the clock skew fault has adjusted the clock, `elapsed` nanoseconds
+  since the start of the run. Skews with an `until-ns` slew the clock
+  gradually, the others make it jump."
+  [fault elapsed]
+  (let [at (get fault :at-ns 0)
+        by (get fault :by-ns 0)
+        until (get fault :until-ns 0)]
+    (cond
+      (< elapsed at) 0
+      (or (<= until at) (<= until elapsed)) by
+      :else (* by (/ (double (- elapsed at)) (- until at))))))
+
+(defn local-time
+  "The reactor's local time at the scheduler's time `at`. Reactors without a
+  clock of their own see the scheduler's time."
+  [data reactor ^java.time.Instant at]
+  (let [clock (get (:clocks data) (keyword reactor) {})
+        elapsed (+ (* (.getEpochSecond at) 1000000000) (.getNano at))
+        skews (->> (:faults data)
+                   (filter #(and (= (:kind %) "clock-skew")
+                                 (= (:from %) reactor)))
+                   (map #(clock-skew % elapsed))
+                   (reduce + 0))]
+    (time/plus-nanos at (double (+ (get clock :offset-ns 0)
+                                   (* (double (get clock :drift 0)) elapsed)
+                                   skews)))))
+
+(defn global-duration
+  "How many nanoseconds a duration on the reactor's clock, e.g. of a timer,
+  takes on the scheduler's clock."
+  [data reactor ns]
+  (/ (double ns) (+ 1.0 (double (get-in data [:clocks (keyword reactor) :drift] 0)))))
+
+(def executor-faults
+  #{"restart" "disk-full" "torn-write" "corrupt-read" "disk-latency"})
+
+(defn fault-entries
+  "Restarts and disk faults are delivered to the executors like the other
+  events, so that they show up in the network trace. Their arguments are the
+  fault itself."
+  [faults]
+  (->> faults
+       (filter #(contains? executor-faults (:kind %)))
+       (mapv (fn [fault]
+               {:kind "fault"
+                :event (:kind fault)
+                :args fault
+                :from (:from fault)
+                :to (:from fault)
+                :at (time/plus-nanos (time/init-clock) (double (get fault :at-ns 0)))}))))
+
+(defn reorder
+  "Swaps the entry with the next entry on the same link, if a reorder fault
+  applies to it. The entries swap their delivery times too."
+  [data agenda entry]
+  (let [i (link-fault data "reorder" entry (inc (:logical-clock data)))
+        swapped (when i
+                  (first (filter #(and (= (:from %) (:from entry))
+                                       (= (:to %) (:to entry))
+                                       (not= (:kind %) "timer")
+                                       (not= (:kind %) "io"))
+                                 agenda)))]
+    (if (nil? swapped)
+      [data agenda entry]
+      [(update data :applied-faults conj i)
+       (agenda/enqueue-many (agenda/empty-agenda)
+                            (map #(if (identical? % swapped)
+                                    (assoc entry :at (:at swapped))
+                                    %)
+                                 agenda))
+       (assoc swapped :at (:at entry))])))
+
 (comment
   (should-drop? {:faults [{:from "a", :to "b", :kind "omission", :at 0}]
                  :logical-clock 0}
@@ -194,16 +323,30 @@
   (if-not (contains? #{:ready :requesting} (:state data))
     [(assoc data :state :error-cannot-execute-in-this-state) nil]
     (let [[agenda' entry] (agenda/dequeue (:agenda data))
+          ;; Entries for a paused reactor queue up until it resumes.
+          resume (paused-until data (:to entry) (:at entry))
+          [data agenda' entry] (if resume
+                                 [data agenda' entry]
+                                 (reorder data agenda' entry))
+          delay-fault (when-not resume
+                        (link-fault data "delay" entry (inc (:logical-clock data))))
           entry-from-client-with-current-request (some #(= (-> % :from)
                                                            (-> entry :from))
                                                        (:client-requests data))
+          held-back? (or (some? resume) (some? delay-fault) entry-from-client-with-current-request)
           data' (-> data
                     (assoc :agenda agenda'
                            :clock (:at entry))
-                    (assoc :agenda (if entry-from-client-with-current-request
+                    (assoc :agenda (cond
+                                     resume
+                                     (agenda/enqueue agenda' (assoc entry :at resume))
+                                     delay-fault
+                                     (agenda/enqueue agenda' (update entry :at #(time/plus-nanos % (double (:by-ns (nth (:faults data) delay-fault))))))
+                                     entry-from-client-with-current-request
                                      (agenda/enqueue agenda' (update entry :at #(time/plus-millis % (:client-delay-ms data))))
-                                     agenda'))
-                    (update :logical-clock (if entry-from-client-with-current-request identity inc))
+                                     :else agenda'))
+                    (cond-> delay-fault (update :applied-faults conj delay-fault))
+                    (update :logical-clock (if held-back? identity inc))
                     (update :state (fn [state]
                                      (case state
                                        :ready :responding
@@ -213,13 +356,16 @@
           ;; this should be checked when commands are enqueued?
           meta {:test-id (:test-id data')
                 :run-id (:run-id data')
-                :logical-time (:logical-clock data')}
+                :logical-time (:logical-clock data')
+                :seed (:run-seed data')
+                :simulated-time (:clock data')}
           executor-id (get (:topology data') (:to entry))]
       (assert executor-id (str "Target `" (:to entry) "' isn't in topology."))
       [data' {:url executor-id
               :timestamp (:at entry)
               :body (assoc entry :meta meta)
               :drop? (cond
+                       (or resume delay-fault) :delay
                        (should-drop? data' entry) :drop
                        entry-from-client-with-current-request :delay
                        :else :keep)}])))
@@ -256,7 +402,7 @@
                                     ::args
                                     ::to
                                     ::from])
-                   #(not (#{"timer"} (:kind %)))))
+                   #(not (#{"timer" "cancel-timer"} (:kind %)))))
 
 (def entries? (s/coll-of entry? :kind vector?))
 
@@ -270,7 +416,7 @@
                           ::args
                           :ext/to
                           ::from])
-         #(not (#{"timer"} (:kind %)))))
+         #(not (#{"timer" "cancel-timer"} (:kind %)))))
 
 (def timer?
   (s/and (s/keys :req-un [::kind
@@ -279,13 +425,21 @@
                           ::duration-ns])
          #(= (:kind %) "timer")))
 
+(def cancel-timer?
+  (s/and (s/keys :req-un [::kind
+                          ::args
+                          ::from])
+         #(= (:kind %) "cancel-timer")))
+
 (def event?
   (s/or :entry entry?
-        :timer timer?))
+        :timer timer?
+        :cancel-timer cancel-timer?))
 
 (def ext-event?
   (s/or :entry ext-entry?
-        :timer timer?))
+        :timer timer?
+        :cancel-timer cancel-timer?))
 
 (def events? (s/coll-of event? :kind vector?))
 
@@ -295,6 +449,7 @@
        (let [up (fn [event]
                   (case (:kind event)
                     "timer" [event]
+                    "cancel-timer" [event]
                     (if (string? (:to event))
                       [event]
                       (mapv #(assoc event :to %) (:to event)))))]
@@ -379,6 +534,7 @@
                                  :sent-logical-time sent-logical-time
                                  :recv-logical-time (:logical-clock data')
                                  :recv-simulated-time (:clock data')
+                                 :recv-local-time (local-time data' (:to body) (:clock data'))
                                  :dropped dropped?}
                           is-from-client? (merge  {:jepsen-type :invoke
                                                    :jepsen-process (-> body
@@ -393,8 +549,19 @@
                   [data' {:events []}])
                 (let ;; TODO(stevan): Retry on failure, this possibly needs changes to executor
                     ;; so that we don't end up executing the same command twice.
-                    [events (-> (client/post (str url (if (= (:kind body) "timer") "timer" "event"))
-                                             {:body (json/write body) :content-type "application/json; charset=utf-8"})
+                    [;; The duplicate is delivered after the entries that are
+                     ;; already due at the same time.
+                     data' (if-let [i (link-fault data' "duplicate" body (:logical-clock data'))]
+                             (-> data'
+                                 (update :agenda #(agenda/enqueue % (dissoc body :meta)))
+                                 (update :applied-faults conj i))
+                             data')
+                     events (-> (client/post (str url (case (:kind body)
+                                                        "timer" "timer"
+                                                        "fault" "fault"
+                                                        "event"))
+                                             {:body (json/write (update body :at #(local-time data' (:to body) %)))
+                                              :content-type "application/json; charset=utf-8"})
                                 :body
                                 json/read
                                 (update :events expand-events))]
@@ -411,6 +578,8 @@
                                            (:events events))
                         internal (mapv #(assoc % :sent-logical-time (:logical-clock data')) internal)
                         data'' (cond-> data'
+                                 ;; An invariant of the executor was violated.
+                                 (:stop events) (assoc :stopped true)
                                  is-from-client? (add-client-request body)
                                  (not (empty? client-responses)) (update :logical-clock inc)
                                  true (remove-client-requests (map :to client-responses)))]
@@ -426,6 +595,7 @@
                                                  :sent-logical-time (:logical-clock data')
                                                  :recv-logical-time (:logical-clock data'')
                                                  :recv-simulated-time (:clock data'')
+                                                 :recv-local-time (:clock data'')
                                                  :dropped false
                                                  :jepsen-type :ok
                                                  :jepsen-process (-> client-response :to parse-client-id)}))
@@ -451,6 +621,64 @@
         first
         (execute!))))
 
+;; See `lib.NetworkModel`, the latencies are drawn the same way so that both
+;; schedulers yield the same run from the same seed.
+
+(defn sample-latency
+  "Draws a latency, in nanoseconds, from the latency distribution."
+  [latency]
+  (let [exponential (fn [mean] (* (- (double mean)) (Math/log (- 1.0 (gen/double)))))]
+    (case (:kind latency)
+      "uniform" (let [min (get latency :min-ns 0)
+                      max (get latency :max-ns 0)]
+                  (+ min (* (gen/double) (- max min))))
+      "exponential" (exponential (get latency :mean-ns 0))
+      "bimodal" (if (< (gen/double) (get latency :slow-fraction 0))
+                  (exponential (get latency :slow-mean-ns 0))
+                  (exponential (get latency :mean-ns 0))))))
+
+(defn link-model
+  "The model of the link from `from` to `to`, the first link that matches takes
+  precedence over the default."
+  [network from to]
+  (let [matches? (fn [pattern reactor]
+                   (or (empty? pattern) (= pattern reactor)))]
+    (or (some (fn [link]
+                (when (or (and (matches? (:from link) from)
+                               (matches? (:to link) to))
+                          (and (:bidirectional link)
+                               (matches? (:from link) to)
+                               (matches? (:to link) from)))
+                  link))
+              (:links network))
+        (:default network))))
+
+(defn deliver-at
+  "When a message sent at `timestamp` is delivered, according to the network
+  model. Without a latency for the link, the latency is drawn like it is for
+  timers."
+  [data entry timestamp]
+  (let [model (link-model (:network data) (:from entry) (:to entry))
+        bandwidth (get model :bandwidth 0)]
+    (if (and (nil? (:latency model)) (not (:fifo model)) (<= bandwidth 0))
+      [data (time/plus-millis timestamp (random/exponential 20))]
+      (let [link [(:from entry) (:to entry)]
+            {:keys [free last]} (get-in data [:links link])
+            start (if (and free (time/before? timestamp free)) free timestamp)
+            ;; The size of a message is that of its arguments as compact JSON,
+            ;; the Go scheduler measures it the same way.
+            size (count (.getBytes ^String (json/write (:args entry)) "UTF-8"))
+            free' (if (pos? bandwidth)
+                    (time/plus-nanos start (* (/ (double size) bandwidth) 1e9))
+                    start)
+            at (if (:latency model)
+                 (time/plus-nanos free' (double (sample-latency (:latency model))))
+                 (time/plus-millis free' (random/exponential 20)))
+            ;; Entries with the same time are delivered in the order they were
+            ;; enqueued.
+            at (if (and (:fifo model) last (time/before? at last)) last at)]
+        [(assoc-in data [:links link] {:free free' :last at}) at]))))
+
 (s/def ::timestamped-entries (s/coll-of agenda/entry?))
 
 (>defn timestamp-entries
@@ -459,19 +687,38 @@
    => (s/tuple ::data (s/keys :req-un [::timestamped-entries]))]
   (with-bindings {#'gen/*rnd* (java.util.Random. (:seed data))}
     (let [new-seed (.nextLong gen/*rnd*)
-          timestamps (->> (gen/vec #(random/exponential 20) (count entries))
-                          (mapv #(time/plus-millis timestamp %)))
-          update-entry (fn [entry timestamp]
+          delayed (fn [] (time/plus-millis timestamp (random/exponential 20)))
+          update-entry (fn [[data entries] entry]
                          (case (:kind entry)
-                           "timer" (-> entry
-                                       ;; Maybe these should have another probability distribution for how slow they are
-                                       (assoc :at (time/plus-nanos timestamp (double (max (:duration-ns entry) 0)))
-                                              :to (:from entry)
-                                              :event :timer)
-                                       (dissoc :duration))
-                           (assoc entry :at timestamp)))]
-      [(assoc data :seed new-seed)
-       {:timestamped-entries (mapv update-entry entries timestamps)}])))
+                           "timer" [data
+                                    (conj entries
+                                          (-> entry
+                                              ;; Maybe these should have another probability distribution for how slow they are
+                                              ;; Timers run on the reactor's clock.
+                                              (assoc :at (time/plus-nanos (delayed) (global-duration data (:from entry) (max (:duration-ns entry) 0)))
+                                                     :to (:from entry)
+                                                     :event :timer)
+                                              (dissoc :duration)))]
+                           "cancel-timer" (do (delayed)
+                                              [data
+                                               (conj entries
+                                                     (assoc entry
+                                                            :at timestamp
+                                                            :to (:from entry)
+                                                            :event :cancel-timer))])
+                           ;; I/O requests come back to the reactor which made
+                           ;; them, as completions, after their latency.
+                           "io" [data
+                                 (conj entries
+                                       (-> entry
+                                           (assoc :at (time/plus-nanos (delayed) (double (max (get entry :duration-ns 0) 0)))
+                                                  :to (:from entry))
+                                           (dissoc :duration-ns)))]
+                           (let [[data' at] (deliver-at data entry timestamp)]
+                             [data' (conj entries (assoc entry :at at))])))
+          [data' timestamped] (reduce update-entry [data []] entries)]
+      [(assoc data' :seed new-seed)
+       {:timestamped-entries timestamped}])))
 
 (comment
   (-> (init-data)
@@ -497,6 +744,7 @@
       (update :state (fn [state]
                        (case state
                          :executors-prepared :executors-prepared ;; Loading initial messages.
+                         :inits-prepared :inits-prepared
                          :responding :responding
                          :error-cannot-enqueue-in-this-state)))
       (ap (fn [data'] {:queue-size (count (:agenda data'))}))))
@@ -571,31 +819,67 @@
                                     :jepsen-type :info
                                     :jepsen-process (-> client :from parse-client-id)})))
 
+(defn timer-of?
+  [reactor id entry]
+  (and (= (:kind entry) "timer")
+       (= (:to entry) reactor)
+       (= (-> entry :args :id) id)))
+
+(>defn cancel-timers
+  "Cancelling a named timer, or setting it again, removes the reactor's pending
+  timer with the same id. Cancellations are applied before the entries are
+  enqueued, so that a run whose last pending timer got cancelled finishes."
+  [data timestamped-entries]
+  [::data ::timestamped-entries => (s/tuple ::data ::timestamped-entries)]
+  (reduce (fn [[data entries] entry]
+            (let [id (-> entry :args :id)
+                  without (fn [reactor]
+                            (let [pending? (partial timer-of? reactor id)]
+                              [(update data :agenda #(agenda/enqueue-many (agenda/empty-agenda)
+                                                                          (remove pending? %)))
+                               (vec (remove pending? entries))]))]
+              (cond
+                (= (:kind entry) "cancel-timer")
+                (if (empty? id) [data entries] (without (:from entry)))
+
+                (and (= (:kind entry) "timer") (not (empty? id)))
+                (update (without (:to entry)) 1 conj entry)
+
+                :else [data (conj entries entry)])))
+          [data []]
+          timestamped-entries))
+
 (>defn enqueue-timestamped-entries
   [data {:keys [timestamped-entries]}]
   [::data (s/keys :req-un [::timestamped-entries])
    => (s/tuple ::data (s/keys :req-un [::queue-size]))]
-  (if (or (and (empty? timestamped-entries) (-> data :agenda empty?) (min-time? data))
-          (max-time? data))
-    (do
-      (expire-clients! data (-> data :client-requests))
-      [(update data :state
-               (fn [state]
-                 (case state
-                   :responding :finished
-                   :executors-prepared :inits-prepared
-                   :error-cannot-enqueue-in-this-state)))
-       {:queue-size (-> data :agenda count)}])
-    (let [data' (-> (reduce (fn [ih timestamped-entry]
-                              (first (enqueue-entry ih timestamped-entry)))
-                            data
-                            timestamped-entries)
-                    (update :state
-                            (fn [state] (case state
-                                          :responding :requesting
-                                          :executors-prepared :inits-prepared
-                                          :error-cannot-enqueue-in-this-state))))]
-      [data' {:queue-size (count (:agenda data'))}])))
+  (let [[data timestamped-entries] (cancel-timers data timestamped-entries)]
+    (if (or (and (empty? timestamped-entries) (-> data :agenda empty?) (min-time? data))
+            (max-time? data)
+            (:stopped data))
+      (do
+        (expire-clients! data (-> data :client-requests))
+        [(update data :state
+                 (fn [state]
+                   (case state
+                     :responding :finished
+                     :executors-prepared :inits-prepared
+                     :inits-prepared :inits-prepared
+                     :waiting-for-executors :waiting-for-executors
+                     :error-cannot-enqueue-in-this-state)))
+         {:queue-size (-> data :agenda count)}])
+      (let [data' (-> (reduce (fn [ih timestamped-entry]
+                                (first (enqueue-entry ih timestamped-entry)))
+                              data
+                              timestamped-entries)
+                      (update :state
+                              (fn [state] (case state
+                                            :responding :requesting
+                                            :executors-prepared :inits-prepared
+                                            :inits-prepared :inits-prepared
+                                            :waiting-for-executors :waiting-for-executors
+                                            :error-cannot-enqueue-in-this-state))))]
+        [data' {:queue-size (count (:agenda data'))}]))))
 
 (comment
   (enqueue-timestamped-entries
@@ -621,12 +905,19 @@
        second)))
 
 (>defn get-initial-events!
-  "Get all the initial events for each component that run on an executor."
+  "Get all the initial events for each component that run on an executor. The
+  components are initialised with the run's meta, so that they see its seed."
   [data {:keys [executor-id]}]
   [::data (s/keys :req-un [::executor-id])
    => (s/tuple ::data (s/keys :req-un [::queue-size]))]
   (let [events (mapv #(assoc % :sent-logical-time (:logical-clock data))
-                     (-> (client/get (str executor-id "/inits"))
+                     (-> (client/post (str executor-id "/inits")
+                                      {:body (json/write {:meta {:test-id (:test-id data)
+                                                                 :run-id (:run-id data)
+                                                                 :logical-time (:logical-clock data)
+                                                                 :seed (:run-seed data)
+                                                                 :simulated-time (:clock data)}})
+                                       :content-type "application/json; charset=utf-8"})
                          :body
                          json/read
                          :events
@@ -658,8 +949,6 @@
                      :test-prepared state'
                      :waiting-for-executors state'
                      :error-cannot-register-in-this-state))))
-      (get-initial-events! {:executor-id executor-id})
-      first
       (ap (fn [data']
             {:remaining-executors (max 0 (- (:total-executors data')
                                             (:connected-executors data')))}))))
@@ -678,16 +967,26 @@
  [::data => (s/tuple ::data (s/nilable (s/keys :req-un [::events])))]
  (assert (or (not (empty? (:agenda data)))
              (not (min-time? data))))
- (let [all-events (transient [])]
-   (doseq [[reactor url] (:topology data)]
+ (let [all-events (transient [])
+       stopped (volatile! false)]
+   (doseq [[reactor url] (:topology data)
+           :when (nil? (paused-until data reactor (:next-tick data)))]
      (let [url (str url "tick")
            events (-> (client/put url
-                                  {:body (json/write {:at (:next-tick data)
-                                                      :reactor reactor})
+                                  {:body (json/write {:at (local-time data reactor (:next-tick data))
+                                                      :reactor reactor
+                                                      :meta {:test-id (:test-id data)
+                                                             :run-id (:run-id data)
+                                                             :logical-time (:logical-clock data)
+                                                             :seed (:run-seed data)
+                                                             :simulated-time (:next-tick data)}})
                                    :content-type "application/json; charset=utf-8"})
                       :body
                       json/read
                       (update :events expand-events))]
+       ;; An invariant of the executor was violated.
+       (when (:stop events)
+         (vreset! stopped true))
        (doseq [event (:events events)]
          (conj! all-events event))))
    (let [events (->> all-events
@@ -697,7 +996,8 @@
           (update :next-tick (fn [c] (time/plus-millis c (:tick-frequency data))))
           (update :logical-clock (if (empty? events) identity inc))
           (assoc :state :responding) ;; probably??
-          (assoc :clock (:next-tick data)))
+          (assoc :clock (:next-tick data))
+          (cond-> @stopped (assoc :stopped true)))
       {:events events}])))
 
 (>defn execute-or-tick!
@@ -787,10 +1087,21 @@
 
 (s/def ::at nat-int?)
 
+(s/def ::until nat-int?)
+(s/def ::groups (s/coll-of (s/coll-of string?)))
+(s/def ::by-ns int?)
+(s/def ::at-ns nat-int?)
+(s/def ::until-ns nat-int?)
+
 (def fault? (s/keys :req-un [:scheduler.agenda/kind
                              :scheduler.agenda/to
                              :scheduler.agenda/from
-                             ::at]))
+                             ::at]
+                    :opt-un [::until
+                             ::groups
+                             ::by-ns
+                             ::at-ns
+                             ::until-ns]))
 
 (s/def ::faults (s/coll-of fault?))
 
@@ -807,27 +1118,43 @@
            ]))
 
 (>defn create-run!
+  "The components are initialised once the run is created, so that they see the
+  run's seed and their initial events are timestamped with the run's clocks and
+  network model."
   [data event]
   [::data ::create-run-event => (s/tuple ::data (s/keys :req-un [::run-id]))]
   (case (:state data)
-    :inits-prepared
-    (let [test-id (:test-id event)
-          run-id (db/next-run-id! test-id)
+    :executors-prepared
+    (let [_ (assert (every? #(> (double (get % :drift 0)) -1.0) (vals (get event :clocks {})))
+                    "The drift of a clock must be greater than -1.")
+          test-id (:test-id event)
+          run-id (db/create-run-event! test-id event)
           seed (:seed event)
           tick-frequency (double (:tick-frequency event))
           min-time (double (:min-time-ns event))
           max-time (double (:max-time-ns event))
           faults (:faults event)
           data (-> data
-                   (assoc :state :ready
-                          :test-id test-id
+                   (assoc :test-id test-id
                           :run-id (:run-id run-id)
                           :seed seed
+                          :run-seed seed
                           :tick-frequency tick-frequency
                           :min-time-ns min-time
                           :max-time-ns max-time
-                          :faults faults))]
-      (db/append-create-run-event! (:test-id data) (:run-id data) event)
+                          :faults faults
+                          :applied-faults #{}
+                          :clocks (get event :clocks {})
+                          :network (:network event)
+                          :links {}
+                          :stopped false))
+          data (reduce (fn [data executor-id]
+                         (first (get-initial-events! data {:executor-id executor-id})))
+                       data
+                       (sort (distinct (vals (:topology data)))))
+          data (-> data
+                   (assoc :state :ready)
+                   (update :agenda #(agenda/enqueue-many % (fault-entries faults))))]
       [data run-id])
     [(assoc data :state :error-cannot-create-run-in-this-state) nil]))
 
@@ -1,9 +1,8 @@
 package broadcast
 
 import (
-	"fmt"
+	"context"
 	"log"
-	"net/http"
 	"reflect"
 	"testing"
 	"time"
@@ -12,87 +11,57 @@ import (
 	"github.com/symbiont-io/detsys-testkit/src/lib"
 )
 
-func once(round Round, testId lib.TestId, runEvent lib.CreateRunEvent, t *testing.T) (lib.RunId, lib.LTLResult) {
-	topology := lib.NewTopology(
-		lib.Item{"A", NewNodeA(round)},
-		lib.Item{"B", NewNode(round, "C")},
-		lib.Item{"C", NewNode(round, "B")},
-	)
-	marshaler := NewMarshaler()
-	var srv http.Server
-	lib.Setup(func() {
-		executor.Deploy(&srv, topology, marshaler)
-	})
-	qs := lib.LoadTest(testId)
-	log.Printf("Loaded test of size: %d\n", qs.QueueSize)
-	lib.Register(testId)
-	log.Printf("Registered executor")
-	runId := lib.CreateRun(testId, runEvent)
-	log.Printf("Created run id: %v", runId)
-	lib.Run()
-	log.Printf("Finished run id: %d %d\n", testId.TestId, runId.RunId)
-	lib.Teardown(&srv)
-	log.Printf("Checking\n")
-	result := lib.LtlChecker(testId, runId, "<> @B'.log = `\"Hello world!\"`")
-	return runId, result
-}
-
 func many(round Round, expectedRuns int, t *testing.T, expectedFaults []lib.Fault) {
 	tickFrequency := 100000000000.0 // Make ticks infrequent.
 
-	// TODO(stevan): GenerateTest should be parametrised by round also.
-	// Currently one test_info.deployment is shared between all rounds,
-	// which is wrong and makes the debugger display the wrong initial
-	// state.
-	testId := lib.GenerateTest("broadcast")
-
-	var faults []lib.Fault
-	failSpec := lib.FailSpec{
-		// NOTE: EFF is set to 2 in the paper. There's a mismatch here,
-		// because in the paper two nodes can send messages at the same
-		// time, we can't. And we also got timers which increase the
-		// logical clock, which means we sometimes need a higher EFF in
-		// order to find the problem.
-		EFF:     3,
-		Crashes: 1,
-		EOT:     10,
-	}
-	neededRuns := 0
-	for {
-		neededRuns++
-		lib.Reset()
-		maxTime := time.Duration(5) * time.Second
-		runEvent := lib.CreateRunEvent{
+	report, err := lib.Explore(context.Background(), lib.Spec{
+		Topology: func() lib.Topology {
+			return lib.NewTopology(
+				lib.Item{"A", NewNodeA(round)},
+				lib.Item{"B", NewNode(round, "C")},
+				lib.Item{"C", NewNode(round, "B")},
+			)
+		},
+		Marshaler: NewMarshaler(),
+		// TODO(stevan): GenerateTest should be parametrised by round also.
+		// Currently one test_info.deployment is shared between all rounds,
+		// which is wrong and makes the debugger display the wrong initial
+		// state.
+		TestId: lib.GenerateTest("broadcast"),
+		Deploy: executor.DeployTopology,
+		Run: lib.CreateRunEvent{
 			Seed:          lib.Seed(1),
-			Faults:        lib.Faults{faults},
 			TickFrequency: tickFrequency,
-			MaxTimeNs:     maxTime,
+			MaxTimeNs:     time.Duration(5) * time.Second,
 			MinTimeNs:     0,
-		}
-		runId, result := once(round, testId, runEvent, t)
-		if !result.Result {
-			fmt.Printf("%+v and %+v doesn't pass analysis\n%s\n", testId, runId, result.Reason)
-			if !reflect.DeepEqual(faults, expectedFaults) {
-				t.Errorf("Expected faults:\n%#v, but got faults:\n%#v\n",
-					expectedFaults, faults)
-			}
-			break
-		}
-		faults = lib.Ldfi(testId, nil, failSpec).Faults
-		if len(faults) == 0 {
-			break
-		}
+		},
+		FailSpec: lib.FailSpec{
+			// NOTE: EFF is set to 2 in the paper. There's a mismatch here,
+			// because in the paper two nodes can send messages at the same
+			// time, we can't. And we also got timers which increase the
+			// logical clock, which means we sometimes need a higher EFF in
+			// order to find the problem.
+			EFF:     3,
+			Crashes: 1,
+			EOT:     10,
+		},
+		Checkers: []lib.Checker{lib.NewLtlChecker(lib.Eventually(
+			lib.Eq(lib.Heap("B").After().Field("log"), lib.Value("Hello world!"))))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Println(report)
+	faults := []lib.Fault{}
+	if report.Counterexample != nil {
+		faults = report.Counterexample.Faults
+	}
+	if !reflect.DeepEqual(faults, expectedFaults) {
+		t.Errorf("Expected faults:\n%#v, but got faults:\n%#v\n",
+			expectedFaults, faults)
 	}
-	if !reflect.DeepEqual(faults, []lib.Fault{}) {
-		if neededRuns != expectedRuns {
-			t.Errorf("Expected to find counterexample in %d runs, but it took %d runs",
-				expectedRuns, neededRuns)
-		}
-	} else {
-		if neededRuns != expectedRuns {
-			t.Errorf("Expected to find no counterexamples in %d runs, but it took %d runs",
-				expectedRuns, neededRuns)
-		}
+	if len(report.Runs) != expectedRuns {
+		t.Errorf("Expected %d runs, but it took %d runs", expectedRuns, len(report.Runs))
 	}
 }
 
@@ -1,6 +1,6 @@
 module github.com/symbiont-io/detsys-detsys/src/sut/broadcast
 
-go 1.15
+go 1.18
 
 replace github.com/symbiont-io/detsys-testkit/src/executor => ../../executor
 
@@ -10,3 +10,12 @@ require (
 	github.com/symbiont-io/detsys-testkit/src/executor v0.0.0-00010101000000-000000000000
 	github.com/symbiont-io/detsys-testkit/src/lib v0.0.0-00010101000000-000000000000
 )
+
+require (
+	github.com/evanphx/json-patch v4.9.0+incompatible // indirect
+	github.com/mattn/go-sqlite3 v1.14.5 // indirect
+	github.com/pkg/errors v0.8.1 // indirect
+	go.uber.org/atomic v1.7.0 // indirect
+	go.uber.org/multierr v1.6.0 // indirect
+	go.uber.org/zap v1.20.0 // indirect
+)
@@ -1,9 +1,8 @@
 package sut
 
 import (
-	"fmt"
+	"context"
 	"log"
-	"net/http"
 	"reflect"
 	"testing"
 	"time"
@@ -20,26 +19,6 @@ func createTopology(newFrontEnd func() lib.Reactor) lib.Topology {
 	)
 }
 
-func once(newFrontEnd func() lib.Reactor, testId lib.TestId, runEvent lib.CreateRunEvent, t *testing.T) (lib.RunId, bool) {
-	topology := createTopology(newFrontEnd)
-	marshaler := NewMarshaler()
-	var srv http.Server
-	lib.Setup(func() {
-		executor.Deploy(&srv, topology, marshaler)
-	})
-	qs := lib.LoadTest(testId)
-	log.Printf("Loaded test of size: %d\n", qs.QueueSize)
-	lib.Register(testId)
-	runId := lib.CreateRun(testId, runEvent)
-	lib.Run()
-	log.Printf("Finished run id: %d\n", runId.RunId)
-	lib.Teardown(&srv)
-	model := "list-append"
-	log.Printf("Analysing model %s for %+v and %+v\n", model, testId, runId)
-	result := lib.Check(model, testId, runId)
-	return runId, result
-}
-
 func testRegisterWithFrontEnd(newFrontEnd func() lib.Reactor, tickFrequency float64, t *testing.T, expectedRuns int, expectedFaults []lib.Fault) {
 	agenda := []lib.ScheduledEvent{
 		lib.ScheduledEvent{
@@ -62,50 +41,38 @@ func testRegisterWithFrontEnd(newFrontEnd func() lib.Reactor, tickFrequency floa
 		},
 	}
 
-	testId := lib.GenerateTestFromTopologyAndAgenda(createTopology(newFrontEnd), agenda)
-
-	var faults []lib.Fault
-	failSpec := lib.FailSpec{
-		EFF:     7,
-		Crashes: 0,
-		EOT:     0,
-	}
-	neededRuns := 0
-	for {
-		neededRuns++
-		lib.Reset()
-		runEvent := lib.CreateRunEvent{
+	report, err := lib.Explore(context.Background(), lib.Spec{
+		Topology:  func() lib.Topology { return createTopology(newFrontEnd) },
+		Marshaler: NewMarshaler(),
+		Agenda:    agenda,
+		Deploy:    executor.DeployTopology,
+		Run: lib.CreateRunEvent{
 			Seed:          lib.Seed(4),
-			Faults:        lib.Faults{faults},
 			TickFrequency: tickFrequency,
 			MinTimeNs:     0,
 			MaxTimeNs:     0,
-		}
-		log.Printf("Injecting faults: %#v\n", faults)
-		runId, result := once(newFrontEnd, testId, runEvent, t)
-		if !result {
-			fmt.Printf("%+v and %+v doesn't pass analysis\n", testId, runId)
-			if !reflect.DeepEqual(faults, expectedFaults) {
-				t.Errorf("Expected faults:\n%#v, but got faults:\n%#v\n",
-					expectedFaults, faults)
-			}
-			break
-		}
-		faults = lib.Ldfi(testId, nil, failSpec).Faults
-		if len(faults) == 0 {
-			break
-		}
+		},
+		FailSpec: lib.FailSpec{
+			EFF:     7,
+			Crashes: 0,
+			EOT:     0,
+		},
+		Checkers: []lib.Checker{lib.NewJepsenChecker("list-append")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Println(report)
+	faults := []lib.Fault{}
+	if report.Counterexample != nil {
+		faults = report.Counterexample.Faults
+	}
+	if !reflect.DeepEqual(faults, expectedFaults) {
+		t.Errorf("Expected faults:\n%#v, but got faults:\n%#v\n",
+			expectedFaults, faults)
 	}
-	if !reflect.DeepEqual(faults, []lib.Fault{}) {
-		if neededRuns != expectedRuns {
-			t.Errorf("Expected to find counterexample in %d runs, but it took %d runs",
-				expectedRuns, neededRuns)
-		}
-	} else {
-		if neededRuns != expectedRuns {
-			t.Errorf("Expected to find no counterexamples in %d runs, but it took %d runs",
-				expectedRuns, neededRuns)
-		}
+	if len(report.Runs) != expectedRuns {
+		t.Errorf("Expected %d runs, but it took %d runs", expectedRuns, len(report.Runs))
 	}
 }
 
@@ -1,6 +1,6 @@
 module github.com/symbiont-io/detsys-testkit/src/sut/register
 
-go 1.15
+go 1.18
 
 replace github.com/symbiont-io/detsys-testkit/src/executor => ../../executor
 
@@ -10,7 +10,17 @@ replace github.com/symbiont-io/detsys-testkit/src/lib => ../../lib
 
 require (
 	github.com/symbiont-io/detsys-testkit/src/executor v0.0.0-00010101000000-000000000000
-	github.com/symbiont-io/detsys-testkit/src/executorEL v0.0.0-00010101000000-000000000000 // indirect
+	github.com/symbiont-io/detsys-testkit/src/executorEL v0.0.0-00010101000000-000000000000
 	github.com/symbiont-io/detsys-testkit/src/lib v0.0.0-20211029070032-cb4608e5c898
-	go.uber.org/zap v1.16.0 // indirect
+)
+
+require (
+	github.com/evanphx/json-patch v4.9.0+incompatible // indirect
+	github.com/mattn/go-sqlite3 v1.14.5 // indirect
+	github.com/pkg/errors v0.8.1 // indirect
+	go.uber.org/atomic v1.7.0 // indirect
+	go.uber.org/multierr v1.6.0 // indirect
+	go.uber.org/tools v0.0.0-20190618225709-2cfd321de3ee // indirect
+	go.uber.org/zap v1.20.0 // indirect
+	honnef.co/go/tools v0.0.1-2019.2.3 // indirect
 )
@@ -2,112 +2,52 @@ package sut
 
 import (
 	"encoding/json"
-	"fmt"
 	"strconv"
-	"strings"
 
 	"github.com/symbiont-io/detsys-testkit/src/lib"
 )
 
-type Marshaler struct{}
-
-func NewMarshaler() *Marshaler {
-	return &Marshaler{}
+func NewMarshaler() *lib.Registry {
+	r := lib.NewRegistry()
+	lib.MustRegisterType[Read](r)
+	lib.MustRegisterType[Write](r)
+	lib.MustRegisterType[Value](r)
+	lib.MustRegisterType[Ack](r)
+	// The messages between the front ends and the registers are named after
+	// the request or response they carry.
+	lib.MustRegisterMessage(r, "read", internalRequest[Read])
+	lib.MustRegisterMessage(r, "write", internalRequest[Write])
+	lib.MustRegisterMessage(r, "value", internalResponse[Value])
+	lib.MustRegisterMessage(r, "ack", internalResponse[Ack])
+	return r
 }
 
-func (_ *Marshaler) UnmarshalRequest(request string, raw json.RawMessage, req *lib.Request) error {
-	switch strings.ToLower(request) {
-	case "read":
-		var op Read
-		if err := json.Unmarshal(raw, &op); err != nil {
-			return err
-		}
-		*req = op
-	case "write":
-		var op Write
-		if err := json.Unmarshal(raw, &op); err != nil {
-			return err
-		}
-		*req = op
-	default:
-		return fmt.Errorf("Unknown request type: %s\n%s", request, raw)
+func internalRequest[R lib.Request](raw json.RawMessage) (lib.Message, error) {
+	var op struct {
+		Id      SessionId `json:"id"`
+		Request R         `json:"request"`
 	}
-	return nil
-}
-
-func (_ *Marshaler) UnmarshalResponse(response string, raw json.RawMessage) lib.Response {
-	var resp lib.Response
-	switch strings.ToLower(response) {
-	case "value":
-		var op Value
-		if err := json.Unmarshal(raw, &op); err != nil {
-			panic(err)
-		}
-		resp = op
-	case "ack":
-		var op Ack
-		if err := json.Unmarshal(raw, &op); err != nil {
-			panic(err)
-		}
-		resp = op
+	if err := json.Unmarshal(raw, &op); err != nil {
+		return nil, err
 	}
-	return resp
+	return InternalRequest{
+		Id:      op.Id,
+		Request: op.Request,
+	}, nil
 }
 
-func (_ *Marshaler) UnmarshalMessage(message string, raw json.RawMessage, msg *lib.Message) error {
-	switch strings.ToLower(message) {
-	case "read":
-		var op struct {
-			Id      SessionId `json:"id"`
-			Request Read      `json:"request"`
-		}
-		if err := json.Unmarshal(raw, &op); err != nil {
-			panic(err)
-		}
-		*msg = InternalRequest{
-			Id:      op.Id,
-			Request: op.Request,
-		}
-	case "write":
-		var op struct {
-			Id      SessionId `json:"id"`
-			Request Write     `json:"request"`
-		}
-		if err := json.Unmarshal(raw, &op); err != nil {
-			panic(err)
-		}
-		*msg = InternalRequest{
-			Id:      op.Id,
-			Request: op.Request,
-		}
-	case "ack":
-		var op struct {
-			Id       SessionId `json:"id"`
-			Response Ack       `json:"response"`
-		}
-		if err := json.Unmarshal(raw, &op); err != nil {
-			panic(err)
-		}
-		*msg = InternalResponse{
-			Id:       op.Id,
-			Response: op.Response,
-		}
-	case "value":
-		var op struct {
-			Id       SessionId `json:"id"`
-			Response Value     `json:"response"`
-		}
-		if err := json.Unmarshal(raw, &op); err != nil {
-			panic(err)
-		}
-		*msg = InternalResponse{
-			Id:       op.Id,
-			Response: op.Response,
-		}
-	default:
-		panic(fmt.Errorf("Unknown message type: %s\n%s", message, raw))
+func internalResponse[R lib.Response](raw json.RawMessage) (lib.Message, error) {
+	var op struct {
+		Id       SessionId `json:"id"`
+		Response R         `json:"response"`
 	}
-	return nil
+	if err := json.Unmarshal(raw, &op); err != nil {
+		return nil, err
+	}
+	return InternalResponse{
+		Id:       op.Id,
+		Response: op.Response,
+	}, nil
 }
 
 func (sessionId SessionId) MarshalJSON() ([]byte, error) {
@@ -0,0 +1,47 @@
+package sut
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/symbiont-io/detsys-testkit/src/lib"
+)
+
+// The sequential specification of the register, for `lib.Linearizable`: a
+// write appends its value to the register and a read returns all values
+// written so far, in order.
+var Model = lib.Model{
+	Name: "register",
+	Init: func() interface{} { return []int{} },
+	Step: func(state interface{}, op lib.Op) (bool, interface{}) {
+		values := state.([]int)
+		switch op.Input.Event {
+		case "write":
+			var write Write
+			if err := json.Unmarshal(op.Input.Args, &write); err != nil {
+				return false, state
+			}
+			if op.Output != nil && op.Output.Event != "ack" {
+				return false, state
+			}
+			next := make([]int, len(values), len(values)+1)
+			copy(next, values)
+			return true, append(next, write.Value)
+		case "read":
+			if op.Output == nil {
+				return true, state
+			}
+			var value Value
+			if op.Output.Event != "value" ||
+				json.Unmarshal(op.Output.Args, &value) != nil {
+				return false, state
+			}
+			if value.Value == nil {
+				value.Value = []int{}
+			}
+			return reflect.DeepEqual(values, value.Value), state
+		default:
+			return false, state
+		}
+	},
+}
@@ -0,0 +1,33 @@
+package sut
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/symbiont-io/detsys-testkit/src/lib"
+)
+
+func TestModel(t *testing.T) {
+	op := func(process int, request string, args string, response string, result string, call int, ret int) lib.Op {
+		return lib.Op{
+			Process: process,
+			Input:   lib.HistoryEvent{Event: request, Args: json.RawMessage(args)},
+			Output:  &lib.HistoryEvent{Event: response, Args: json.RawMessage(result)},
+			Call:    call,
+			Return:  ret,
+		}
+	}
+	history := []lib.Op{
+		op(0, "write", `{"value":1}`, "ack", `{}`, 0, 1),
+		op(0, "write", `{"value":2}`, "ack", `{}`, 2, 5),
+		op(1, "read", `{}`, "value", `{"value":[1,2]}`, 3, 4),
+	}
+	if verdict := lib.Linearizable(Model, history); !verdict.Linearizable {
+		t.Errorf("Expected a linearizable history, got: %+v", verdict)
+	}
+
+	history[2] = op(1, "read", `{}`, "value", `{"value":[2]}`, 3, 4)
+	if verdict := lib.Linearizable(Model, history); verdict.Linearizable {
+		t.Errorf("Expected the lost write to be found, got: %+v", verdict)
+	}
+}